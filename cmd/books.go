@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Formats of book files.
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// bookColumns are the columns of CSV files, the id is ignored on import.
+var bookColumns = []string{"id", "name", "author", "price", "currency", "genre", "amount", "reorder_threshold"}
+
+func booksTable(books []models.Book) table {
+	t := table{header: []string{"ID", "NAME", "AUTHOR", "PRICE", "CURRENCY", "GENRE", "AMOUNT"}}
+	for _, book := range books {
+		t.rows = append(t.rows, []string{strconv.Itoa(book.ID), book.Name, book.Author, book.Price.String(),
+			book.Currency, strconv.Itoa(book.Genre), strconv.Itoa(book.Amount)})
+	}
+	return t
+}
+
+func listBooks(args []string) error {
+	flagSet := newFlagSet("books list")
+	genre := flagSet.Int("genre", 0, "only books of the genre")
+	availability := flagSet.String("availability", models.AvailabilityAll, "in_stock, out_of_stock or all")
+	location := flagSet.Int("location", 0, "decide availability by the stock at the location")
+	sort := flagSet.String("sort", "", "rating lists the best rated books first")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if !models.ValidAvailability(*availability) {
+		return fmt.Errorf("unknown availability %q", *availability)
+	}
+	if *sort != "" && *sort != models.SortRating {
+		return fmt.Errorf("unknown sort %q", *sort)
+	}
+	filterCondition := url.Values{"availability": {*availability}}
+	if *genre != 0 {
+		filterCondition.Set("genre", strconv.Itoa(*genre))
+	}
+	if *location != 0 {
+		filterCondition.Set("location", strconv.Itoa(*location))
+	}
+	if *sort != "" {
+		filterCondition.Set("sort", *sort)
+	}
+
+	cfg, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	books, closeBooks := newBooksService(cfg, db)
+	defer closeBooks()
+	list, err := books.GetBooks(filterCondition)
+	if err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, *output, list, booksTable(list))
+}
+
+// importBooks creates the books of a file. All books are validated like the API does before any
+// is created, a failure stops the import and reports the books created before it.
+func importBooks(args []string) error {
+	flagSet := newFlagSet("books import FILE (- reads stdin)")
+	format := flagSet.String("format", "", "json or csv, from the file extension when empty")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("import takes the file of the books")
+	}
+	path := flagSet.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	newBooks, err := readBooks(in, *format)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for i, book := range newBooks {
+		if err := binding.Validator.ValidateStruct(&book); err != nil {
+			problems = append(problems, fmt.Sprintf("book %d %q: %s", i+1, book.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid books, none imported:\n%s", strings.Join(problems, "\n"))
+	}
+
+	cfg, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	books, closeBooks := newBooksService(cfg, db)
+	defer closeBooks()
+	created := make([]models.Book, 0, len(newBooks))
+	for _, book := range newBooks {
+		if book.ID, err = books.CreateBook(book); err != nil {
+			err = fmt.Errorf("failed to create book %q: %w", book.Name, err)
+			break
+		}
+		created = append(created, book.WithDefaults())
+	}
+	if outputErr := writeOutput(os.Stdout, *output, created, booksTable(created)); outputErr != nil && err == nil {
+		err = outputErr
+	}
+	return err
+}
+
+func exportBooks(args []string) error {
+	flagSet := newFlagSet("books export")
+	format := flagSet.String("format", formatJSON, "json or csv")
+	file := flagSet.String("file", "", "file to write, stdout when empty")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if *format != formatJSON && *format != formatCSV {
+		return fmt.Errorf("unknown format %q, it must be json or csv", *format)
+	}
+
+	cfg, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	books, closeBooks := newBooksService(cfg, db)
+	defer closeBooks()
+	list, err := books.GetBooks(url.Values{"availability": {models.AvailabilityAll}})
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == formatCSV {
+		return writeBooksCSV(out, list)
+	}
+	return writeOutput(out, outputJSON, list, table{})
+}
+
+func readBooks(r io.Reader, format string) ([]models.Book, error) {
+	switch format {
+	case formatJSON:
+		var books []models.Book
+		if err := json.NewDecoder(r).Decode(&books); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return books, nil
+	case formatCSV:
+		return readBooksCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown format %q, it must be json or csv", format)
+	}
+}
+
+// readBooksCSV reads books with a header of bookColumns, in any order and with the optional ones left out.
+func readBooksCSV(r io.Reader) ([]models.Book, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, column := range bookColumns {
+			known = known || column == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	var books []models.Book
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return books, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		book, err := bookFromRecord(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		books = append(books, book)
+	}
+}
+
+func bookFromRecord(record []string, columns map[string]int) (models.Book, error) {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(column string) (int, error) {
+		if value(column) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value(column))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", column, value(column))
+		}
+		return n, nil
+	}
+	book := models.Book{Name: value("name"), Author: value("author"), Currency: value("currency")}
+	var err error
+	if book.Price, err = models.ParseMoney(value("price")); err != nil {
+		return book, fmt.Errorf("invalid price %q", value("price"))
+	}
+	if book.Genre, err = number("genre"); err != nil {
+		return book, err
+	}
+	if book.Amount, err = number("amount"); err != nil {
+		return book, err
+	}
+	if book.ReorderThreshold, err = number("reorder_threshold"); err != nil {
+		return book, err
+	}
+	return book, nil
+}
+
+func writeBooksCSV(w io.Writer, books []models.Book) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(bookColumns); err != nil {
+		return err
+	}
+	for _, book := range books {
+		err := writer.Write([]string{strconv.Itoa(book.ID), book.Name, book.Author, book.Price.String(), book.Currency,
+			strconv.Itoa(book.Genre), strconv.Itoa(book.Amount), strconv.Itoa(book.ReorderThreshold)})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
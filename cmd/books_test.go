@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestBooksCSV(t *testing.T) {
+	books := []models.Book{
+		{ID: 1, Name: "Treasure Island", Author: "Robert Louis Stevenson", Price: 1250, Currency: "USD", Genre: 1, Amount: 8, ReorderThreshold: 2},
+		{ID: 2, Name: "Moby-Dick, or The Whale", Price: 1140, Currency: "EUR", Genre: 2},
+	}
+	var out bytes.Buffer
+	require.NoError(t, writeBooksCSV(&out, books))
+	assert.Equal(t, "id,name,author,price,currency,genre,amount,reorder_threshold\n"+
+		"1,Treasure Island,Robert Louis Stevenson,12.50,USD,1,8,2\n"+
+		"2,\"Moby-Dick, or The Whale\",,11.40,EUR,2,0,0\n", out.String())
+
+	read, err := readBooks(&out, formatCSV)
+	require.NoError(t, err)
+	// Ids are given by the database on import.
+	books[0].ID, books[1].ID = 0, 0
+	assert.Equal(t, books, read)
+
+	read, err = readBooks(strings.NewReader("genre,price,name\n3,14,The Hobbit\n"), formatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Book{{Name: "The Hobbit", Price: 1400, Genre: 3}}, read)
+
+	_, err = readBooks(strings.NewReader("name,title\nThe Hobbit,The Hobbit\n"), formatCSV)
+	assert.EqualError(t, err, `unknown CSV column "title"`)
+	_, err = readBooks(strings.NewReader("name,price\nThe Hobbit,cheap\n"), formatCSV)
+	assert.EqualError(t, err, `line 2: invalid price "cheap"`)
+}
+
+func TestWriteOutput(t *testing.T) {
+	genre := models.Genre{ID: 4, Name: "science fiction"}
+	genreTable := table{header: []string{"ID", "NAME"}, rows: [][]string{{"4", "science fiction"}}}
+
+	var out bytes.Buffer
+	require.NoError(t, writeOutput(&out, outputTable, genre, genreTable))
+	assert.Equal(t, "ID  NAME\n4   science fiction\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeOutput(&out, outputJSON, genre, genreTable))
+	assert.Equal(t, "{\n  \"id\": 4,\n  \"name\": \"science fiction\"\n}\n", out.String())
+}
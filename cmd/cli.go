@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/pkg/cache"
+	"github.com/TenderLimbo/rest-api/pkg/config"
+	"github.com/TenderLimbo/rest-api/pkg/redis"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/spf13/pflag"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats of the commands.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// newFlagSet returns the flags of a command with the flags of the configuration.
+func newFlagSet(name string) *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("restapi "+name, pflag.ContinueOnError)
+	config.AddFlags(flagSet)
+	return flagSet
+}
+
+func addOutputFlag(flagSet *pflag.FlagSet) *string {
+	return flagSet.StringP("output", "o", outputTable, "output format: table or json")
+}
+
+func checkOutput(format string) error {
+	if format != outputTable && format != outputJSON {
+		return fmt.Errorf("unknown output %q, it must be table or json", format)
+	}
+	return nil
+}
+
+// table is the human-readable output of a command.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// writeOutput writes value as JSON or the table aligned in columns.
+func writeOutput(w io.Writer, format string, value interface{}, t table) error {
+	if format == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// openDatabase loads the configuration of the parsed flags and connects to its database.
+// SQL logs go to stderr, so the output of commands can be piped.
+func openDatabase(flagSet *pflag.FlagSet) (config.Config, *gorm.DB, error) {
+	source, err := config.FromFlags(flagSet)
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	cfg := source.Config()
+	db, err := repository.NewPostgresDB(cfg.DB)
+	if err != nil {
+		return config.Config{}, nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	db.Logger = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      logger.Warn,
+	})
+	return cfg, db, nil
+}
+
+// newBooksService returns the books service of the server. With Redis the books cached by the
+// servers are invalidated on changes, their in-memory caches expire after cache.ttl.
+func newBooksService(cfg config.Config, db *gorm.DB) (*service.BooksManagerService, func()) {
+	if cfg.Redis.Addr == "" {
+		books := service.NewService(repository.NewRepository(db))
+		books.UseCurrencies(cfg.Pricing.Rates)
+		return books, func() {}
+	}
+	redisClient := redis.NewClient(cfg.Redis.Addr, cfg.Redis.PoolSize, cfg.Redis.Timeout)
+	cached := service.NewCachedBooksManager(repository.NewRepository(db), cache.NewRedis(redisClient, "restapi:"),
+		cfg.Cache.TTL)
+	books := service.NewService(cached)
+	books.UseCurrencies(cfg.Pricing.Rates)
+	return books, func() { redisClient.Close() }
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin/binding"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func addGenre(args []string) error {
+	flagSet := newFlagSet("genres add NAME")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("add takes the name of the genre")
+	}
+	genre := models.Genre{Name: strings.TrimSpace(flagSet.Arg(0))}
+	if err := binding.Validator.ValidateStruct(&genre); err != nil {
+		return fmt.Errorf("invalid genre: %w", err)
+	}
+
+	_, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	if genre.ID, err = service.NewGenresService(repository.NewGenresPostgres(db)).CreateGenre(genre); err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, *output, genre, table{
+		header: []string{"ID", "NAME"},
+		rows:   [][]string{{strconv.Itoa(genre.ID), genre.Name}},
+	})
+}
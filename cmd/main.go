@@ -1,60 +1,92 @@
 package main
 
 import (
-	"context"
-	"github.com/TenderLimbo/rest-api/models"
-	"github.com/TenderLimbo/rest-api/pkg/handler"
-	"github.com/TenderLimbo/rest-api/pkg/repository"
-	"github.com/TenderLimbo/rest-api/pkg/service"
-	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
-	"log"
+	"errors"
+	"fmt"
+	"github.com/spf13/pflag"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 )
 
-func InitConfig() error {
-	viper.AddConfigPath("configs")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+// command is a subcommand of the binary, run with the arguments after its name.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "run the HTTP and gRPC servers, the default without a command", run: serve},
+	{name: "migrate", usage: "apply or revert database migrations: up, down or version", run: migrateCommand},
+	{name: "seed", usage: "add sample books to a database without books", run: seed},
+	{name: "books", usage: "manage the catalog: list, import or export", run: booksCommand},
+	{name: "genres", usage: "manage genres: add", run: genresCommand},
+	{name: "users", usage: "manage users: create", run: usersCommand},
+}
+
+var bookCommands = []command{
+	{name: "list", usage: "list books", run: listBooks},
+	{name: "import", usage: "create the books of a JSON or CSV file", run: importBooks},
+	{name: "export", usage: "write all books as JSON or CSV", run: exportBooks},
+}
+
+var genreCommands = []command{
+	{name: "add", usage: "add a genre: genres add NAME", run: addGenre},
+}
+
+var userCommands = []command{
+	{name: "create", usage: "create a user: users create EMAIL [--name NAME]", run: createUser},
 }
 
 func main() {
-	var err error
-	if err = InitConfig(); err != nil {
-		log.Fatalf("failed to init config : %s", err.Error())
+	args := os.Args[1:]
+	// Without a command the server runs, as it did before there were commands.
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
 	}
-	if err = godotenv.Load(); err != nil {
-		log.Fatalf("failed to init .env : %s", err.Error())
+	if err := dispatch("restapi", commands, args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
+}
 
-	db, err := repository.NewPostgresDB(viper.GetStringMapString("db"), os.Getenv("POSTGRES_PASSWORD"))
-	if err != nil {
-		log.Fatalf("failed to connect database : %s", err.Error())
+// dispatch runs the command named by the first argument.
+func dispatch(parent string, commands []command, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(parent, commands)
+		if len(args) == 0 {
+			return errors.New("command is missing")
+		}
+		return pflag.ErrHelp
 	}
-	repo := repository.NewRepository(db)
-	services := service.NewService(repo)
-	handlers := handler.NewHandler(services)
-
-	srv := new(models.Server)
-	go func() {
-		if err = srv.Run(viper.GetString("port"), handlers.InitRoutes()); err != nil {
-			log.Println("listen: ", err)
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
 		}
-	}()
+	}
+	printUsage(parent, commands)
+	return fmt.Errorf("unknown command %q", parent+" "+args[0])
+}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+func printUsage(parent string, commands []command) {
+	fmt.Fprintf(os.Stderr, "Usage: %s COMMAND [flags]\n\nCommands:\n", parent)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s COMMAND --help for the flags of a command.\n", parent)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func booksCommand(args []string) error {
+	return dispatch("restapi books", bookCommands, args)
+}
 
-	if err = srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
-	}
-	log.Println("Server exiting")
+func genresCommand(args []string) error {
+	return dispatch("restapi genres", genreCommands, args)
+}
+
+func usersCommand(args []string) error {
+	return dispatch("restapi users", userCommands, args)
 }
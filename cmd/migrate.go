@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/pkg/migrate"
+	"os"
+	"strconv"
+)
+
+// migrateCommand applies the migrations of --dir: up to the latest or --steps of them, down --steps
+// or --all of them, or shows the version of the database.
+func migrateCommand(args []string) error {
+	flagSet := newFlagSet("migrate up|down|version")
+	dir := flagSet.String("dir", "migrations", "directory of the migrations")
+	steps := flagSet.Int("steps", 0, "number of migrations to apply, all of them up when 0")
+	all := flagSet.Bool("all", false, "revert all migrations with down")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("migrate takes one of up, down or version")
+	}
+	action := flagSet.Arg(0)
+	if *steps < 0 {
+		return errors.New("steps must not be negative")
+	}
+	if action == "down" && *steps == 0 && !*all {
+		return errors.New("down takes --steps or --all")
+	}
+
+	migrations, err := migrate.Read(os.DirFS(*dir))
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	_, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	migrator := migrate.NewMigrator(db, migrations)
+
+	var applied []migrate.Migration
+	switch action {
+	case "up":
+		applied, err = migrator.Up(*steps)
+	case "down":
+		applied, err = migrator.Down(*steps)
+	case "version":
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		return writeOutput(os.Stdout, *output, version, table{
+			header: []string{"VERSION", "DIRTY"},
+			rows:   [][]string{{strconv.FormatUint(uint64(version.Version), 10), strconv.FormatBool(version.Dirty)}},
+		})
+	default:
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+	// The migrations applied before a failure are reported with it.
+	t := table{header: []string{"VERSION", "MIGRATION"}}
+	for _, migration := range applied {
+		t.rows = append(t.rows, []string{strconv.FormatUint(uint64(migration.Version), 10), migration.Name})
+	}
+	if applied == nil {
+		applied = []migrate.Migration{}
+	}
+	if outputErr := writeOutput(os.Stdout, *output, applied, t); outputErr != nil && err == nil {
+		err = outputErr
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"net/url"
+	"os"
+)
+
+//go:embed seed/books.json
+var seedBooks []byte
+
+// seed adds sample books for development. A database with books is left as it is.
+func seed(args []string) error {
+	flagSet := newFlagSet("seed")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	var newBooks []models.Book
+	if err := json.Unmarshal(seedBooks, &newBooks); err != nil {
+		return err
+	}
+
+	cfg, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	books, closeBooks := newBooksService(cfg, db)
+	defer closeBooks()
+	existing, err := books.GetBooks(url.Values{"availability": {models.AvailabilityAll}})
+	if err != nil {
+		return err
+	}
+	created := make([]models.Book, 0, len(newBooks))
+	if len(existing) > 0 {
+		fmt.Fprintln(os.Stderr, "the database has books, nothing is seeded")
+	} else {
+		for _, book := range newBooks {
+			if book.ID, err = books.CreateBook(book); err != nil {
+				return fmt.Errorf("failed to create book %q: %w", book.Name, err)
+			}
+			created = append(created, book.WithDefaults())
+		}
+	}
+	return writeOutput(os.Stdout, *output, created, booksTable(created))
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/cache"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/config"
+	"github.com/TenderLimbo/rest-api/pkg/gql"
+	"github.com/TenderLimbo/rest-api/pkg/handler"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/mail"
+	"github.com/TenderLimbo/rest-api/pkg/outbox"
+	"github.com/TenderLimbo/rest-api/pkg/ratelimit"
+	"github.com/TenderLimbo/rest-api/pkg/redis"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/rpc"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func NewEventPublisher(cfg config.OutboxConfig) (outbox.EventPublisher, error) {
+	switch cfg.Publisher {
+	case "log":
+		return outbox.LogPublisher{}, nil
+	case "http":
+		return outbox.NewHTTPPublisher(cfg.URL, &http.Client{Timeout: cfg.Timeout}), nil
+	case "nats":
+		conn, err := outbox.DialNATS(cfg.NATSAddr, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return outbox.NewNATSPublisher(conn, cfg.Subject), nil
+	default:
+		return nil, fmt.Errorf("unknown publisher %q", cfg.Publisher)
+	}
+}
+
+// NewLowStockNotifiers always logs low stock alerts and emails them when recipients are configured.
+// Without an SMTP server the emails are only logged.
+func NewLowStockNotifiers(sender mail.Sender, cfg config.EmailConfig) []service.LowStockNotifier {
+	notifiers := []service.LowStockNotifier{service.LogNotifier{}}
+	if len(cfg.To) == 0 {
+		return notifiers
+	}
+	return append(notifiers, service.NewEmailNotifier(sender, cfg.From, cfg.To))
+}
+
+// NewMailSender returns the SMTP server of alerts, emails are only logged without one.
+func NewMailSender(cfg config.EmailConfig) mail.Sender {
+	if cfg.SMTPAddr != "" {
+		return mail.NewSMTPSender(cfg.SMTPAddr, cfg.Username, cfg.Password)
+	}
+	return mail.NewFake()
+}
+
+// serve runs the HTTP and gRPC servers until SIGINT or SIGTERM.
+func serve(args []string) error {
+	source, err := config.Open(args)
+	if err != nil {
+		return err
+	}
+	cfg := source.Config()
+	if err = source.OnReload(func(runtime config.Runtime) error {
+		level, err := logging.ParseLevel(runtime.LogLevel)
+		if err != nil {
+			return err
+		}
+		logging.SetLevel(level)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to set log level: %w", err)
+	}
+
+	db, err := repository.NewPostgresDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("failed to connect database: %w", err)
+	}
+	var booksCache cache.Cache = cache.NewLRU(cfg.Cache.Size)
+	var limiterStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Redis.Addr != "" {
+		redisClient := redis.NewClient(cfg.Redis.Addr, cfg.Redis.PoolSize, cfg.Redis.Timeout)
+		defer redisClient.Close()
+		booksCache = cache.NewRedis(redisClient, "restapi:")
+		limiterStore = ratelimit.NewRedisStore(redisClient, "restapi:ratelimit:")
+	}
+
+	webhooks := service.NewWebhooksService(repository.NewWebhooksPostgres(db), cfg.Webhooks)
+	// Reads are cached below the service, so promotions are applied to cached books on every read.
+	cachedBooks := service.NewCachedBooksManager(repository.NewRepository(db), booksCache, cfg.Cache.TTL)
+	books := service.NewService(cachedBooks)
+	books.UsePromotions(repository.NewPromotionsPostgres(db))
+	books.UseCurrencies(cfg.Pricing.Rates)
+	books.Subscribe(webhooks)
+	mailSender := NewMailSender(cfg.Alerts.Email)
+	alerts := service.NewAlertsService(cfg.Alerts.QueueSize, NewLowStockNotifiers(mailSender, cfg.Alerts.Email)...)
+	books.Subscribe(alerts)
+	waitlist := service.NewWaitlistService(repository.NewWaitlistPostgres(db),
+		service.NewWaitlistNotifier(mailSender, cfg.Waitlist.From, cfg.Waitlist.Timeout), clock.Real{}, cfg.Waitlist)
+	books.Subscribe(waitlist)
+	broadcaster := service.NewBroadcaster(cfg.Stream.BufferSize, cfg.Stream.QueueSize)
+	books.Subscribe(broadcaster)
+
+	publisher, err := NewEventPublisher(cfg.Outbox)
+	if err != nil {
+		return fmt.Errorf("failed to init outbox publisher: %w", err)
+	}
+	dispatcher := outbox.NewDispatcher(repository.NewOutboxPostgres(db), publisher, cfg.Outbox.Config)
+
+	// Scheduled prices are stored by the price history, the books service drops cached reads and announces them.
+	scheduler := service.NewPriceSchedulerService(repository.NewPriceHistoryPostgres(db), books, clock.Real{},
+		cfg.Scheduler.Interval)
+
+	carts := service.NewCartsService(repository.NewCartsPostgres(db), books, clock.Real{}, cfg.Carts)
+
+	idempotency := service.NewIdempotencyService(repository.NewIdempotencyKeysPostgres(db), clock.Real{},
+		cfg.Idempotency)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	// Workers are stopped on errors below too, so they do not outlive the server.
+	defer stopWorkers()
+	webhooks.Start(workersCtx)
+	dispatcher.Start(workersCtx)
+	scheduler.Start(workersCtx)
+	alerts.Start(workersCtx)
+	waitlist.Start(workersCtx)
+	carts.Start(workersCtx)
+	idempotency.Start(workersCtx)
+
+	pricing, err := service.NewPricingService(repository.NewPricesPostgres(db), cfg.Pricing.Rates)
+	if err != nil {
+		return fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+	covers := service.NewCoversService(repository.NewCoversPostgres(db), storage.NewLocalStore(cfg.Covers.Dir),
+		cfg.Covers, cachedBooks)
+
+	services := &service.Service{
+		BooksManager: books,
+		Genres:       service.NewGenresService(repository.NewGenresPostgres(db)),
+		Idempotency:  idempotency,
+		Webhooks:     webhooks,
+		Stream:       broadcaster,
+		Pricing:      pricing,
+		Promotions:   service.NewPromotionsService(repository.NewPromotionsPostgres(db)),
+		PriceHistory: scheduler,
+		Inventory:    service.NewInventoryService(repository.NewInventoryPostgres(db)),
+		Carts:        carts,
+		Reviews:      service.NewReviewsService(repository.NewReviewsPostgres(db), cachedBooks),
+		Waitlist:     waitlist,
+		Covers:       covers,
+		Locations:    service.NewLocationsService(repository.NewLocationsPostgres(db), cachedBooks),
+		Suppliers:    service.NewSuppliersService(repository.NewSuppliersPostgres(db)),
+		PurchaseOrders: service.NewPurchaseOrdersService(repository.NewPurchaseOrdersPostgres(db), books,
+			clock.Real{}),
+		Stocktakes: service.NewStocktakesService(repository.NewStocktakesPostgres(db), books, clock.Real{}),
+	}
+	limiter := ratelimit.NewLimiter(limiterStore, cfg.RateLimit)
+	// The config is validated, so the sunset is a date.
+	sunset, _ := cfg.API.Sunset()
+	if sunset.Before(time.Now()) {
+		logging.Warnf("api.legacy_sunset %s has passed but the unversioned routes are still served", cfg.API.LegacySunset)
+	}
+	graphqlSrv, err := gql.NewServer(services, cfg.GraphQL)
+	if err != nil {
+		return fmt.Errorf("failed to build graphql schema: %w", err)
+	}
+	handlers := handler.NewHandler(services, handler.Options{
+		GraphQL:         graphqlSrv,
+		Limiter:         limiter,
+		LegacySunset:    sunset,
+		StreamHeartbeat: cfg.Stream.Heartbeat,
+		Availability:    cfg.API.Availability,
+		CoverMaxSize:    cfg.Covers.MaxSize,
+		TrustedProxies:  cfg.API.TrustedProxies,
+		Config:          source,
+	})
+	// Rate limits, CORS origins and feature flags follow the config file.
+	if err = source.OnReload(func(runtime config.Runtime) error {
+		limiter.SetLimits(runtime.RateLimit)
+		handlers.SetRuntime(handler.Runtime{AllowedOrigins: runtime.CORS.AllowedOrigins, Features: runtime.Features})
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to apply runtime config: %w", err)
+	}
+	listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen grpc port: %w", err)
+	}
+	source.Watch()
+
+	srv := new(models.Server)
+	go func() {
+		if err := srv.Run(cfg.Port, handlers.InitRoutes()); err != nil {
+			log.Println("listen: ", err)
+		}
+	}()
+	adminSrv := new(models.Server)
+	if cfg.AdminAddr != "" {
+		go func() {
+			if err := adminSrv.RunAddr(cfg.AdminAddr, handlers.InitAdminRoutes()); err != nil {
+				log.Println("admin listen: ", err)
+			}
+		}()
+	}
+
+	grpcSrv := rpc.NewServer(services)
+	go func() {
+		if err := grpcSrv.Serve(listener); err != nil {
+			log.Println("grpc serve: ", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	grpcSrv.GracefulStop()
+	broadcaster.Close()
+	// The workers are stopped and waited for even when the server did not shut down in time.
+	shutdownErr := srv.Shutdown(ctx)
+	if shutdownErr != nil {
+		shutdownErr = fmt.Errorf("server forced to shutdown: %w", shutdownErr)
+	}
+	if cfg.AdminAddr != "" {
+		if err = adminSrv.Shutdown(ctx); err != nil {
+			log.Println("admin server forced to shutdown: ", err)
+		}
+	}
+	stopWorkers()
+	webhooks.Wait()
+	dispatcher.Wait()
+	scheduler.Wait()
+	alerts.Wait()
+	waitlist.Wait()
+	carts.Wait()
+	idempotency.Wait()
+	log.Println("Server exiting")
+	return shutdownErr
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin/binding"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func createUser(args []string) error {
+	flagSet := newFlagSet("users create EMAIL")
+	name := flagSet.String("name", "", "name of the user")
+	output := addOutputFlag(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if flagSet.NArg() != 1 {
+		return errors.New("create takes the email of the user")
+	}
+	user := models.User{Email: strings.TrimSpace(flagSet.Arg(0)), Name: strings.TrimSpace(*name)}
+	if err := binding.Validator.ValidateStruct(&user); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
+	_, db, err := openDatabase(flagSet)
+	if err != nil {
+		return err
+	}
+	if user, err = service.NewUsersService(repository.NewUsersPostgres(db)).CreateUser(user); err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, *output, user, table{
+		header: []string{"ID", "EMAIL", "NAME"},
+		rows:   [][]string{{strconv.Itoa(user.ID), user.Email, user.Name}},
+	})
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reviews;
+
+ALTER TABLE books DROP COLUMN IF EXISTS review_count;
+ALTER TABLE books DROP COLUMN IF EXISTS avg_rating;
@@ -0,0 +1,17 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS avg_rating NUMERIC(3, 2) NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS review_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS reviews (
+                                       id SERIAL PRIMARY KEY,
+                                       book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                       author VARCHAR(100) NOT NULL,
+                                       rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+                                       text VARCHAR(2000) NOT NULL DEFAULT '',
+                                       status VARCHAR(20) NOT NULL DEFAULT 'pending',
+                                       token_hash CHAR(64) NOT NULL,
+                                       created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                       updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS reviews_book_idx ON reviews (book_id, status, created_at);
+CREATE INDEX IF NOT EXISTS reviews_status_idx ON reviews (status, created_at);
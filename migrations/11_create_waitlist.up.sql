@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+                                                id SERIAL PRIMARY KEY,
+                                                book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                                email VARCHAR(254) NOT NULL DEFAULT '',
+                                                url VARCHAR(2000) NOT NULL DEFAULT '',
+                                                created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                                notified_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_pending_idx ON waitlist_entries (book_id, id) WHERE notified_at IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS stock_transfers;
+
+DROP TABLE IF EXISTS stock_levels;
+
+DROP TABLE IF EXISTS locations;
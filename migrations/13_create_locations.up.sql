@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS locations (
+                                         id SERIAL PRIMARY KEY,
+                                         name VARCHAR(100) NOT NULL UNIQUE,
+                                         is_default BOOLEAN NOT NULL DEFAULT FALSE,
+                                         created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS locations_default_idx ON locations (is_default) WHERE is_default;
+
+INSERT INTO locations (name, is_default) VALUES ('Warehouse', TRUE) ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+                                            book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                            location_id INT NOT NULL REFERENCES locations (id),
+                                            amount INT NOT NULL DEFAULT 0 CHECK (amount >= 0),
+                                            PRIMARY KEY (book_id, location_id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_levels_location_idx ON stock_levels (location_id, book_id);
+
+-- The stock so far is all at the default location.
+INSERT INTO stock_levels (book_id, location_id, amount)
+SELECT id, (SELECT id FROM locations WHERE is_default), amount FROM books WHERE amount > 0
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS stock_transfers (
+                                               id SERIAL PRIMARY KEY,
+                                               book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                               from_location_id INT NOT NULL REFERENCES locations (id),
+                                               to_location_id INT NOT NULL REFERENCES locations (id),
+                                               quantity INT NOT NULL CHECK (quantity > 0),
+                                               created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
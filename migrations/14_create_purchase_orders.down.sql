@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS purchase_order_status_changes;
+
+DROP TABLE IF EXISTS purchase_order_receipts;
+
+DROP TABLE IF EXISTS purchase_order_items;
+
+DROP TABLE IF EXISTS purchase_orders;
+
+DROP TABLE IF EXISTS suppliers;
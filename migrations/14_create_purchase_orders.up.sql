@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+                                         id SERIAL PRIMARY KEY,
+                                         name VARCHAR(100) NOT NULL UNIQUE,
+                                         email VARCHAR(254) NOT NULL DEFAULT '',
+                                         created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+                                               id SERIAL PRIMARY KEY,
+                                               supplier_id INT NOT NULL REFERENCES suppliers (id),
+                                               status VARCHAR(20) NOT NULL DEFAULT 'draft',
+                                               created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                               updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_supplier_idx ON purchase_orders (supplier_id, status);
+
+CREATE TABLE IF NOT EXISTS purchase_order_items (
+                                                    purchase_order_id INT NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+                                                    book_id INT NOT NULL REFERENCES books (id),
+                                                    quantity INT NOT NULL CHECK (quantity > 0),
+                                                    received INT NOT NULL DEFAULT 0 CHECK (received >= 0 AND received <= quantity),
+                                                    unit_cost NUMERIC(10, 2) NOT NULL,
+                                                    PRIMARY KEY (purchase_order_id, book_id)
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_receipts (
+                                                       id SERIAL PRIMARY KEY,
+                                                       purchase_order_id INT NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+                                                       book_id INT NOT NULL REFERENCES books (id),
+                                                       location_id INT NOT NULL REFERENCES locations (id),
+                                                       quantity INT NOT NULL CHECK (quantity > 0),
+                                                       unit_cost NUMERIC(10, 2) NOT NULL,
+                                                       received_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS purchase_order_receipts_order_idx ON purchase_order_receipts (purchase_order_id);
+
+CREATE TABLE IF NOT EXISTS purchase_order_status_changes (
+                                                             id SERIAL PRIMARY KEY,
+                                                             purchase_order_id INT NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+                                                             status VARCHAR(20) NOT NULL,
+                                                             changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS purchase_order_status_changes_order_idx ON purchase_order_status_changes (purchase_order_id);
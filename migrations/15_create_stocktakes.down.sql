@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS stocktake_adjustments;
+
+DROP TABLE IF EXISTS stocktake_items;
+
+DROP TABLE IF EXISTS stocktakes;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS stocktakes (
+                                          id SERIAL PRIMARY KEY,
+                                          genre INT NOT NULL DEFAULT 0,
+                                          location_id INT REFERENCES locations (id),
+                                          status VARCHAR(20) NOT NULL DEFAULT 'open',
+                                          created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                          finalized_at TIMESTAMP
+);
+
+-- Expected is the stock of the book when the stocktake was opened, counted is NULL until the book is counted.
+CREATE TABLE IF NOT EXISTS stocktake_items (
+                                               stocktake_id INT NOT NULL REFERENCES stocktakes (id) ON DELETE CASCADE,
+                                               book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                               expected INT NOT NULL,
+                                               counted INT CHECK (counted >= 0),
+                                               PRIMARY KEY (stocktake_id, book_id)
+);
+
+CREATE TABLE IF NOT EXISTS stocktake_adjustments (
+                                                     id SERIAL PRIMARY KEY,
+                                                     stocktake_id INT NOT NULL REFERENCES stocktakes (id) ON DELETE CASCADE,
+                                                     book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                                     expected INT NOT NULL,
+                                                     counted INT NOT NULL,
+                                                     previous_amount INT NOT NULL,
+                                                     amount INT NOT NULL,
+                                                     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS stocktake_adjustments_stocktake_idx ON stocktake_adjustments (stocktake_id);
@@ -0,0 +1,2 @@
+-- The sequence stays past the existing genres.
+SELECT 1;
@@ -0,0 +1,2 @@
+-- Genres were inserted with their ids, so the sequence was never advanced past them.
+SELECT setval('genres_id_seq', (SELECT MAX(id) FROM genres));
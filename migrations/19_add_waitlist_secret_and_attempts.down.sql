@@ -0,0 +1,2 @@
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS attempts;
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS secret;
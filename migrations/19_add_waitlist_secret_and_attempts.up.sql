@@ -0,0 +1,2 @@
+ALTER TABLE waitlist_entries ADD COLUMN IF NOT EXISTS secret VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE waitlist_entries ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+                                     id SERIAL PRIMARY KEY,
+                                     email VARCHAR(254) NOT NULL UNIQUE,
+                                     name VARCHAR(100) NOT NULL DEFAULT '',
+                                     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+-- Carts created before tokens have none that matches, their holds expire.
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS token_hash CHAR(64) NOT NULL DEFAULT '';
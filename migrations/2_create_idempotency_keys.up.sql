@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+                                                key VARCHAR(300) PRIMARY KEY,
+                                                request_hash VARCHAR(64) NOT NULL,
+                                                status_code INT NOT NULL DEFAULT 0,
+                                                body BYTEA,
+                                                expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+                                                     id SERIAL PRIMARY KEY,
+                                                     url VARCHAR(2000) NOT NULL,
+                                                     events TEXT NOT NULL,
+                                                     secret VARCHAR(64) NOT NULL,
+                                                     active BOOLEAN NOT NULL DEFAULT TRUE,
+                                                     failure_count INT NOT NULL DEFAULT 0,
+                                                     created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+                                                  id SERIAL PRIMARY KEY,
+                                                  subscription_id INT NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+                                                  event_type VARCHAR(50) NOT NULL,
+                                                  payload TEXT NOT NULL,
+                                                  attempt INT NOT NULL,
+                                                  status_code INT NOT NULL DEFAULT 0,
+                                                  error TEXT NOT NULL DEFAULT '',
+                                                  success BOOLEAN NOT NULL,
+                                                  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_id_idx ON webhook_deliveries (subscription_id, id);
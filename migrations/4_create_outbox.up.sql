@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+                                      id BIGSERIAL PRIMARY KEY,
+                                      aggregate_type VARCHAR(50) NOT NULL,
+                                      aggregate_id INT NOT NULL,
+                                      event_type VARCHAR(50) NOT NULL,
+                                      payload JSONB NOT NULL,
+                                      created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                      published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
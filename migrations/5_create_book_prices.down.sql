@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS book_prices;
+
+ALTER TABLE books DROP COLUMN IF EXISTS currency;
+ALTER TABLE books ALTER COLUMN price TYPE NUMERIC(8);
@@ -0,0 +1,10 @@
+-- Prices were rounded to whole units by NUMERIC(8), the lost cents cannot be restored.
+ALTER TABLE books ALTER COLUMN price TYPE NUMERIC(10, 2);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS book_prices (
+                                           book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                           currency CHAR(3) NOT NULL,
+                                           price NUMERIC(10, 2) NOT NULL,
+                                           PRIMARY KEY (book_id, currency)
+);
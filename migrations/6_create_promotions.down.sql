@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS promotions;
+
+ALTER TABLE books DROP COLUMN IF EXISTS author;
@@ -0,0 +1,16 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS author VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS promotions (
+                                          id SERIAL PRIMARY KEY,
+                                          name VARCHAR(100) NOT NULL,
+                                          type VARCHAR(20) NOT NULL,
+                                          value NUMERIC(10, 2) NOT NULL,
+                                          scope VARCHAR(20) NOT NULL,
+                                          target VARCHAR(100) NOT NULL,
+                                          starts_at TIMESTAMP NOT NULL,
+                                          ends_at TIMESTAMP NOT NULL,
+                                          priority INT NOT NULL DEFAULT 0,
+                                          stackable BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS promotions_period_idx ON promotions (starts_at, ends_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS price_history (
+                                             id BIGSERIAL PRIMARY KEY,
+                                             book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                             price NUMERIC(10, 2) NOT NULL,
+                                             currency CHAR(3) NOT NULL,
+                                             changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS price_history_book_idx ON price_history (book_id, changed_at);
+
+-- Books keep their current price as the first entry of their history.
+INSERT INTO price_history (book_id, price, currency) SELECT id, price, currency FROM books;
+
+CREATE TABLE IF NOT EXISTS scheduled_prices (
+                                                id SERIAL PRIMARY KEY,
+                                                book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                                price NUMERIC(10, 2) NOT NULL,
+                                                effective_at TIMESTAMP NOT NULL,
+                                                applied_at TIMESTAMP,
+                                                created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_prices_due_idx ON scheduled_prices (effective_at) WHERE applied_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS books_low_stock_idx;
+
+ALTER TABLE books DROP COLUMN IF EXISTS reorder_threshold;
@@ -0,0 +1,3 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS reorder_threshold INT NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0);
+
+CREATE INDEX IF NOT EXISTS books_low_stock_idx ON books (id) WHERE amount <= reorder_threshold;
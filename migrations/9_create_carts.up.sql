@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS carts (
+                                     id SERIAL PRIMARY KEY,
+                                     status VARCHAR(20) NOT NULL DEFAULT 'open',
+                                     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+                                     checked_out_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+                                          cart_id INT NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+                                          book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+                                          quantity INT NOT NULL CHECK (quantity > 0),
+                                          expires_at TIMESTAMP NOT NULL,
+                                          PRIMARY KEY (cart_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS cart_items_holds_idx ON cart_items (book_id, expires_at);
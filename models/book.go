@@ -1,14 +1,75 @@
 package models
 
 type Book struct {
-	ID     int     `json:"id"`
-	Name   string  `json:"name" binding:"min=1,max=100"`
-	Price  float64 `json:"price" binding:"min=0"`
-	Genre  int     `json:"genre" binding:"min=1,max=3"`
-	Amount int     `json:"amount" binding:"min=0"`
+	ID       int    `json:"id"`
+	Name     string `json:"name" binding:"min=1,max=100"`
+	Author   string `json:"author,omitempty" binding:"max=100"`
+	Price    Money  `json:"price" binding:"min=0"`
+	Currency string `json:"currency" binding:"omitempty,len=3,uppercase"`
+	Genre    int    `json:"genre" binding:"min=1,max=3"`
+	Amount   int    `json:"amount" binding:"min=0"`
+	// ReorderThreshold is the amount at or below which the book is low on stock.
+	ReorderThreshold int `json:"reorder_threshold" binding:"min=0"`
+	// AvgRating and ReviewCount summarize the approved reviews, they are kept by the reviews.
+	AvgRating   float64 `json:"avg_rating"`
+	ReviewCount int     `json:"review_count"`
+	// Cover is the file name of the uploaded cover image, empty without one.
+	Cover string `json:"-"`
+	// Held is the amount held by unexpired holds of open carts, it is computed on reads and never stored.
+	Held int `json:"held,omitempty" gorm:"->"`
+	// EffectivePrice, Promotions, Available and the cover URLs are computed on reads and never stored.
+	EffectivePrice  *Money             `json:"effective_price,omitempty" gorm:"-"`
+	Promotions      []AppliedPromotion `json:"promotions,omitempty" gorm:"-"`
+	Available       bool               `json:"available" gorm:"-"`
+	CoverURL        string             `json:"cover_url,omitempty" gorm:"-"`
+	CoverThumbnails map[string]string  `json:"cover_thumbnails,omitempty" gorm:"-"`
+}
+
+// Values of the availability filter of book lists.
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+	AvailabilityAll        = "all"
+)
+
+func ValidAvailability(availability string) bool {
+	switch availability {
+	case AvailabilityInStock, AvailabilityOutOfStock, AvailabilityAll:
+		return true
+	}
+	return false
+}
+
+// SortRating sorts book lists by the rating of their reviews, best first.
+const SortRating = "rating"
+
+// WithDefaults returns the book with the default currency if it has none.
+func (b Book) WithDefaults() Book {
+	if b.Currency == "" {
+		b.Currency = DefaultCurrency
+	}
+	return b
+}
+
+// InStock reports whether some of the book can be sold, the amount held by carts cannot.
+func (b Book) InStock() bool {
+	return b.Amount > b.Held
+}
+
+// CrossedReorderThreshold reports whether a stock change from previousAmount made the book low on stock.
+func (b Book) CrossedReorderThreshold(previousAmount int) bool {
+	return previousAmount > b.ReorderThreshold && b.Amount <= b.ReorderThreshold
 }
 
 type Genre struct {
 	ID   int    `json:"id"`
-	Name string `json:"name"`
+	Name string `json:"name" binding:"min=1,max=100"`
+}
+
+// BookPrice is the price of a book in a currency other than its own. It takes
+// precedence over the conversion with exchange rates.
+type BookPrice struct {
+	BookID   int    `json:"book_id" gorm:"primaryKey"`
+	Currency string `json:"currency" gorm:"primaryKey" binding:"len=3,uppercase"`
+	Price    Money  `json:"price" binding:"min=0"`
 }
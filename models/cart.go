@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	CartOpen       = "open"
+	CartCheckedOut = "checked_out"
+)
+
+// Cart holds books for a customer. Its items keep the books out of other carts until they expire.
+type Cart struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	// Token lets the customer use the cart, it is only returned on creation.
+	Token        string     `json:"token,omitempty" gorm:"-"`
+	TokenHash    string     `json:"-"`
+	Items        []CartItem `json:"items" gorm:"foreignKey:CartID"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CheckedOutAt *time.Time `json:"checked_out_at,omitempty"`
+}
+
+// CartItem is a hold of Quantity books until ExpiresAt, after checkout it is a line of the order.
+type CartItem struct {
+	CartID    int       `json:"-" gorm:"primaryKey"`
+	BookID    int       `json:"book_id" gorm:"primaryKey"`
+	Quantity  int       `json:"quantity" binding:"min=1"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
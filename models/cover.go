@@ -0,0 +1,21 @@
+package models
+
+import "strings"
+
+// CoverThumbnails are the widths of the thumbnails generated for every cover, by name.
+var CoverThumbnails = map[string]int{
+	"small":  160,
+	"medium": 480,
+}
+
+// CoverFile returns the file name of the cover in the size, the original without one.
+func CoverFile(cover, size string) string {
+	if size == "" {
+		return cover
+	}
+	i := strings.LastIndex(cover, ".")
+	if i < 0 {
+		i = len(cover)
+	}
+	return cover[:i] + "-" + size + cover[i:]
+}
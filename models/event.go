@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	EventBookCreated    = "book.created"
+	EventBookUpdated    = "book.updated"
+	EventBookDeleted    = "book.deleted"
+	EventBookOutOfStock = "book.out_of_stock"
+	EventBookLowStock   = "book.low_stock"
+	// EventBookBackInStock is emitted when the amount of a book goes from 0 to positive.
+	EventBookBackInStock = "book.back_in_stock"
+)
+
+var EventTypes = []string{EventBookCreated, EventBookUpdated, EventBookDeleted, EventBookOutOfStock, EventBookLowStock,
+	EventBookBackInStock}
+
+type Event struct {
+	Type       string    `json:"type"`
+	BookID     int       `json:"book_id"`
+	Book       *Book     `json:"book,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// StreamEvent is an event numbered by the broadcaster, the ID lets stream clients resume.
+type StreamEvent struct {
+	ID int64
+	Event
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type IdempotencyRecord struct {
+	Key         string `gorm:"primaryKey"`
+	RequestHash string
+	StatusCode  int
+	Header      ResponseHeader
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+// ResponseHeader is stored as a JSON column.
+type ResponseHeader http.Header
+
+func (h ResponseHeader) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(h)
+	return string(b), err
+}
+
+func (h *ResponseHeader) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case string:
+		b = []byte(src)
+	case []byte:
+		b = src
+	case nil:
+	default:
+		return fmt.Errorf("unsupported response header type %T", src)
+	}
+	*h = nil
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, h)
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Location is a shop or a warehouse holding stock. Stock added through book updates goes to
+// the default location, and sold stock is taken from it first.
+type Location struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" binding:"min=1,max=100"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StockLevel is the amount of a book at a location. The amount of a book is the total of its stock levels.
+type StockLevel struct {
+	BookID     int `json:"book_id" gorm:"primaryKey;autoIncrement:false"`
+	LocationID int `json:"location_id" gorm:"primaryKey;autoIncrement:false"`
+	Amount     int `json:"amount"`
+}
+
+// StockTransfer moves stock of a book between locations.
+type StockTransfer struct {
+	ID             int       `json:"id"`
+	BookID         int       `json:"book_id" binding:"required,min=1"`
+	FromLocationID int       `json:"from_location_id" binding:"required,min=1"`
+	ToLocationID   int       `json:"to_location_id" binding:"required,min=1,nefield=FromLocationID"`
+	Quantity       int       `json:"quantity" binding:"min=1"`
+	CreatedAt      time.Time `json:"created_at"`
+}
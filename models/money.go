@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of books created without one.
+const DefaultCurrency = "USD"
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+// Money is an amount in minor units (cents). It is written as a decimal string to JSON
+// and to NUMERIC columns, so prices never pass through a float on the way.
+type Money int64
+
+// ParseMoney parses a decimal amount like "67.88" with at most two fraction digits.
+func ParseMoney(s string) (Money, error) {
+	negative := strings.HasPrefix(s, "-")
+	units, cents := strings.TrimPrefix(s, "-"), ""
+	if i := strings.IndexByte(units, '.'); i >= 0 {
+		units, cents = units[:i], units[i+1:]
+	}
+	if units == "" || len(cents) > 2 || strings.ContainsAny(units+cents, "+-") {
+		return 0, errInvalidMoney
+	}
+	cents += strings.Repeat("0", 2-len(cents))
+	value, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil {
+		return 0, errInvalidMoney
+	}
+	if negative {
+		value = -value
+	}
+	return Money(value), nil
+}
+
+func (m Money) String() string {
+	sign, value := "", int64(m)
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON accepts a decimal string as well as a plain JSON number sent by older clients.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	value, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case []byte:
+		*m, err = ParseMoney(string(v))
+	case string:
+		*m, err = ParseMoney(v)
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = Money(math.Round(v * 100))
+	default:
+		err = fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedMoney Money
+		expectError   bool
+	}{
+		{input: "67.88", expectedMoney: 6788},
+		{input: "67.8", expectedMoney: 6780},
+		{input: "67", expectedMoney: 6700},
+		{input: "0.05", expectedMoney: 5},
+		{input: "-1.50", expectedMoney: -150},
+		{input: "67.885", expectError: true},
+		{input: ".5", expectError: true},
+		{input: "1e3", expectError: true},
+		{input: "1.-5", expectError: true},
+		{input: "", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			money, err := ParseMoney(test.input)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedMoney, money)
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var book Book
+	assert.NoError(t, json.Unmarshal([]byte(`{"price": 67.88}`), &book))
+	assert.Equal(t, Money(6788), book.Price)
+	assert.NoError(t, json.Unmarshal([]byte(`{"price": "0.1"}`), &book))
+	assert.Equal(t, Money(10), book.Price)
+
+	data, err := json.Marshal(Money(-5))
+	assert.NoError(t, err)
+	assert.Equal(t, `"-0.05"`, string(data))
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const AggregateBook = "book"
+
+type OutboxMessage struct {
+	ID            int64
+	AggregateType string
+	AggregateID   int
+	EventType     string
+	Payload       []byte
+	CreatedAt     time.Time
+	PublishedAt   *time.Time
+	ClaimedUntil  *time.Time // set while a dispatcher publishes the message
+	Attempts      int        // failed attempts to publish the message
+	LastError     *string
+	RetryAt       *time.Time // the message and the later ones of its aggregate wait until then after a failure
+	FailedAt      *time.Time // set once the message is given up on, it is not published anymore
+}
+
+// OutboxFailure is a failed attempt to publish the outbox message ID, it is tried again at RetryAt.
+type OutboxFailure struct {
+	ID      int64
+	Error   string
+	RetryAt time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
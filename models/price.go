@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PriceChange records the price a book had from ChangedAt on.
+type PriceChange struct {
+	ID        int64     `json:"-"`
+	BookID    int       `json:"book_id"`
+	Price     Money     `json:"price"`
+	Currency  string    `json:"currency"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (PriceChange) TableName() string {
+	return "price_history"
+}
+
+// ScheduledPrice is applied to the book by the scheduler once EffectiveAt has passed.
+type ScheduledPrice struct {
+	ID          int        `json:"id"`
+	BookID      int        `json:"book_id"`
+	Price       Money      `json:"price" binding:"min=0"`
+	EffectiveAt time.Time  `json:"effective_at" binding:"required"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
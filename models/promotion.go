@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	PromotionPercentage = "percentage"
+	PromotionFixed      = "fixed"
+
+	PromotionScopeGenre  = "genre"
+	PromotionScopeBook   = "book"
+	PromotionScopeAuthor = "author"
+)
+
+// Promotion discounts the books of its scope while it runs. Target is the genre ID,
+// the book ID or the author name. Value is a percentage for percentage promotions
+// and an amount in the currency of the book for fixed ones.
+type Promotion struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" binding:"min=1,max=100"`
+	Type      string    `json:"type" binding:"oneof=percentage fixed"`
+	Value     Money     `json:"value" binding:"gt=0"`
+	Scope     string    `json:"scope" binding:"oneof=genre book author"`
+	Target    string    `json:"target" binding:"min=1,max=100"`
+	StartsAt  time.Time `json:"starts_at" binding:"required"`
+	EndsAt    time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Priority  int       `json:"priority"`
+	Stackable bool      `json:"stackable"`
+}
+
+// AppliedPromotion is a promotion that lowered the effective price of a book.
+type AppliedPromotion struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value Money  `json:"value"`
+}
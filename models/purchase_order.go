@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Supplier sells books to the store through purchase orders.
+type Supplier struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" binding:"min=1,max=100"`
+	Email     string    `json:"email,omitempty" binding:"omitempty,email,max=254"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderSent              = "sent"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+func ValidPurchaseOrderStatus(status string) bool {
+	switch status {
+	case PurchaseOrderDraft, PurchaseOrderSent, PurchaseOrderPartiallyReceived, PurchaseOrderReceived, PurchaseOrderCancelled:
+		return true
+	}
+	return false
+}
+
+// purchaseOrderTransitions lists the statuses staff can move a purchase order to from each status.
+// Received statuses are only reached by receiving books.
+var purchaseOrderTransitions = map[string][]string{
+	PurchaseOrderDraft:             {PurchaseOrderSent, PurchaseOrderCancelled},
+	PurchaseOrderSent:              {PurchaseOrderCancelled},
+	PurchaseOrderPartiallyReceived: {PurchaseOrderCancelled},
+}
+
+// CanChangePurchaseOrderStatus reports whether staff can move a purchase order from one status to the other.
+func CanChangePurchaseOrderStatus(from, to string) bool {
+	for _, status := range purchaseOrderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReceive reports whether books of a purchase order in the status can be received.
+func CanReceive(status string) bool {
+	return status == PurchaseOrderSent || status == PurchaseOrderPartiallyReceived
+}
+
+// PurchaseOrder orders books from a supplier. Receiving its books adds them to the stock.
+type PurchaseOrder struct {
+	ID         int                         `json:"id"`
+	SupplierID int                         `json:"supplier_id" binding:"required,min=1"`
+	Status     string                      `json:"status"`
+	Items      []PurchaseOrderItem         `json:"items" gorm:"foreignKey:PurchaseOrderID" binding:"required,min=1,dive"`
+	Receipts   []PurchaseOrderReceipt      `json:"receipts" gorm:"foreignKey:PurchaseOrderID"`
+	History    []PurchaseOrderStatusChange `json:"history" gorm:"foreignKey:PurchaseOrderID"`
+	CreatedAt  time.Time                   `json:"created_at"`
+	UpdatedAt  time.Time                   `json:"updated_at"`
+}
+
+// PurchaseOrderItem is a line of a purchase order, UnitCost is the cost agreed with the supplier.
+type PurchaseOrderItem struct {
+	PurchaseOrderID int   `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	BookID          int   `json:"book_id" gorm:"primaryKey;autoIncrement:false" binding:"required,min=1"`
+	Quantity        int   `json:"quantity" binding:"min=1"`
+	Received        int   `json:"received"`
+	UnitCost        Money `json:"unit_cost" binding:"min=0"`
+}
+
+// PurchaseOrderReceipt records books of a purchase order received at a location and what they cost.
+type PurchaseOrderReceipt struct {
+	ID              int `json:"id"`
+	PurchaseOrderID int `json:"-"`
+	BookID          int `json:"book_id" binding:"required,min=1"`
+	// LocationID is the location the books are put in, the default location when 0.
+	LocationID int `json:"location_id" binding:"min=0"`
+	Quantity   int `json:"quantity" binding:"min=1"`
+	// UnitCost is what each of the books cost, the cost agreed for the item when not given.
+	UnitCost   *Money    `json:"unit_cost" binding:"omitempty,min=0"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Delivery is the books of a purchase order received at once.
+type Delivery struct {
+	Items []PurchaseOrderReceipt `json:"items" binding:"required,min=1,dive"`
+}
+
+type PurchaseOrderStatusChange struct {
+	ID              int       `json:"-"`
+	PurchaseOrderID int       `json:"-"`
+	Status          string    `json:"status"`
+	ChangedAt       time.Time `json:"changed_at"`
+}
+
+// OutstandingOrders sums up the books ordered from a supplier that are still to be received.
+type OutstandingOrders struct {
+	SupplierID   int    `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	Orders       int    `json:"orders"`
+	Books        int    `json:"books"`
+	// Cost is the cost of the books still to be received at the agreed unit costs.
+	Cost Money `json:"cost"`
+}
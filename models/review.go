@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review of a book, it is public and counted in the rating of the book once approved.
+type Review struct {
+	ID     int    `json:"id"`
+	BookID int    `json:"book_id"`
+	Author string `json:"author" binding:"min=1,max=100"`
+	Rating int    `json:"rating" binding:"min=1,max=5"`
+	Text   string `json:"text" binding:"max=2000"`
+	Status string `json:"status"`
+	// Token lets the author edit or delete the review, it is only returned on creation.
+	Token     string    `json:"token,omitempty" gorm:"-"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Page selects a part of a list.
+type Page struct {
+	Limit  int
+	Offset int
+}
@@ -11,8 +11,12 @@ type Server struct {
 }
 
 func (s *Server) Run(port string, router *gin.Engine) error {
+	return s.RunAddr(":"+port, router)
+}
+
+func (s *Server) RunAddr(addr string, router *gin.Engine) error {
 	s.httpServer = &http.Server{
-		Addr:    ":" + port,
+		Addr:    addr,
 		Handler: router,
 	}
 	return s.httpServer.ListenAndServe()
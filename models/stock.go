@@ -0,0 +1,8 @@
+package models
+
+// StockChange is a committed change of the amount of a book, by a book update or a checkout
+// and the like. Book holds the state after the change.
+type StockChange struct {
+	PreviousAmount int
+	Book           Book
+}
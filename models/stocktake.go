@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	StocktakeOpen      = "open"
+	StocktakeFinalized = "finalized"
+)
+
+// Stocktake is a session of counting books. It counts the books of the genre, or all books without one,
+// at the location when it is set and otherwise their amount.
+type Stocktake struct {
+	ID          int                   `json:"id"`
+	Genre       int                   `json:"genre,omitempty" binding:"required_without=LocationID,omitempty,min=1,max=3"`
+	LocationID  *int                  `json:"location_id,omitempty" binding:"omitempty,min=1"`
+	Status      string                `json:"status"`
+	Items       []StocktakeItem       `json:"items,omitempty" gorm:"foreignKey:StocktakeID"`
+	Adjustments []StocktakeAdjustment `json:"adjustments,omitempty" gorm:"foreignKey:StocktakeID"`
+	CreatedAt   time.Time             `json:"created_at"`
+	FinalizedAt *time.Time            `json:"finalized_at,omitempty"`
+}
+
+// StocktakeItem is a book to count. Expected is its stock when the stocktake was opened, Counted is nil until it is counted.
+type StocktakeItem struct {
+	StocktakeID int  `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	BookID      int  `json:"book_id" gorm:"primaryKey;autoIncrement:false"`
+	Expected    int  `json:"expected"`
+	Counted     *int `json:"counted"`
+}
+
+// StocktakeCount is a counted quantity of a book. Scans add it to the count so far instead of replacing it.
+type StocktakeCount struct {
+	BookID   int `json:"book_id" binding:"required,min=1"`
+	Quantity int `json:"quantity" binding:"min=0"`
+}
+
+type StocktakeCounts struct {
+	Counts []StocktakeCount `json:"counts" binding:"required,min=1,dive"`
+}
+
+// StocktakeVariance compares the count of a book with its stock when the stocktake was opened.
+type StocktakeVariance struct {
+	BookID   int `json:"book_id"`
+	Expected int `json:"expected"`
+	Counted  int `json:"counted"`
+	Variance int `json:"variance"`
+}
+
+// StocktakeAdjustment records a correction of the stock applied by a finalized stocktake.
+// The variance is applied to the stock at finalization, so books sold during the count stay sold.
+type StocktakeAdjustment struct {
+	ID             int       `json:"-"`
+	StocktakeID    int       `json:"-"`
+	BookID         int       `json:"book_id"`
+	Expected       int       `json:"expected"`
+	Counted        int       `json:"counted"`
+	PreviousAmount int       `json:"previous_amount"`
+	Amount         int       `json:"amount"`
+	CreatedAt      time.Time `json:"created_at"`
+}
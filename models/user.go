@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// User is an account of the people running the shop, created with the users command.
+type User struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email" binding:"required,email,max=254"`
+	Name      string    `json:"name" binding:"max=100"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// WaitlistEntry subscribes an email address or a webhook URL to a book coming back in stock.
+// Subscribers are notified once, in the order they joined. Events posted to URLs are signed with Secret.
+type WaitlistEntry struct {
+	ID         int        `json:"id"`
+	BookID     int        `json:"book_id"`
+	Email      string     `json:"email,omitempty" binding:"required_without=URL,excluded_with=URL,omitempty,email,max=254"`
+	URL        string     `json:"url,omitempty" binding:"omitempty,url,startswith=https://,max=2000"`
+	Secret     string     `json:"secret,omitempty"`
+	Attempts   int        `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+}
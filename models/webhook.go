@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventList is stored as a comma separated column.
+type EventList []string
+
+func (l EventList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+func (l *EventList) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	case nil:
+	default:
+		return fmt.Errorf("unsupported event list type %T", src)
+	}
+	*l = nil
+	if s != "" {
+		*l = strings.Split(s, ",")
+	}
+	return nil
+}
+
+func (l EventList) Has(eventType string) bool {
+	for _, e := range l {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookSubscription struct {
+	ID           int       `json:"id"`
+	URL          string    `json:"url" binding:"required,url,startswith=https://,max=2000"`
+	Events       EventList `json:"events" binding:"required,min=1,dive,oneof=book.created book.updated book.deleted book.out_of_stock book.low_stock book.back_in_stock"`
+	Secret       string    `json:"secret,omitempty"`
+	Active       *bool     `json:"active"` // left unchanged by updates without it
+	FailureCount int       `json:"failure_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int       `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	EventType      string    `json:"event_type"`
+	Payload        string    `json:"payload"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"status_code"`
+	Error          string    `json:"error,omitempty"`
+	Success        bool      `json:"success"`
+	CreatedAt      time.Time `json:"created_at"`
+}
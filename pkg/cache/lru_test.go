@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	assert.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+
+	assert.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok, "least recently used entry must be evicted")
+
+	now = now.Add(2 * time.Minute)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.False(t, ok, "expired entry must not be returned")
+
+	value, ok, err := c.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), value)
+
+	assert.NoError(t, c.Delete(ctx, "c"))
+	_, ok, _ = c.Get(ctx, "c")
+	assert.False(t, ok)
+}
@@ -0,0 +1,502 @@
+// Package config loads the configuration of the server. Values come from, in increasing precedence,
+// the defaults below, configs/config.yml, a .env file, APP_ environment variables and command line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/gql"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/outbox"
+	"github.com/TenderLimbo/rest-api/pkg/ratelimit"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/joho/godotenv"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"io/fs"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variables of configuration keys, e.g. APP_DB_HOST sets db.host.
+const EnvPrefix = "APP"
+
+// DateLayout is the layout of the dates in the configuration.
+const DateLayout = "2006-01-02"
+
+type Config struct {
+	Port        string                     `mapstructure:"port"`
+	GRPCPort    string                     `mapstructure:"grpc_port"`
+	AdminAddr   string                     `mapstructure:"admin_addr"`
+	LogLevel    string                     `mapstructure:"log_level"`
+	CORS        CORSConfig                 `mapstructure:"cors"`
+	Features    map[string]bool            `mapstructure:"features"`
+	API         APIConfig                  `mapstructure:"api"`
+	DB          repository.Config          `mapstructure:"db"`
+	RateLimit   map[string]ratelimit.Limit `mapstructure:"ratelimit"`
+	Idempotency service.IdempotencyConfig  `mapstructure:"idempotency"`
+	GraphQL     gql.Limits                 `mapstructure:"graphql"`
+	Webhooks    service.WebhooksConfig     `mapstructure:"webhooks"`
+	Outbox      OutboxConfig               `mapstructure:"outbox"`
+	Pricing     PricingConfig              `mapstructure:"pricing"`
+	Alerts      AlertsConfig               `mapstructure:"alerts"`
+	Waitlist    service.WaitlistConfig     `mapstructure:"waitlist"`
+	Covers      service.CoversConfig       `mapstructure:"covers"`
+	Carts       service.CartsConfig        `mapstructure:"carts"`
+	Scheduler   SchedulerConfig            `mapstructure:"scheduler"`
+	Stream      StreamConfig               `mapstructure:"stream"`
+	Cache       CacheConfig                `mapstructure:"cache"`
+	Redis       RedisConfig                `mapstructure:"redis"`
+}
+
+type APIConfig struct {
+	LegacySunset string `mapstructure:"legacy_sunset"`
+	// Availability is the availability of book lists without ?availability= per route group.
+	Availability map[string]string `mapstructure:"availability"`
+	// TrustedProxies are the IPs or CIDRs of the proxies whose X-Forwarded-For gives the client IP.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// Sunset is the date the unversioned routes are removed.
+func (c APIConfig) Sunset() (time.Time, error) {
+	return time.Parse(DateLayout, c.LegacySunset)
+}
+
+type CORSConfig struct {
+	// AllowedOrigins may call the API from browsers, "*" allows any origin.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+// OutboxConfig picks the publisher of outbox events: log, http to URL or nats to Subject at NATSAddr.
+type OutboxConfig struct {
+	outbox.Config `mapstructure:",squash"`
+	Publisher     string        `mapstructure:"publisher"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	URL           string        `mapstructure:"url"`
+	NATSAddr      string        `mapstructure:"nats_addr"`
+	Subject       string        `mapstructure:"subject"`
+}
+
+type PricingConfig struct {
+	// Rates are the units of each currency per unit of the base currency.
+	Rates map[string]string `mapstructure:"rates"`
+}
+
+type AlertsConfig struct {
+	QueueSize int         `mapstructure:"queue_size"`
+	Email     EmailConfig `mapstructure:"email"`
+}
+
+// EmailConfig is the SMTP server emails are sent through, they are only logged without SMTPAddr.
+// The password is a secret and never comes from the config file.
+type EmailConfig struct {
+	SMTPAddr string   `mapstructure:"smtp_addr"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"-"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+type SchedulerConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+type StreamConfig struct {
+	BufferSize int           `mapstructure:"buffer_size"`
+	QueueSize  int           `mapstructure:"queue_size"`
+	Heartbeat  time.Duration `mapstructure:"heartbeat"`
+}
+
+type CacheConfig struct {
+	TTL  time.Duration `mapstructure:"ttl"`
+	Size int           `mapstructure:"size"`
+}
+
+// RedisConfig is the Redis shared between replicas, books are cached and rate limited in memory without Addr.
+type RedisConfig struct {
+	Addr     string        `mapstructure:"addr"`
+	PoolSize int           `mapstructure:"pool_size"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+var defaults = map[string]interface{}{
+	"port":                         "8080",
+	"grpc_port":                    "9090",
+	"admin_addr":                   "127.0.0.1:8090",
+	"log_level":                    "info",
+	"cors.allowed_origins":         []string{},
+	"features.graphql":             true,
+	"features.stream":              true,
+	"api.legacy_sunset":            "2027-06-30",
+	"api.availability.v1":          models.AvailabilityInStock,
+	"api.availability.legacy":      models.AvailabilityInStock,
+	"api.trusted_proxies":          []string{},
+	"db.host":                      "localhost",
+	"db.port":                      "5432",
+	"db.user":                      "postgres",
+	"db.dbname":                    "books_db",
+	"db.sslmode":                   "disable",
+	"idempotency.ttl":              "24h",
+	"idempotency.cleanup_interval": "1h",
+	"graphql.max_depth":            5,
+	"graphql.max_complexity":       500,
+	"webhooks.workers":             4,
+	"webhooks.queue_size":          1000,
+	"webhooks.max_attempts":        5,
+	"webhooks.backoff":             "1s",
+	"webhooks.max_failures":        20,
+	"webhooks.timeout":             "10s",
+	"outbox.publisher":             "log",
+	"outbox.interval":              "1s",
+	"outbox.batch_size":            100,
+	"outbox.claim_timeout":         "1m",
+	"outbox.max_attempts":          10,
+	"outbox.backoff":               "1s",
+	"outbox.timeout":               "5s",
+	"outbox.url":                   "",
+	"outbox.nats_addr":             "",
+	"outbox.subject":               "books",
+	"alerts.queue_size":            100,
+	"alerts.email.smtp_addr":       "",
+	"alerts.email.username":        "",
+	"alerts.email.from":            "",
+	"alerts.email.to":              []string{},
+	"waitlist.queue_size":          100,
+	"waitlist.interval":            "5m",
+	"waitlist.from":                "",
+	"waitlist.timeout":             "5s",
+	"waitlist.max_attempts":        5,
+	"covers.dir":                   "./data/covers",
+	"covers.max_size":              5 << 20,
+	"covers.max_concurrent":        2,
+	"carts.hold_ttl":               "15m",
+	"carts.reaper_interval":        "1m",
+	"carts.max_quantity":           20,
+	"scheduler.interval":           "1m",
+	"stream.buffer_size":           1000,
+	"stream.queue_size":            64,
+	"stream.heartbeat":             "15s",
+	"cache.ttl":                    "1m",
+	"cache.size":                   1000,
+	"redis.addr":                   "",
+	"redis.pool_size":              10,
+	"redis.timeout":                "1s",
+}
+
+// flags are the command line flags of configuration keys.
+var flags = []struct {
+	name, key, usage string
+}{
+	{"port", "port", "HTTP port"},
+	{"grpc-port", "grpc_port", "gRPC port"},
+	{"admin-addr", "admin_addr", "address of the admin routes, empty to turn them off"},
+	{"db-host", "db.host", "PostgreSQL host"},
+	{"db-port", "db.port", "PostgreSQL port"},
+	{"db-name", "db.dbname", "PostgreSQL database"},
+	{"covers-dir", "covers.dir", "directory of cover images"},
+}
+
+// Load reads the configuration with the command line arguments args, without the program name,
+// and validates it. A missing config or .env file is fine unless its path is given with a flag.
+func Load(args []string) (Config, error) {
+	source, err := Open(args)
+	if err != nil {
+		return Config{}, err
+	}
+	return source.Config(), nil
+}
+
+// Open loads the configuration like Load and keeps its source to reload it.
+func Open(args []string) (*Source, error) {
+	flagSet := pflag.NewFlagSet("restapi", pflag.ContinueOnError)
+	AddFlags(flagSet)
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+	return FromFlags(flagSet)
+}
+
+// AddFlags adds the flags of the configuration to a flag set of a command with flags of its own.
+func AddFlags(flagSet *pflag.FlagSet) {
+	flagSet.String("config", "configs/config.yml", "config file")
+	flagSet.String("env-file", ".env", "file of environment variables, the environment takes precedence")
+	for _, flag := range flags {
+		flagSet.String(flag.name, "", flag.usage)
+	}
+}
+
+// FromFlags loads the configuration like Open with the parsed flags added by AddFlags.
+func FromFlags(flagSet *pflag.FlagSet) (*Source, error) {
+	configFile, err := flagSet.GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	envFile, err := flagSet.GetString("env-file")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := godotenv.Load(envFile); err != nil && (flagSet.Changed("env-file") || !isNotExist(err)) {
+		return nil, fmt.Errorf("failed to read %s: %w", envFile, err)
+	}
+
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	v.SetConfigFile(configFile)
+	hasFile := true
+	if err := v.ReadInConfig(); err != nil {
+		if flagSet.Changed("config") || !isNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
+		}
+		hasFile = false
+	}
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	for _, flag := range flags {
+		// Flags only take precedence when they are set.
+		if flagSet.Changed(flag.name) {
+			if err := v.BindPFlag(flag.key, flagSet.Lookup(flag.name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cfg, err := decode(v)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(v, hasFile, cfg), nil
+}
+
+// decode reads the configuration from v with the secrets and validates it.
+func decode(v *viper.Viper) (Config, error) {
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to decode config: %w", err)
+	}
+	var problems []string
+	var err error
+	if cfg.DB.Password, err = readSecret("POSTGRES_PASSWORD"); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if cfg.Alerts.Email.Password, err = readSecret("SMTP_PASSWORD"); err != nil {
+		problems = append(problems, err.Error())
+	}
+	var invalid *ValidationError
+	if err = cfg.Validate(); errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return Config{}, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// readSecret reads the secret from the environment variable name, or from the file at name_FILE
+// as Docker secrets are mounted.
+func readSecret(name string) (string, error) {
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	value, fromEnv := os.LookupEnv(name)
+	switch {
+	case fromFile && fromEnv:
+		return "", fmt.Errorf("only one of %s and %s_FILE can be set", name, name)
+	case fromFile:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	default:
+		return value, nil
+	}
+}
+
+// ValidationError lists every problem of a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the whole configuration and reports all of its problems at once.
+func (c Config) Validate() error {
+	var p problems
+	p.port("port", c.Port)
+	p.port("grpc_port", c.GRPCPort)
+	if c.AdminAddr != "" {
+		if _, port, err := net.SplitHostPort(c.AdminAddr); err != nil {
+			p.addf("admin_addr %q must be like 127.0.0.1:8090", c.AdminAddr)
+		} else {
+			p.port("admin_addr port", port)
+		}
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		p.addf("log_level %q must be debug, info, warn or error", c.LogLevel)
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme != "http" && u.Scheme != "https" ||
+			u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "") {
+			p.addf("cors.allowed_origins %q must be * or like https://books.example", origin)
+		}
+	}
+
+	if _, err := c.API.Sunset(); err != nil {
+		p.addf("api.legacy_sunset must be a date like %s", DateLayout)
+	}
+	for _, group := range sortedKeys(c.API.Availability) {
+		if !models.ValidAvailability(c.API.Availability[group]) {
+			p.addf("api.availability.%s must be %s, %s or %s", group,
+				models.AvailabilityInStock, models.AvailabilityOutOfStock, models.AvailabilityAll)
+		}
+	}
+	for _, proxy := range c.API.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			p.addf("api.trusted_proxies %q must be an IP or a CIDR like 10.0.0.0/8", proxy)
+		}
+	}
+
+	p.required("db.host", c.DB.Host)
+	p.port("db.port", c.DB.Port)
+	p.required("db.user", c.DB.User)
+	p.required("db.dbname", c.DB.DBName)
+	switch c.DB.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		p.addf("db.sslmode %q is not a PostgreSQL sslmode", c.DB.SSLMode)
+	}
+
+	groups := make([]string, 0, len(c.RateLimit))
+	for group := range c.RateLimit {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		limit := c.RateLimit[group]
+		p.positive("ratelimit."+group+".rate", limit.Rate)
+		p.positive("ratelimit."+group+".burst", float64(limit.Burst))
+	}
+	p.duration("idempotency.ttl", c.Idempotency.TTL)
+	p.duration("idempotency.cleanup_interval", c.Idempotency.CleanupInterval)
+	p.positive("graphql.max_depth", float64(c.GraphQL.MaxDepth))
+	p.positive("graphql.max_complexity", float64(c.GraphQL.MaxComplexity))
+
+	p.positive("webhooks.workers", float64(c.Webhooks.Workers))
+	p.positive("webhooks.queue_size", float64(c.Webhooks.QueueSize))
+	p.positive("webhooks.max_attempts", float64(c.Webhooks.MaxAttempts))
+	p.duration("webhooks.backoff", c.Webhooks.Backoff)
+	p.positive("webhooks.max_failures", float64(c.Webhooks.MaxFailures))
+	p.duration("webhooks.timeout", c.Webhooks.Timeout)
+
+	switch c.Outbox.Publisher {
+	case "log":
+	case "http":
+		p.required("outbox.url", c.Outbox.URL)
+	case "nats":
+		p.required("outbox.nats_addr", c.Outbox.NATSAddr)
+		p.required("outbox.subject", c.Outbox.Subject)
+	default:
+		p.addf("outbox.publisher %q must be log, http or nats", c.Outbox.Publisher)
+	}
+	p.duration("outbox.interval", c.Outbox.Interval)
+	p.positive("outbox.batch_size", float64(c.Outbox.BatchSize))
+	p.duration("outbox.claim_timeout", c.Outbox.ClaimTimeout)
+	p.positive("outbox.max_attempts", float64(c.Outbox.MaxAttempts))
+	p.duration("outbox.backoff", c.Outbox.Backoff)
+	p.duration("outbox.timeout", c.Outbox.Timeout)
+
+	for _, currency := range sortedKeys(c.Pricing.Rates) {
+		if r, ok := new(big.Rat).SetString(c.Pricing.Rates[currency]); !ok || r.Sign() <= 0 {
+			p.addf("pricing.rates.%s must be a positive number", currency)
+		}
+	}
+
+	p.positive("alerts.queue_size", float64(c.Alerts.QueueSize))
+	if len(c.Alerts.Email.To) > 0 {
+		p.required("alerts.email.from", c.Alerts.Email.From)
+	}
+	p.positive("waitlist.queue_size", float64(c.Waitlist.QueueSize))
+	p.duration("waitlist.interval", c.Waitlist.Interval)
+	p.duration("waitlist.timeout", c.Waitlist.Timeout)
+	p.positive("waitlist.max_attempts", float64(c.Waitlist.MaxAttempts))
+	if c.Alerts.Email.SMTPAddr != "" {
+		p.required("waitlist.from", c.Waitlist.From)
+	}
+
+	p.required("covers.dir", c.Covers.Dir)
+	p.positive("covers.max_size", float64(c.Covers.MaxSize))
+	p.positive("covers.max_concurrent", float64(c.Covers.MaxConcurrent))
+	p.duration("carts.hold_ttl", c.Carts.HoldTTL)
+	p.duration("carts.reaper_interval", c.Carts.ReaperInterval)
+	p.positive("carts.max_quantity", float64(c.Carts.MaxQuantity))
+	p.duration("scheduler.interval", c.Scheduler.Interval)
+	p.positive("stream.buffer_size", float64(c.Stream.BufferSize))
+	p.positive("stream.queue_size", float64(c.Stream.QueueSize))
+	p.duration("stream.heartbeat", c.Stream.Heartbeat)
+	p.duration("cache.ttl", c.Cache.TTL)
+	p.positive("cache.size", float64(c.Cache.Size))
+	if c.Redis.Addr != "" {
+		p.positive("redis.pool_size", float64(c.Redis.PoolSize))
+		p.duration("redis.timeout", c.Redis.Timeout)
+	}
+
+	if len(p) > 0 {
+		return &ValidationError{Problems: p}
+	}
+	return nil
+}
+
+type problems []string
+
+func (p *problems) addf(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *problems) required(key, value string) {
+	if value == "" {
+		p.addf("%s is required", key)
+	}
+}
+
+func (p *problems) port(key, value string) {
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		p.addf("%s %q is not a port", key, value)
+	}
+}
+
+func (p *problems) positive(key string, value float64) {
+	if value <= 0 {
+		p.addf("%s must be positive", key)
+	}
+}
+
+func (p *problems) duration(key string, value time.Duration) {
+	if value <= 0 {
+		p.addf("%s must be a positive duration", key)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
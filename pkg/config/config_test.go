@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	cfg, err := Load([]string{"--config", "../../configs/config.yml"})
+	assert.NoError(t, err)
+	assert.Equal(t, "db", cfg.DB.Host)
+	assert.Equal(t, 500, cfg.GraphQL.MaxComplexity)
+	assert.Equal(t, 100, cfg.Outbox.BatchSize)
+	assert.Equal(t, "0.88", cfg.Pricing.Rates["eur"])
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	configFile := writeFile(t, "config.yml", "port: \"8000\"\ndb:\n  host: \"file\"\n  user: \"file\"\n  dbname: \"file\"\ncache:\n  size: 10\n")
+	envFile := writeFile(t, ".env", "APP_DB_HOST=dotenv\nAPP_DB_USER=dotenv\n")
+	// The .env file sets variables the environment does not have, for the rest of the process.
+	t.Cleanup(func() {
+		os.Unsetenv("APP_DB_USER")
+	})
+	t.Setenv("APP_DB_HOST", "env")
+	t.Setenv("APP_CACHE_TTL", "5m")
+
+	cfg, err := Load([]string{"--config", configFile, "--env-file", envFile, "--port", "7000"})
+	require.NoError(t, err)
+	assert.Equal(t, "7000", cfg.Port)
+	assert.Equal(t, "9090", cfg.GRPCPort)
+	assert.Equal(t, "env", cfg.DB.Host)
+	assert.Equal(t, "dotenv", cfg.DB.User)
+	assert.Equal(t, "file", cfg.DB.DBName)
+	assert.Equal(t, 10, cfg.Cache.Size)
+	assert.Equal(t, 5*time.Minute, cfg.Cache.TTL)
+}
+
+func TestLoadFiles(t *testing.T) {
+	_, err := Load([]string{"--env-file", filepath.Join(t.TempDir(), ".env")})
+	assert.Error(t, err, "a .env file given with a flag must exist")
+
+	_, err = Load([]string{"--config", filepath.Join(t.TempDir(), "config.yml")})
+	assert.Error(t, err, "a config file given with a flag must exist")
+
+	// Without files the defaults are used.
+	cfg, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Port)
+}
+
+func TestLoadSecrets(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD_FILE", writeFile(t, "postgres_password", "secret\n"))
+	t.Setenv("SMTP_PASSWORD", "smtp")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", cfg.DB.Password)
+	assert.Equal(t, "smtp", cfg.Alerts.Email.Password)
+
+	t.Setenv("POSTGRES_PASSWORD", "other")
+	t.Setenv("SMTP_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load(nil)
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []string{
+		"only one of POSTGRES_PASSWORD and POSTGRES_PASSWORD_FILE can be set",
+		"only one of SMTP_PASSWORD and SMTP_PASSWORD_FILE can be set",
+	}, invalid.Problems)
+}
+
+func TestValidate(t *testing.T) {
+	t.Setenv("APP_PORT", "http")
+	t.Setenv("APP_ADMIN_ADDR", "8081")
+	t.Setenv("APP_API_LEGACY_SUNSET", "soon")
+	t.Setenv("APP_API_AVAILABILITY_V1", "some")
+	t.Setenv("APP_OUTBOX_PUBLISHER", "http")
+	t.Setenv("APP_WEBHOOKS_TIMEOUT", "0s")
+	t.Setenv("APP_COVERS_MAX_SIZE", "0")
+
+	_, err := Load([]string{"--config", writeFile(t, "config.yml",
+		"api:\n  trusted_proxies: [\"10.0.0.0/8\", \"proxy\"]\ndb:\n  host: \"\"\n")})
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []string{
+		`port "http" is not a port`,
+		`admin_addr "8081" must be like 127.0.0.1:8090`,
+		"api.legacy_sunset must be a date like 2006-01-02",
+		"api.availability.v1 must be in_stock, out_of_stock or all",
+		`api.trusted_proxies "proxy" must be an IP or a CIDR like 10.0.0.0/8`,
+		"db.host is required",
+		"webhooks.timeout must be a positive duration",
+		"outbox.url is required",
+		"covers.max_size must be positive",
+	}, invalid.Problems)
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/ratelimit"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// redacted replaces secrets in the effective settings.
+const redacted = "[redacted]"
+
+// shownKeys are the settings Effective shows as they are, with the keys below them. Other settings are
+// redacted, so a setting is only shown once it is listed here.
+var shownKeys = map[string]bool{
+	"port":                  true,
+	"grpc_port":             true,
+	"admin_addr":            true,
+	"log_level":             true,
+	"cors":                  true,
+	"features":              true,
+	"api":                   true,
+	"db.host":               true,
+	"db.port":               true,
+	"db.user":               true,
+	"db.dbname":             true,
+	"db.sslmode":            true,
+	"ratelimit":             true,
+	"idempotency":           true,
+	"graphql":               true,
+	"webhooks.workers":      true,
+	"webhooks.queue_size":   true,
+	"webhooks.max_attempts": true,
+	"webhooks.backoff":      true,
+	"webhooks.max_failures": true,
+	"webhooks.timeout":      true,
+	"outbox.publisher":      true,
+	"outbox.interval":       true,
+	"outbox.batch_size":     true,
+	"outbox.claim_timeout":  true,
+	"outbox.max_attempts":   true,
+	"outbox.backoff":        true,
+	"outbox.timeout":        true,
+	"outbox.subject":        true,
+	"pricing.rates":         true,
+	"alerts.queue_size":     true,
+	"alerts.email.username": true,
+	"alerts.email.from":     true,
+	"alerts.email.to":       true,
+	"waitlist.queue_size":   true,
+	"waitlist.interval":     true,
+	"waitlist.from":         true,
+	"waitlist.timeout":      true,
+	"waitlist.max_attempts": true,
+	"covers.dir":            true,
+	"covers.max_size":       true,
+	"covers.max_concurrent": true,
+	"carts.hold_ttl":        true,
+	"carts.reaper_interval": true,
+	"carts.max_quantity":    true,
+	"scheduler.interval":    true,
+	"stream.buffer_size":    true,
+	"stream.queue_size":     true,
+	"stream.heartbeat":      true,
+	"cache.ttl":             true,
+	"cache.size":            true,
+	"redis.pool_size":       true,
+	"redis.timeout":         true,
+}
+
+// addrKeys are host:port addresses, they are shown unless they carry more, such as credentials.
+var addrKeys = map[string]bool{
+	"alerts.email.smtp_addr": true,
+	"outbox.nats_addr":       true,
+	"redis.addr":             true,
+}
+
+// urlKeys are shown without their credentials, query and fragment.
+var urlKeys = map[string]bool{
+	"outbox.url": true,
+}
+
+// runtimeKeys are the top-level keys of the settings in Runtime.
+var runtimeKeys = []string{"log_level", "ratelimit", "cors", "features"}
+
+// Runtime is the part of the configuration that is applied while the server runs. Changes of
+// other settings in the config file are applied on restart.
+type Runtime struct {
+	LogLevel  string
+	RateLimit map[string]ratelimit.Limit
+	CORS      CORSConfig
+	Features  map[string]bool
+}
+
+func (c Config) Runtime() Runtime {
+	return Runtime{LogLevel: c.LogLevel, RateLimit: c.RateLimit, CORS: c.CORS, Features: c.Features}
+}
+
+func (c Config) withRuntime(runtime Runtime) Config {
+	c.LogLevel = runtime.LogLevel
+	c.RateLimit = runtime.RateLimit
+	c.CORS = runtime.CORS
+	c.Features = runtime.Features
+	return c
+}
+
+// Applier puts runtime settings into effect, it fails when it cannot.
+type Applier func(Runtime) error
+
+// Source is the loaded configuration. It reloads the runtime settings when the config file changes.
+type Source struct {
+	v       *viper.Viper
+	hasFile bool
+	// mu serializes reloads.
+	mu       sync.Mutex
+	appliers []Applier
+	state    atomic.Value // state
+}
+
+type state struct {
+	cfg      Config
+	settings map[string]interface{}
+}
+
+func newSource(v *viper.Viper, hasFile bool, cfg Config) *Source {
+	s := &Source{v: v, hasFile: hasFile}
+	s.state.Store(state{cfg: cfg, settings: v.AllSettings()})
+	return s
+}
+
+// Config is the configuration in effect.
+func (s *Source) Config() Config {
+	return s.state.Load().(state).cfg
+}
+
+// Effective returns the settings in effect by key. Only the settings of shownKeys, addrKeys and urlKeys
+// are shown, the others are redacted.
+func (s *Source) Effective() map[string]interface{} {
+	st := s.state.Load().(state)
+	settings := filterSettings("", st.settings)
+	settings["db"] = withValue(settings["db"], "password", redact(st.cfg.DB.Password))
+	alerts, _ := settings["alerts"].(map[string]interface{})
+	settings["alerts"] = withValue(alerts, "email", withValue(alerts["email"], "password", redact(st.cfg.Alerts.Email.Password)))
+	return settings
+}
+
+// filterSettings copies the settings below the key prefix, redacting the ones that are not listed.
+func filterSettings(prefix string, settings map[string]interface{}) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(settings))
+	for name, value := range settings {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		section, isSection := value.(map[string]interface{})
+		switch {
+		case shownKeys[key]:
+			filtered[name] = value
+		case addrKeys[key]:
+			filtered[name] = redactAddr(value)
+		case urlKeys[key]:
+			filtered[name] = redactURL(value)
+		case isSection:
+			filtered[name] = filterSettings(key, section)
+		default:
+			filtered[name] = redactValue(value)
+		}
+	}
+	return filtered
+}
+
+func redactValue(value interface{}) string {
+	if value == nil || value == "" {
+		return ""
+	}
+	return redacted
+}
+
+func redactAddr(value interface{}) string {
+	addr, _ := value.(string)
+	if addr == "" {
+		return redactValue(value)
+	}
+	if host, _, err := net.SplitHostPort(addr); err != nil || strings.ContainsAny(host, "@/") {
+		return redacted
+	}
+	return addr
+}
+
+func redactURL(value interface{}) string {
+	raw, _ := value.(string)
+	if raw == "" {
+		return redactValue(value)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u.Redacted()
+}
+
+// withValue copies the settings section with the key set to value.
+func withValue(section interface{}, key string, value interface{}) map[string]interface{} {
+	values, _ := section.(map[string]interface{})
+	copied := make(map[string]interface{}, len(values)+1)
+	for k, v := range values {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// OnReload applies the current runtime settings with apply and applies the settings of every reload with it.
+func (s *Source) OnReload(apply Applier) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := apply(s.Config().Runtime()); err != nil {
+		return err
+	}
+	s.appliers = append(s.appliers, apply)
+	return nil
+}
+
+// Watch reloads the configuration whenever the config file changes. An invalid config file,
+// or one that cannot be applied, is logged and the settings in effect are kept.
+func (s *Source) Watch() {
+	if !s.hasFile {
+		return
+	}
+	s.v.OnConfigChange(func(fsnotify.Event) {
+		if err := s.reload(); err != nil {
+			logging.Errorf("config: %s, keeping the previous config", err)
+		}
+	})
+	s.v.WatchConfig()
+}
+
+// reload reads the config file, validates it and applies its runtime settings. When an applier
+// fails, the settings in effect are applied again, so nothing is left half changed.
+func (s *Source) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", s.v.ConfigFileUsed(), err)
+	}
+	next, err := decode(s.v)
+	if err != nil {
+		return err
+	}
+	current := s.state.Load().(state)
+	runtime, previous := next.Runtime(), current.cfg.Runtime()
+	for i, apply := range s.appliers {
+		if err := apply(runtime); err != nil {
+			for _, undo := range s.appliers[:i+1] {
+				if undoErr := undo(previous); undoErr != nil {
+					logging.Errorf("config: failed to roll back: %s", undoErr)
+				}
+			}
+			return fmt.Errorf("failed to apply config: %w", err)
+		}
+	}
+
+	cfg := current.cfg.withRuntime(runtime)
+	if !reflect.DeepEqual(cfg, next) {
+		logging.Warnf("config: only log_level, ratelimit, cors and features are reloaded, other changes need a restart")
+	}
+	settings := make(map[string]interface{}, len(current.settings))
+	for key, value := range current.settings {
+		settings[key] = value
+	}
+	all := s.v.AllSettings()
+	for _, key := range runtimeKeys {
+		if value, ok := all[key]; ok {
+			settings[key] = value
+		} else {
+			delete(settings, key)
+		}
+	}
+	s.state.Store(state{cfg: cfg, settings: settings})
+	logging.Infof("config: reloaded %s", s.v.ConfigFileUsed())
+	return nil
+}
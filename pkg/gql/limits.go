@@ -0,0 +1,115 @@
+package gql
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"strings"
+)
+
+// listFactor is the assumed size of a list field when the query complexity is estimated.
+const listFactor = 10
+
+type Limits struct {
+	MaxDepth      int `mapstructure:"max_depth"`
+	MaxComplexity int `mapstructure:"max_complexity"`
+}
+
+type analysis struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// checkLimits rejects operations nested deeper or estimated costlier than the limits.
+// Every field costs 1, fields of list items cost listFactor times more.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operationName string, limits Limits) error {
+	a := analysis{schema: schema, fragments: make(map[string]*ast.FragmentDefinition)}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+	var root graphql.Type = schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	depth, complexity := a.selectionSet(operation.SelectionSet, root, 0, map[string]bool{})
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+	}
+	return nil
+}
+
+func (a *analysis) selectionSet(set *ast.SelectionSet, parent graphql.Type, depth int, visited map[string]bool) (int, int) {
+	if set == nil {
+		return depth, 0
+	}
+	maxDepth, complexity := depth, 0
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = a.field(selection, parent, depth, visited)
+		case *ast.InlineFragment:
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = a.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			d, c = a.selectionSet(selection.SelectionSet, typ, depth, visited)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			d, c = a.selectionSet(fragment.SelectionSet, a.schema.Type(fragment.TypeCondition.Name.Value), depth, visited)
+			delete(visited, name)
+		}
+		if d > maxDepth {
+			maxDepth = d
+		}
+		complexity += c
+	}
+	return maxDepth, complexity
+}
+
+func (a *analysis) field(field *ast.Field, parent graphql.Type, depth int, visited map[string]bool) (int, int) {
+	// Introspection is answered from the schema and is not limited.
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return depth, 0
+	}
+	var fieldType graphql.Type
+	if object, ok := parent.(*graphql.Object); ok {
+		if def, ok := object.Fields()[field.Name.Value]; ok {
+			fieldType = def.Type
+		}
+	}
+	factor := 1
+	for {
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+			continue
+		}
+		if list, ok := fieldType.(*graphql.List); ok {
+			factor *= listFactor
+			fieldType = list.OfType
+			continue
+		}
+		break
+	}
+	d, c := a.selectionSet(field.SelectionSet, fieldType, depth+1, visited)
+	return d, 1 + factor*c
+}
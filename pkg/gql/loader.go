@@ -0,0 +1,74 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"sync"
+)
+
+type loaderKey struct{}
+
+// genreLoader collects genre ids requested while a query level is resolved
+// and loads them with a single call when the first result is needed.
+type genreLoader struct {
+	genres  service.Genres
+	mu      sync.Mutex
+	pending map[int]struct{}
+	loaded  map[int]models.Genre
+}
+
+func newGenreLoader(genres service.Genres) *genreLoader {
+	return &genreLoader{
+		genres:  genres,
+		pending: make(map[int]struct{}),
+		loaded:  make(map[int]models.Genre),
+	}
+}
+
+func loaderFromContext(ctx context.Context) *genreLoader {
+	return ctx.Value(loaderKey{}).(*genreLoader)
+}
+
+// Load returns a thunk, graphql executes thunks after all fields of the level are resolved.
+func (l *genreLoader) Load(id int) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[id]; !ok {
+		l.pending[id] = struct{}{}
+	}
+	l.mu.Unlock()
+	return func() (interface{}, error) {
+		if err := l.flush(); err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		genre, ok := l.loaded[id]
+		if !ok {
+			return nil, fmt.Errorf("genre %d not found", id)
+		}
+		return genre, nil
+	}
+}
+
+func (l *genreLoader) flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	l.pending = make(map[int]struct{})
+	genres, err := l.genres.GetGenresByIDs(ids)
+	if err != nil {
+		return err
+	}
+	for _, genre := range genres {
+		l.loaded[genre.ID] = genre
+	}
+	return nil
+}
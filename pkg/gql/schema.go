@@ -0,0 +1,236 @@
+package gql
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"strconv"
+)
+
+var errInvalidInput = errors.New("invalid input")
+
+var genreType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Genre",
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var bookInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "BookInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":             &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"price":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"currency":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"author":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"genre":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"amount":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		"reorderThreshold": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+func newSchema(services *service.Service) (graphql.Schema, error) {
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"price": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).Price.String(), nil
+				},
+			},
+			"effectivePrice": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if price := p.Source.(models.Book).EffectivePrice; price != nil {
+						return price.String(), nil
+					}
+					return nil, nil
+				},
+			},
+			"currency": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"amount":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"reorderThreshold": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).ReorderThreshold, nil
+				},
+			},
+			"available": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).InStock(), nil
+				},
+			},
+			"avgRating": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).AvgRating, nil
+				},
+			},
+			"reviewCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Book).ReviewCount, nil
+				},
+			},
+			"genre": &graphql.Field{
+				Type: graphql.NewNonNull(genreType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFromContext(p.Context).Load(p.Source.(models.Book).Genre), nil
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"books": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+				Args: graphql.FieldConfigArgument{
+					"genre":        &graphql.ArgumentConfig{Type: graphql.Int},
+					"availability": &graphql.ArgumentConfig{Type: graphql.String},
+					"sort":         &graphql.ArgumentConfig{Type: graphql.String},
+					"location":     &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filterCondition := map[string][]string{}
+					if genre, ok := p.Args["genre"].(int); ok {
+						if genre < 1 || genre > 3 {
+							return nil, errors.New("invalid filter condition")
+						}
+						filterCondition["genre"] = []string{strconv.Itoa(genre)}
+					}
+					if availability, ok := p.Args["availability"].(string); ok {
+						if !models.ValidAvailability(availability) {
+							return nil, errors.New("invalid filter condition")
+						}
+						filterCondition["availability"] = []string{availability}
+					}
+					if location, ok := p.Args["location"].(int); ok {
+						if location < 1 {
+							return nil, errors.New("invalid filter condition")
+						}
+						filterCondition["location"] = []string{strconv.Itoa(location)}
+					}
+					if sort, ok := p.Args["sort"].(string); ok {
+						if sort != models.SortRating {
+							return nil, errors.New("invalid sort")
+						}
+						filterCondition["sort"] = []string{sort}
+					}
+					return services.BooksManager.GetBooks(filterCondition)
+				},
+			},
+			"book": &graphql.Field{
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return services.BooksManager.GetBookByID(p.Args["id"].(int))
+				},
+			},
+			"genres": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(genreType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return services.Genres.GetGenres()
+				},
+			},
+			"genre": &graphql.Field{
+				Type: genreType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFromContext(p.Context).Load(p.Args["id"].(int)), nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					book, err := bookFromInput(p.Args["input"])
+					if err != nil {
+						return nil, err
+					}
+					if book.ID, err = services.BooksManager.CreateBook(book); err != nil {
+						return nil, err
+					}
+					return book, nil
+				},
+			},
+			"updateBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					book, err := bookFromInput(p.Args["input"])
+					if err != nil {
+						return nil, err
+					}
+					book.ID = p.Args["id"].(int)
+					if _, err = services.BooksManager.UpdateBookByID(book.ID, book); err != nil {
+						return nil, err
+					}
+					return book.WithDefaults(), nil
+				},
+			},
+			"deleteBook": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := services.BooksManager.DeleteBookByID(p.Args["id"].(int)); err != nil {
+						return nil, err
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// bookFromInput converts BookInput and checks the same constraints as the REST handlers.
+func bookFromInput(input interface{}) (models.Book, error) {
+	fields, ok := input.(map[string]interface{})
+	if !ok {
+		return models.Book{}, errInvalidInput
+	}
+	book := models.Book{}
+	book.Name, _ = fields["name"].(string)
+	price, _ := fields["price"].(string)
+	book.Currency, _ = fields["currency"].(string)
+	book.Author, _ = fields["author"].(string)
+	book.Genre, _ = fields["genre"].(int)
+	book.Amount, _ = fields["amount"].(int)
+	book.ReorderThreshold, _ = fields["reorderThreshold"].(int)
+	var err error
+	if book.Price, err = models.ParseMoney(price); err != nil {
+		return book, errInvalidInput
+	}
+	if err = binding.Validator.ValidateStruct(book); err != nil {
+		return book, errInvalidInput
+	}
+	return book, nil
+}
@@ -0,0 +1,42 @@
+package gql
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Server struct {
+	schema graphql.Schema
+	genres service.Genres
+	limits Limits
+}
+
+func NewServer(services *service.Service, limits Limits) (*Server, error) {
+	schema, err := newSchema(services)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{schema: schema, genres: services.Genres, limits: limits}, nil
+}
+
+// Execute runs a query after checking it against the depth and complexity limits.
+func (s *Server) Execute(ctx context.Context, query, operationName string, variables map[string]interface{}) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query)})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if err = checkLimits(&s.schema, doc, operationName, s.limits); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  query,
+		VariableValues: variables,
+		OperationName:  operationName,
+		Context:        context.WithValue(ctx, loaderKey{}, newGenreLoader(s.genres)),
+	})
+}
@@ -0,0 +1,122 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	type mockBehavior func(b *mock_service.MockBooksManager, g *mock_service.MockGenres)
+	tests := []struct {
+		name           string
+		query          string
+		limits         Limits
+		mockBehavior   mockBehavior
+		expectedData   string
+		expectedErrors []string
+	}{
+		{
+			name:  "Books with genres batched",
+			query: `{ books { name genre { name } } }`,
+			mockBehavior: func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {
+				b.EXPECT().GetBooks(map[string][]string{}).Return([]models.Book{
+					{ID: 1, Name: "book1", Genre: 1},
+					{ID: 2, Name: "book2", Genre: 3},
+					{ID: 3, Name: "book3", Genre: 1},
+				}, nil)
+				g.EXPECT().GetGenresByIDs(gomock.Any()).DoAndReturn(func(ids []int) ([]models.Genre, error) {
+					sort.Ints(ids)
+					assert.Equal(t, []int{1, 3}, ids)
+					return []models.Genre{{ID: 1, Name: "adventure"}, {ID: 3, Name: "fantasy"}}, nil
+				}).Times(1)
+			},
+			expectedData: `{"books":[{"genre":{"name":"adventure"},"name":"book1"},` +
+				`{"genre":{"name":"fantasy"},"name":"book2"},{"genre":{"name":"adventure"},"name":"book3"}]}`,
+		},
+		{
+			name:  "Filter by genre",
+			query: `{ books(genre: 2) { id } }`,
+			mockBehavior: func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {
+				b.EXPECT().GetBooks(map[string][]string{"genre": {"2"}}).Return([]models.Book{{ID: 4, Genre: 2}}, nil)
+			},
+			expectedData: `{"books":[{"id":4}]}`,
+		},
+		{
+			name:  "Filter by availability",
+			query: `{ books(availability: "out_of_stock") { id available } }`,
+			mockBehavior: func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {
+				b.EXPECT().GetBooks(map[string][]string{"availability": {"out_of_stock"}}).Return([]models.Book{{ID: 4, Genre: 2}}, nil)
+			},
+			expectedData: `{"books":[{"available":false,"id":4}]}`,
+		},
+		{
+			name:  "Create book",
+			query: `mutation { createBook(input: {name: "hello", price: "67.88", genre: 1, amount: 7}) { id name } }`,
+			mockBehavior: func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {
+				b.EXPECT().CreateBook(models.Book{Name: "hello", Price: 6788, Genre: 1, Amount: 7}).Return(5, nil)
+			},
+			expectedData: `{"createBook":{"id":5,"name":"hello"}}`,
+		},
+		{
+			name:           "Create invalid book",
+			query:          `mutation { createBook(input: {name: "hello", price: "67.88", genre: 6, amount: 7}) { id } }`,
+			mockBehavior:   func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {},
+			expectedData:   `null`,
+			expectedErrors: []string{"invalid input"},
+		},
+		{
+			name:           "Create book with invalid price",
+			query:          `mutation { createBook(input: {name: "hello", price: "67.885", genre: 1, amount: 7}) { id } }`,
+			mockBehavior:   func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {},
+			expectedData:   `null`,
+			expectedErrors: []string{"invalid input"},
+		},
+		{
+			name:           "Depth limit",
+			query:          `{ books { genre { name } } }`,
+			limits:         Limits{MaxDepth: 2},
+			mockBehavior:   func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {},
+			expectedData:   `null`,
+			expectedErrors: []string{"query depth 3 exceeds the limit of 2"},
+		},
+		{
+			name:           "Complexity limit",
+			query:          `query { ...all } fragment all on Query { books { id name genre { id name } } }`,
+			limits:         Limits{MaxComplexity: 50},
+			mockBehavior:   func(b *mock_service.MockBooksManager, g *mock_service.MockGenres) {},
+			expectedData:   `null`,
+			expectedErrors: []string{"query complexity 51 exceeds the limit of 50"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			mockGenres := mock_service.NewMockGenres(c)
+			test.mockBehavior(mockManager, mockGenres)
+
+			srv, err := NewServer(&service.Service{BooksManager: mockManager, Genres: mockGenres}, test.limits)
+			if err != nil {
+				t.Fatalf("failed to build schema: %s", err)
+			}
+			result := srv.Execute(context.Background(), test.query, "", nil)
+
+			data, _ := json.Marshal(result.Data)
+			assert.Equal(t, test.expectedData, string(data))
+			var messages []string
+			for _, e := range result.Errors {
+				messages = append(messages, e.Message)
+			}
+			assert.Equal(t, test.expectedErrors, messages)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Runtime holds the settings of the handlers that are changed while the server runs.
+type Runtime struct {
+	// AllowedOrigins may call the API from browsers, "*" allows any origin.
+	AllowedOrigins []string
+	// Features turn routes off, features missing from it are enabled.
+	Features map[string]bool
+}
+
+// Features of Runtime.Features.
+const (
+	FeatureGraphQL = "graphql"
+	FeatureStream  = "stream"
+)
+
+func (r Runtime) allowsOrigin(origin string) bool {
+	for _, allowed := range r.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Runtime) enabled(feature string) bool {
+	enabled, ok := r.Features[feature]
+	return !ok || enabled
+}
+
+// ConfigSource is the configuration shown at /admin/config.
+type ConfigSource interface {
+	// Effective returns the settings in effect with the secrets redacted.
+	Effective() map[string]interface{}
+}
+
+// SetRuntime swaps the runtime settings, requests in flight keep the ones they started with.
+func (h *Handler) SetRuntime(runtime Runtime) {
+	h.runtime.Store(runtime)
+}
+
+func (h *Handler) currentRuntime() Runtime {
+	runtime, _ := h.runtime.Load().(Runtime)
+	return runtime
+}
+
+func (h *Handler) GetConfig(ctx *gin.Context) {
+	if h.config == nil {
+		NewErrorResponse(ctx, http.StatusNotFound, "config is not available")
+		return
+	}
+	ctx.JSON(http.StatusOK, h.config.Effective())
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// cartTokenHeader carries the token a cart was created with, every request about the cart needs it.
+const cartTokenHeader = "Cart-Token"
+
+func (h *Handler) initCartsRoutes(group *gin.RouterGroup) {
+	carts := group.Group("/carts")
+	{
+		carts.POST("", h.CreateCart)
+		carts.GET("/:id", h.GetCartByID)
+		carts.DELETE("/:id", h.DeleteCartByID)
+		carts.PUT("/:id/items/:book_id", h.SetCartItem)
+		carts.DELETE("/:id/items/:book_id", h.DeleteCartItem)
+		carts.POST("/:id/checkout", h.Checkout)
+	}
+}
+
+// CreateCart responds with the id of the cart and the token to use it.
+func (h *Handler) CreateCart(ctx *gin.Context) {
+	cart, err := h.carts.CreateCart()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id":    cart.ID,
+		"token": cart.Token,
+	})
+}
+
+func (h *Handler) GetCartByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	cart, err := h.carts.GetCartByID(id, ctx.GetHeader(cartTokenHeader))
+	if err != nil {
+		cartErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (h *Handler) DeleteCartByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.carts.DeleteCart(id, ctx.GetHeader(cartTokenHeader)); err != nil {
+		cartErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
+
+// SetCartItem holds the quantity of the book in the cart, the response tells until when.
+func (h *Handler) SetCartItem(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	bookID, err := strconv.Atoi(ctx.Param("book_id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	item := models.CartItem{BookID: bookID}
+	if err = ctx.BindJSON(&item); err != nil || item.BookID != bookID {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	item, err = h.carts.SetCartItem(id, ctx.GetHeader(cartTokenHeader), item)
+	if err != nil {
+		cartErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteCartItem(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	bookID, err := strconv.Atoi(ctx.Param("book_id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.carts.DeleteCartItem(id, bookID, ctx.GetHeader(cartTokenHeader)); err != nil {
+		cartErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
+
+func (h *Handler) Checkout(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	cart, err := h.carts.Checkout(id, ctx.GetHeader(cartTokenHeader))
+	if err != nil {
+		cartErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, cart)
+}
+
+// cartErrorResponse responds with a conflict when the state of the cart or the stock prevents the change,
+// and forbids the change without the token of the cart.
+func cartErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCartToken):
+		NewErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInsufficientStock),
+		errors.Is(err, service.ErrCartNotOpen),
+		errors.Is(err, service.ErrCartEmpty),
+		errors.Is(err, service.ErrCartFull):
+		NewErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
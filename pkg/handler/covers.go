@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// coverCacheControl lets clients and proxies keep cover files forever, a new cover gets new URLs.
+	coverCacheControl = "public, max-age=31536000, immutable"
+	// multipartOverhead is allowed on top of the cover size for the rest of the multipart body.
+	multipartOverhead = 64 << 10
+)
+
+func (h *Handler) initCoversRoutes(group *gin.RouterGroup) {
+	cover := group.Group("/books/:id/cover", h.rateLimit("books"))
+	{
+		cover.PUT("", h.SetCover)
+		cover.GET("/:file", h.GetCover)
+	}
+}
+
+// SetCover takes a JPEG or PNG image from the cover field of a multipart form.
+func (h *Handler) SetCover(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if h.coverMaxSize > 0 {
+		if ctx.Request.ContentLength > h.coverMaxSize+multipartOverhead {
+			NewErrorResponse(ctx, http.StatusRequestEntityTooLarge, service.ErrCoverTooLarge.Error())
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.coverMaxSize+multipartOverhead)
+	}
+	header, err := ctx.FormFile("cover")
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if contentType := header.Header.Get("Content-Type"); contentType != "image/jpeg" && contentType != "image/png" {
+		NewErrorResponse(ctx, http.StatusUnsupportedMediaType, service.ErrUnsupportedCover.Error())
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+	cover, err := h.covers.SetCover(id, file)
+	switch {
+	case errors.Is(err, service.ErrBookNotFound):
+		NewErrorResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCoverTooLarge):
+		NewErrorResponse(ctx, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrUnsupportedCover):
+		NewErrorResponse(ctx, http.StatusUnsupportedMediaType, err.Error())
+	case err != nil:
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	default:
+		book := models.Book{ID: id, Cover: cover}
+		setCoverURLs(&book)
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"cover_url":        book.CoverURL,
+			"cover_thumbnails": book.CoverThumbnails,
+		})
+	}
+}
+
+// GetCover serves a cover file with headers to cache it forever.
+func (h *Handler) GetCover(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	blob, err := h.covers.GetCover(id, ctx.Param("file"))
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		NewErrorResponse(ctx, http.StatusNotFound, "cover not found")
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer blob.Content.Close()
+	ctx.DataFromReader(http.StatusOK, blob.Size, blob.ContentType, blob.Content, map[string]string{
+		"Cache-Control": coverCacheControl,
+	})
+}
+
+// setCoverURLs sets the URLs of the cover of the book and its thumbnails, if it has one.
+func setCoverURLs(book *models.Book) {
+	if book.Cover == "" {
+		return
+	}
+	book.CoverURL = coverURL(book.ID, book.Cover)
+	book.CoverThumbnails = make(map[string]string, len(models.CoverThumbnails))
+	for size := range models.CoverThumbnails {
+		book.CoverThumbnails[size] = coverURL(book.ID, models.CoverFile(book.Cover, size))
+	}
+}
+
+func coverURL(bookID int, file string) string {
+	return fmt.Sprintf("/api/v1/books/%d/cover/%s", bookID, file)
+}
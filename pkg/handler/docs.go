@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"embed"
+	"github.com/gin-gonic/gin"
+	"io/fs"
+	"net/http"
+)
+
+var (
+	//go:embed docs/openapi.json
+	openAPISpec []byte
+	//go:embed docs/index.html
+	swaggerUI []byte
+	// swaggerUIAssets is swagger-ui-dist 5.18.2, served from the API so /docs works offline.
+	//go:embed docs/swagger-ui/swagger-ui-bundle.js docs/swagger-ui/swagger-ui.css
+	swaggerUIAssets embed.FS
+)
+
+// swaggerUIFS serves the assets without their directory.
+var swaggerUIFS, _ = fs.Sub(swaggerUIAssets, "docs/swagger-ui")
+
+func (h *Handler) GetOpenAPI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+func (h *Handler) GetDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+}
+
+func (h *Handler) GetDocsAsset(ctx *gin.Context) {
+	name := ctx.Param("name")[1:]
+	if _, err := fs.Stat(swaggerUIFS, name); err != nil {
+		NewErrorResponse(ctx, http.StatusNotFound, "asset not found")
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	ctx.FileFromFS(name, http.FS(swaggerUIFS))
+}
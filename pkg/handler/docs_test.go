@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]openAPIProperty `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// openAPIProperty holds the constraints of a property that binding tags have counterparts of.
+type openAPIProperty struct {
+	Format           string           `json:"format"`
+	Pattern          string           `json:"pattern"`
+	MinLength        *int             `json:"minLength"`
+	MaxLength        *int             `json:"maxLength"`
+	Minimum          *float64         `json:"minimum"`
+	Maximum          *float64         `json:"maximum"`
+	ExclusiveMinimum bool             `json:"exclusiveMinimum"`
+	MinItems         *int             `json:"minItems"`
+	MaxItems         *int             `json:"maxItems"`
+	Enum             []string         `json:"enum"`
+	Items            *openAPIProperty `json:"items"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDocument {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid: %s", err)
+	}
+	return doc
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := loadOpenAPI(t)
+	param := regexp.MustCompile(`[:*]([^/]+)`)
+
+	routers := map[bool]*gin.Engine{false: (&Handler{}).InitRoutes(), true: (&Handler{}).InitAdminRoutes()}
+	for admin, router := range routers {
+		for _, route := range router.Routes() {
+			path := param.ReplaceAllString(route.Path, "{$1}")
+			operations, ok := doc.Paths[path]
+			if !assert.Truef(t, ok, "path %s is missing in openapi.json", path) {
+				continue
+			}
+			_, ok = operations[strings.ToLower(route.Method)]
+			assert.Truef(t, ok, "operation %s %s is missing in openapi.json", route.Method, path)
+			// Admin routes name the admin listener as their server.
+			_, ok = operations["servers"]
+			assert.Equalf(t, admin, ok, "servers of path %s", path)
+		}
+	}
+}
+
+func TestOpenAPICoversModels(t *testing.T) {
+	doc := loadOpenAPI(t)
+	schemas := map[string]interface{}{
+		"Book":                      models.Book{},
+		"Genre":                     models.Genre{},
+		"BookPrice":                 models.BookPrice{},
+		"Promotion":                 models.Promotion{},
+		"AppliedPromotion":          models.AppliedPromotion{},
+		"PriceChange":               models.PriceChange{},
+		"ScheduledPrice":            models.ScheduledPrice{},
+		"Cart":                      models.Cart{},
+		"CartItem":                  models.CartItem{},
+		"Review":                    models.Review{},
+		"WaitlistEntry":             models.WaitlistEntry{},
+		"Location":                  models.Location{},
+		"StockLevel":                models.StockLevel{},
+		"StockTransfer":             models.StockTransfer{},
+		"Supplier":                  models.Supplier{},
+		"PurchaseOrder":             models.PurchaseOrder{},
+		"PurchaseOrderItem":         models.PurchaseOrderItem{},
+		"PurchaseOrderReceipt":      models.PurchaseOrderReceipt{},
+		"PurchaseOrderStatusChange": models.PurchaseOrderStatusChange{},
+		"Delivery":                  models.Delivery{},
+		"OutstandingOrders":         models.OutstandingOrders{},
+		"Stocktake":                 models.Stocktake{},
+		"StocktakeItem":             models.StocktakeItem{},
+		"StocktakeCount":            models.StocktakeCount{},
+		"StocktakeCounts":           models.StocktakeCounts{},
+		"StocktakeVariance":         models.StocktakeVariance{},
+		"StocktakeAdjustment":       models.StocktakeAdjustment{},
+		"ErrorResponse":             ErrorResponse{},
+		"StatusResponse":            StatusResponse{},
+		"WebhookSubscription":       models.WebhookSubscription{},
+		"WebhookDelivery":           models.WebhookDelivery{},
+		"Event":                     models.Event{},
+	}
+	for name, model := range schemas {
+		schema, ok := doc.Components.Schemas[name]
+		if !assert.Truef(t, ok, "schema %s is missing in openapi.json", name) {
+			continue
+		}
+		typ := reflect.TypeOf(model)
+		for i := 0; i < typ.NumField(); i++ {
+			field := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if field == "" || field == "-" {
+				continue
+			}
+			property, ok := schema.Properties[field]
+			if !assert.Truef(t, ok, "property %s.%s is missing in openapi.json", name, field) {
+				continue
+			}
+			binding := typ.Field(i).Tag.Get("binding")
+			if binding == "" {
+				continue
+			}
+			rules := strings.Split(binding, ",")
+			for _, rule := range rules {
+				if rule == "required" {
+					assert.Containsf(t, schema.Required, field, "%s.%s is required", name, field)
+				}
+			}
+			assertBinding(t, name+"."+field, typ.Field(i).Type, rules, property)
+		}
+	}
+}
+
+var money = reflect.TypeOf(models.Money(0))
+
+// assertBinding checks that the property documents the rules of a binding tag of a field of type typ.
+// Rules after dive apply to the items of slices.
+func assertBinding(t *testing.T, name string, typ reflect.Type, rules []string, property openAPIProperty) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	for i, rule := range rules {
+		if rule == "dive" {
+			if assert.NotNilf(t, property.Items, "%s has items", name) {
+				assertBinding(t, name+"[]", typ.Elem(), rules[i+1:], *property.Items)
+			}
+			return
+		}
+		key, value := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			key, value = rule[:j], rule[j+1:]
+		}
+		number, _ := strconv.ParseFloat(value, 64)
+		length, _ := strconv.Atoi(value)
+		switch {
+		case typ == money:
+			// Money is a decimal string, its pattern leaves out negative amounts.
+			assert.Truef(t, property.Pattern != "" && !strings.Contains(property.Pattern, "-"), "%s has a pattern of non-negative amounts", name)
+		case key == "email":
+			assert.Equalf(t, "email", property.Format, "format of %s", name)
+		case key == "url":
+			assert.Equalf(t, "uri", property.Format, "format of %s", name)
+		case key == "startswith":
+			assert.Truef(t, strings.HasPrefix(property.Pattern, "^"+regexp.QuoteMeta(value)), "pattern of %s starts with %s", name, value)
+		case key == "oneof":
+			assert.ElementsMatchf(t, strings.Fields(value), property.Enum, "enum of %s", name)
+		case key == "len" && property.Pattern != "":
+			assert.Containsf(t, property.Pattern, "{"+value+"}", "pattern of %s has length %s", name, value)
+		case typ.Kind() == reflect.String && key == "len":
+			assert.Equalf(t, &length, property.MinLength, "minLength of %s", name)
+			assert.Equalf(t, &length, property.MaxLength, "maxLength of %s", name)
+		case typ.Kind() == reflect.String && key == "min":
+			assert.Equalf(t, &length, property.MinLength, "minLength of %s", name)
+		case typ.Kind() == reflect.String && key == "max":
+			assert.Equalf(t, &length, property.MaxLength, "maxLength of %s", name)
+		case typ.Kind() == reflect.Slice && key == "min":
+			assert.Equalf(t, &length, property.MinItems, "minItems of %s", name)
+		case typ.Kind() == reflect.Slice && key == "max":
+			assert.Equalf(t, &length, property.MaxItems, "maxItems of %s", name)
+		case key == "min":
+			assert.Equalf(t, &number, property.Minimum, "minimum of %s", name)
+		case key == "max":
+			assert.Equalf(t, &number, property.Maximum, "maximum of %s", name)
+		case key == "gt":
+			assert.Truef(t, property.ExclusiveMinimum, "%s has an exclusive minimum", name)
+			assert.Equalf(t, &number, property.Minimum, "minimum of %s", name)
+		}
+	}
+}
+
+func TestGetDocsAsset(t *testing.T) {
+	tests := []struct {
+		name                string
+		target              string
+		expectedStatusCode  int
+		expectedContentType string
+	}{
+		{name: "Script", target: "/docs/assets/swagger-ui-bundle.js", expectedStatusCode: http.StatusOK,
+			expectedContentType: "text/javascript; charset=utf-8"},
+		{name: "Stylesheet", target: "/docs/assets/swagger-ui.css", expectedStatusCode: http.StatusOK,
+			expectedContentType: "text/css; charset=utf-8"},
+		{name: "Not found", target: "/docs/assets/LICENSE", expectedStatusCode: http.StatusNotFound,
+			expectedContentType: "application/json; charset=utf-8"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := (&Handler{}).InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestDocsAreSelfHosted(t *testing.T) {
+	assert.NotRegexp(t, `(src|href)="https?://`, string(swaggerUI))
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *Handler) GraphQL(ctx *gin.Context) {
+	var req graphQLRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	result := h.graphql.Execute(ctx.Request.Context(), req.Query, req.OperationName, req.Variables)
+	ctx.JSON(http.StatusOK, result)
+}
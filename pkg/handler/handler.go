@@ -1,33 +1,171 @@
 package handler
 
 import (
+	"errors"
+	"expvar"
 	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/gql"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/ratelimit"
 	"github.com/TenderLimbo/rest-api/pkg/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type Handler struct {
-	service service.BooksManager
+	service         service.BooksManager
+	idempotencyKeys service.Idempotency
+	webhooks        service.Webhooks
+	stream          service.Stream
+	streamHeartbeat time.Duration
+	pricing         service.Pricing
+	promotions      service.Promotions
+	priceHistory    service.PriceHistory
+	inventory       service.Inventory
+	carts           service.Carts
+	reviews         service.Reviews
+	waitlist        service.Waitlist
+	covers          service.Covers
+	locations       service.Locations
+	suppliers       service.Suppliers
+	purchaseOrders  service.PurchaseOrders
+	stocktakes      service.Stocktakes
+	coverMaxSize    int64
+	graphql         *gql.Server
+	limiter         *ratelimit.Limiter
+	legacySunset    time.Time
+	availability    map[string]string
+	trustedProxies  []string
+	runtime         atomic.Value // Runtime
+	config          ConfigSource
 }
 
-func NewHandler(service service.BooksManager) *Handler {
-	return &Handler{service: service}
+// Options holds the transport level dependencies of the handlers.
+type Options struct {
+	GraphQL         *gql.Server
+	Limiter         *ratelimit.Limiter
+	LegacySunset    time.Time
+	StreamHeartbeat time.Duration
+	// Availability is the availability filter of book lists without one, per route group.
+	// Groups missing from it list the books in stock.
+	Availability map[string]string
+	// CoverMaxSize bounds the request bodies of cover uploads, unbounded when 0.
+	CoverMaxSize int64
+	// TrustedProxies may set the client IP with X-Forwarded-For, none are trusted when empty.
+	TrustedProxies []string
+	// Runtime are the settings until Handler.SetRuntime changes them.
+	Runtime Runtime
+	Config  ConfigSource
+}
+
+// Route groups of Options.Availability.
+const (
+	RouteGroupV1     = "v1"
+	RouteGroupLegacy = "legacy"
+)
+
+func NewHandler(services *service.Service, opts Options) *Handler {
+	if opts.StreamHeartbeat <= 0 {
+		opts.StreamHeartbeat = defaultStreamHeartbeat
+	}
+	h := &Handler{
+		service:         services.BooksManager,
+		idempotencyKeys: services.Idempotency,
+		webhooks:        services.Webhooks,
+		stream:          services.Stream,
+		streamHeartbeat: opts.StreamHeartbeat,
+		pricing:         services.Pricing,
+		promotions:      services.Promotions,
+		priceHistory:    services.PriceHistory,
+		inventory:       services.Inventory,
+		carts:           services.Carts,
+		reviews:         services.Reviews,
+		waitlist:        services.Waitlist,
+		covers:          services.Covers,
+		locations:       services.Locations,
+		suppliers:       services.Suppliers,
+		purchaseOrders:  services.PurchaseOrders,
+		stocktakes:      services.Stocktakes,
+		coverMaxSize:    opts.CoverMaxSize,
+		graphql:         opts.GraphQL,
+		limiter:         opts.Limiter,
+		legacySunset:    opts.LegacySunset,
+		availability:    opts.Availability,
+		trustedProxies:  opts.TrustedProxies,
+		config:          opts.Config,
+	}
+	h.SetRuntime(opts.Runtime)
+	return h
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
-	router := gin.Default()
-	router.GET("/books", h.GetBooks)
-	router.GET("/books/:id", h.GetBookByID)
-	router.POST("/books", h.CreateBook)
-	router.DELETE("books/:id", h.DeleteBookByID)
-	router.PUT("books/:id", h.UpdateBookByID)
+	router := gin.New()
+	// The config is validated, so the proxies are IPs or CIDRs.
+	if err := router.SetTrustedProxies(h.trustedProxies); err != nil {
+		logging.Errorf("trusted proxies: %s", err)
+	}
+	router.Use(accessLog, gin.Recovery(), h.cors, h.idempotency)
+	router.GET("/openapi.json", h.GetOpenAPI)
+	router.GET("/docs", h.GetDocs)
+	router.GET("/docs/assets/*name", h.GetDocsAsset)
+	router.POST("/graphql", h.feature(FeatureGraphQL), h.rateLimit("graphql"), h.GraphQL)
+
+	api := router.Group("/api")
+	v1 := api.Group("/v1", withPresenter(v1Presenter{}), h.withAvailability(RouteGroupV1))
+	h.initBooksRoutes(v1)
+	h.initWebhooksRoutes(v1)
+	h.initPricesRoutes(v1)
+	h.initPromotionsRoutes(v1)
+	h.initInventoryRoutes(v1)
+	h.initCartsRoutes(v1)
+	h.initReviewsRoutes(v1)
+	h.initWaitlistRoutes(v1)
+	h.initCoversRoutes(v1)
+	h.initLocationsRoutes(v1)
+	h.initPurchaseOrdersRoutes(v1)
+	h.initStocktakesRoutes(v1)
+
+	// Routes from before versioning are kept as aliases of v1 until the sunset date.
+	h.initBooksRoutes(router.Group("", h.deprecated, withPresenter(v1Presenter{}), h.withAvailability(RouteGroupLegacy)))
 	return router
 }
 
+// InitAdminRoutes returns the routes for operators. They are served on a separate listener that
+// is not exposed publicly.
+func (h *Handler) InitAdminRoutes() *gin.Engine {
+	router := gin.New()
+	router.Use(accessLog, gin.Recovery())
+	router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	admin := router.Group("/admin")
+	admin.GET("/config", h.GetConfig)
+	h.initModerationRoutes(admin)
+	return router
+}
+
+func (h *Handler) initBooksRoutes(group *gin.RouterGroup) {
+	books := group.Group("/books", h.rateLimit("books"))
+	{
+		books.GET("", h.GetBooks)
+		books.GET("/stream", h.feature(FeatureStream), h.StreamBooks)
+		books.GET("/:id", h.GetBookByID)
+		books.POST("", h.CreateBook)
+		books.DELETE("/:id", h.DeleteBookByID)
+		books.PUT("/:id", h.UpdateBookByID)
+	}
+}
+
 func (h *Handler) GetBooks(ctx *gin.Context) {
 	filterCondition := ctx.Request.URL.Query()
+	filterCondition.Del("currency")
+	availability := filterCondition.Get("availability")
+	filterCondition.Del("availability")
+	sort := filterCondition.Get("sort")
+	filterCondition.Del("sort")
+	location := filterCondition.Get("location")
+	filterCondition.Del("location")
 	if len(filterCondition) != 0 {
 		if !filterCondition.Has("genre") {
 			NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
@@ -39,12 +177,38 @@ func (h *Handler) GetBooks(ctx *gin.Context) {
 			return
 		}
 	}
+	if availability == "" {
+		availability = defaultAvailability(ctx)
+	}
+	if !models.ValidAvailability(availability) {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
+		return
+	}
+	filterCondition.Set("availability", availability)
+	if location != "" {
+		if locationID, err := strconv.Atoi(location); err != nil || locationID < 1 {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
+			return
+		}
+		filterCondition.Set("location", location)
+	}
+	if sort != "" {
+		if sort != models.SortRating {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid sort")
+			return
+		}
+		filterCondition.Set("sort", sort)
+	}
 	books, err := h.service.GetBooks(filterCondition)
 	if err != nil {
 		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
-	ctx.JSON(http.StatusOK, books)
+	books, ok := h.convertPrices(ctx, books)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, presenter(ctx).Books(books))
 }
 
 func (h *Handler) GetBookByID(ctx *gin.Context) {
@@ -58,7 +222,11 @@ func (h *Handler) GetBookByID(ctx *gin.Context) {
 		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
-	ctx.JSON(http.StatusOK, book)
+	books, ok := h.convertPrices(ctx, []models.Book{book})
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, presenter(ctx).Book(books[0]))
 }
 
 func (h *Handler) CreateBook(ctx *gin.Context) {
@@ -68,6 +236,10 @@ func (h *Handler) CreateBook(ctx *gin.Context) {
 		return
 	}
 	id, err := h.service.CreateBook(newBook)
+	if errors.Is(err, service.ErrUnknownCurrency) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
@@ -101,10 +273,15 @@ func (h *Handler) UpdateBookByID(ctx *gin.Context) {
 		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
 		return
 	}
-	if err = h.service.UpdateBookByID(id, newBook); err != nil {
+	_, err = h.service.UpdateBookByID(id, newBook)
+	if errors.Is(err, service.ErrUnknownCurrency) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
 		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 	newBook.ID = id
-	ctx.JSON(http.StatusOK, newBook)
+	ctx.JSON(http.StatusOK, presenter(ctx).Book(newBook.WithDefaults()))
 }
@@ -1,19 +1,29 @@
 package handler
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/ratelimit"
 	"github.com/TenderLimbo/rest-api/pkg/service"
 	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateBook(t *testing.T) {
@@ -30,10 +40,11 @@ func TestCreateBook(t *testing.T) {
 			name:      "Ok",
 			inputBody: `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
 			inputBook: models.Book{
-				Name:   "Book1",
-				Price:  0,
-				Genre:  1,
-				Amount: 0,
+				Name:     "Book1",
+				Price:    0,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   0,
 			},
 			mockBehavior: func(r *mock_service.MockBooksManager, book models.Book) {
 				r.EXPECT().CreateBook(book).Return(1, nil)
@@ -55,14 +66,45 @@ func TestCreateBook(t *testing.T) {
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: `{"error":"invalid input"}`,
 		},
+		{
+			name:                 "Fractional cents",
+			inputBody:            `{"name": "hello", "price": "67.885", "genre": 1, "amount": 7}`,
+			mockBehavior:         func(r *mock_service.MockBooksManager, book models.Book) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:                 "Invalid currency",
+			inputBody:            `{"name": "hello", "price": "67.88", "currency": "usd", "genre": 1, "amount": 7}`,
+			mockBehavior:         func(r *mock_service.MockBooksManager, book models.Book) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:      "Unknown currency",
+			inputBody: `{"name": "hello", "price": "67.88", "currency": "XXX", "genre": 1, "amount": 7}`,
+			inputBook: models.Book{
+				Name:     "hello",
+				Price:    6788,
+				Currency: "XXX",
+				Genre:    1,
+				Amount:   7,
+			},
+			mockBehavior: func(r *mock_service.MockBooksManager, book models.Book) {
+				r.EXPECT().CreateBook(book).Return(0, service.ErrUnknownCurrency)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"unknown currency"}`,
+		},
 		{
 			name:      "Not unique name",
-			inputBody: `{"name": "hello", "price": 67.88, "genre": 1, "amount": 7}`,
+			inputBody: `{"name": "hello", "price": "67.88", "genre": 1, "amount": 7}`,
 			inputBook: models.Book{
-				Name:   "hello",
-				Price:  67.88,
-				Genre:  1,
-				Amount: 7,
+				Name:     "hello",
+				Price:    6788,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   7,
 			},
 			mockBehavior: func(r *mock_service.MockBooksManager, book models.Book) {
 				r.EXPECT().CreateBook(book).Return(0, errors.New("input book name isn't unique"))
@@ -81,7 +123,7 @@ func TestCreateBook(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputBook)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := gin.New()
 			r.POST("/books", handler.CreateBook)
@@ -144,7 +186,7 @@ func TestDeleteBookByID(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := gin.New()
 			r.DELETE("/books/:id", handler.DeleteBookByID)
@@ -160,6 +202,26 @@ func TestDeleteBookByID(t *testing.T) {
 	}
 }
 
+func TestDefaultAvailability(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockManager := mock_service.NewMockBooksManager(c)
+	mockManager.EXPECT().GetBooks(map[string][]string{"availability": {models.AvailabilityAll}}).Return([]models.Book{}, nil)
+	mockManager.EXPECT().GetBooks(map[string][]string{"availability": {models.AvailabilityInStock}}).Return([]models.Book{}, nil).Times(2)
+
+	handler := Handler{
+		service:      service.NewService(mockManager),
+		availability: map[string]string{RouteGroupV1: models.AvailabilityAll},
+	}
+	r := handler.InitRoutes()
+	for _, target := range []string{"/api/v1/books", "/books", "/api/v1/books?availability=in_stock"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
 func TestGetBookByID(t *testing.T) {
 	type mockBehavior func(s *mock_service.MockBooksManager, id interface{})
 	tests := []struct {
@@ -181,15 +243,33 @@ func TestGetBookByID(t *testing.T) {
 			inputId: 1,
 			mockBehavior: func(r *mock_service.MockBooksManager, id interface{}) {
 				r.EXPECT().GetBookByID(id).Return(models.Book{
-					ID:     1,
-					Name:   "hello",
-					Price:  4.32,
-					Genre:  2,
-					Amount: 9,
+					ID:       1,
+					Name:     "hello",
+					Price:    432,
+					Currency: "USD",
+					Genre:    2,
+					Amount:   9,
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1,"name":"hello","price":"4.32","currency":"USD","genre":2,"amount":9,"reorder_threshold":0,"avg_rating":0,"review_count":0,"effective_price":"4.32","available":true}`,
+		},
+		{
+			name:    "Held by carts",
+			inputId: 1,
+			mockBehavior: func(r *mock_service.MockBooksManager, id interface{}) {
+				r.EXPECT().GetBookByID(id).Return(models.Book{
+					ID:       1,
+					Name:     "hello",
+					Price:    432,
+					Currency: "USD",
+					Genre:    2,
+					Amount:   2,
+					Held:     2,
 				}, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: `{"id":1,"name":"hello","price":4.32,"genre":2,"amount":9}`,
+			expectedResponseBody: `{"id":1,"name":"hello","price":"4.32","currency":"USD","genre":2,"amount":2,"reorder_threshold":0,"avg_rating":0,"review_count":0,"held":2,"effective_price":"4.32","available":false}`,
 		},
 		{
 			name:    "Id not found",
@@ -212,7 +292,7 @@ func TestGetBookByID(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := gin.New()
 			r.GET("/books/:id", handler.GetBookByID)
@@ -254,12 +334,60 @@ func TestGetBooks(t *testing.T) {
 		{
 			name:            "Get All Ok",
 			filterCondition: map[string][]string{},
+			mockBehavior: func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {
+				r.EXPECT().GetBooks(map[string][]string{"availability": {models.AvailabilityInStock}}).Return([]models.Book{}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:            "Out of stock Ok",
+			filterCondition: map[string][]string{"availability": {"out_of_stock"}, "genre": {"2"}},
+			mockBehavior: func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {
+				r.EXPECT().GetBooks(filterCondition).Return([]models.Book{{ID: 3, Name: "hello", Genre: 2, Amount: 0}}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"id":3,"name":"hello","price":"0.00","currency":"","genre":2,"amount":0,"reorder_threshold":0,"avg_rating":0,"review_count":0,"effective_price":"0.00","available":false}]`,
+		},
+		{
+			name:                 "Invalid availability",
+			filterCondition:      map[string][]string{"availability": {"sold"}},
+			mockBehavior:         func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid filter condition"}`,
+		},
+		{
+			name:            "Sort by rating Ok",
+			filterCondition: map[string][]string{"availability": {"all"}, "sort": {"rating"}},
+			mockBehavior: func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {
+				r.EXPECT().GetBooks(filterCondition).Return([]models.Book{}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Invalid sort",
+			filterCondition:      map[string][]string{"sort": {"price"}},
+			mockBehavior:         func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid sort"}`,
+		},
+		{
+			name:            "In stock at location Ok",
+			filterCondition: map[string][]string{"availability": {"in_stock"}, "genre": {"1"}, "location": {"2"}},
 			mockBehavior: func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {
 				r.EXPECT().GetBooks(filterCondition).Return([]models.Book{}, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
 			expectedResponseBody: `[]`,
 		},
+		{
+			name:                 "Invalid location",
+			filterCondition:      map[string][]string{"location": {"0"}},
+			mockBehavior:         func(r *mock_service.MockBooksManager, filterCondition map[string][]string) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid filter condition"}`,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -270,7 +398,7 @@ func TestGetBooks(t *testing.T) {
 			test.mockBehavior(mockManager, test.filterCondition)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := gin.New()
 			r.GET("/books", handler.GetBooks)
@@ -319,13 +447,14 @@ func TestUpdateBookByID(t *testing.T) {
 			inputId:   1,
 			inputBody: `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
 			inputBook: models.Book{
-				Name:   "Book1",
-				Price:  0,
-				Genre:  1,
-				Amount: 0,
+				Name:     "Book1",
+				Price:    0,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   0,
 			},
 			mockBehavior: func(r *mock_service.MockBooksManager, id interface{}, book models.Book) {
-				r.EXPECT().UpdateBookByID(id, book).Return(errors.New("id not found"))
+				r.EXPECT().UpdateBookByID(id, book).Return(models.StockChange{}, errors.New("id not found"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedResponseBody: `{"error":"id not found"}`,
@@ -335,16 +464,17 @@ func TestUpdateBookByID(t *testing.T) {
 			inputId:   1,
 			inputBody: `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
 			inputBook: models.Book{
-				Name:   "Book1",
-				Price:  0,
-				Genre:  1,
-				Amount: 0,
+				Name:     "Book1",
+				Price:    0,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   0,
 			},
 			mockBehavior: func(r *mock_service.MockBooksManager, id interface{}, book models.Book) {
-				r.EXPECT().UpdateBookByID(id, book).Return(nil)
+				r.EXPECT().UpdateBookByID(id, book).Return(models.StockChange{PreviousAmount: book.Amount, Book: book}, nil)
 			},
 			expectedStatusCode:   http.StatusOK,
-			expectedResponseBody: `{"id":1,"name":"Book1","price":0,"genre":1,"amount":0}`,
+			expectedResponseBody: `{"id":1,"name":"Book1","price":"0.00","currency":"USD","genre":1,"amount":0,"reorder_threshold":0,"avg_rating":0,"review_count":0,"available":false}`,
 		},
 	}
 	for _, test := range tests {
@@ -356,7 +486,7 @@ func TestUpdateBookByID(t *testing.T) {
 			test.mockBehavior(mockManager, test.inputId, test.inputBook)
 
 			services := service.NewService(mockManager)
-			handler := Handler{services}
+			handler := Handler{service: services}
 
 			r := gin.New()
 			r.PUT("/books/:id", handler.UpdateBookByID)
@@ -371,3 +501,1744 @@ func TestUpdateBookByID(t *testing.T) {
 		})
 	}
 }
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name                 string
+		remoteAddr           string
+		apiKey               string
+		forwardedFor         string
+		expectedStatusCode   int
+		expectedRetryAfter   string
+		expectedResponseBody string
+	}{
+		{
+			name:                 "First request Ok",
+			remoteAddr:           "192.0.2.1:1234",
+			apiKey:               "first",
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Other api key limited",
+			remoteAddr:           "192.0.2.1:1234",
+			apiKey:               "second",
+			expectedStatusCode:   http.StatusTooManyRequests,
+			expectedRetryAfter:   "60",
+			expectedResponseBody: `{"error":"rate limit exceeded"}`,
+		},
+		{
+			name:                 "Forwarded for of an untrusted client limited",
+			remoteAddr:           "192.0.2.1:1234",
+			forwardedFor:         "198.51.100.7",
+			expectedStatusCode:   http.StatusTooManyRequests,
+			expectedRetryAfter:   "60",
+			expectedResponseBody: `{"error":"rate limit exceeded"}`,
+		},
+		{
+			name:                 "Other client Ok",
+			remoteAddr:           "192.0.2.2:1234",
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Client behind a trusted proxy Ok",
+			remoteAddr:           "10.0.0.1:1234",
+			forwardedFor:         "198.51.100.7",
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Client behind a trusted proxy limited",
+			remoteAddr:           "10.0.0.1:1234",
+			forwardedFor:         "198.51.100.7",
+			expectedStatusCode:   http.StatusTooManyRequests,
+			expectedRetryAfter:   "60",
+			expectedResponseBody: `{"error":"rate limit exceeded"}`,
+		},
+	}
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockManager := mock_service.NewMockBooksManager(c)
+	mockManager.EXPECT().GetBooks(gomock.Any()).Return([]models.Book{}, nil).AnyTimes()
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[string]ratelimit.Limit{"books": {Rate: 1.0 / 60, Burst: 1}})
+	handler := Handler{service: service.NewService(mockManager), limiter: limiter, trustedProxies: []string{"10.0.0.1"}}
+	r := handler.InitRoutes()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/books", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.apiKey != "" {
+				req.Header.Set("X-API-Key", test.apiKey)
+			}
+			if test.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, test.expectedRetryAfter, w.Header().Get("Retry-After"))
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	type mockBehavior func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency)
+	tests := []struct {
+		name                 string
+		inputBody            string
+		idempotencyKey       string
+		apiKey               string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+		expectedHeader       http.Header
+	}{
+		{
+			name:      "Without key Ok",
+			inputBody: `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				b.EXPECT().CreateBook(gomock.Any()).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:           "First request stored",
+			inputBody:      `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: "abc",
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				i.EXPECT().Begin(scopedKey("", "abc"), gomock.Any()).Return(models.IdempotencyRecord{}, false, nil)
+				b.EXPECT().CreateBook(gomock.Any()).Return(1, nil)
+				i.EXPECT().Complete(scopedKey("", "abc"), http.StatusOK,
+					http.Header{"Content-Type": {"application/json; charset=utf-8"}}, []byte(`{"id":1}`)).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:           "Panicking request released",
+			inputBody:      `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: "abc",
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				i.EXPECT().Begin(scopedKey("", "abc"), gomock.Any()).Return(models.IdempotencyRecord{}, false, nil)
+				b.EXPECT().CreateBook(gomock.Any()).DoAndReturn(func(models.Book) (int, error) {
+					panic("nil map")
+				})
+				i.EXPECT().Abort(scopedKey("", "abc")).Return(nil)
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "Failed request released",
+			inputBody:      `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: "abc",
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				i.EXPECT().Begin(scopedKey("", "abc"), gomock.Any()).Return(models.IdempotencyRecord{}, false, nil)
+				b.EXPECT().CreateBook(gomock.Any()).Return(0, errors.New("connection refused"))
+				i.EXPECT().Abort(scopedKey("", "abc")).Return(nil)
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"error":"connection refused"}`,
+		},
+		{
+			name:           "Retry replayed",
+			inputBody:      `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: "abc",
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				i.EXPECT().Begin(scopedKey("", "abc"), gomock.Any()).Return(models.IdempotencyRecord{
+					StatusCode: http.StatusCreated,
+					Header:     models.ResponseHeader{"Content-Type": {"text/csv"}, "Location": {"/books/1"}},
+					Body:       []byte("id\n1\n"),
+				}, true, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: "id\n1\n",
+			expectedHeader:       http.Header{"Content-Type": {"text/csv"}, "Location": {"/books/1"}, "Idempotent-Replayed": {"true"}},
+		},
+		{
+			name:           "Key reused with other body",
+			inputBody:      `{"name": "Book2", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: "abc",
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				i.EXPECT().Begin(scopedKey("", "abc"), gomock.Any()).
+					Return(models.IdempotencyRecord{}, false, service.ErrIdempotencyKeyReused)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: `{"error":"idempotency key is already used for another request"}`,
+		},
+		{
+			name:           "Long API key fits",
+			inputBody:      `{"name": "Book1", "price": 0, "genre": 1, "amount": 0}`,
+			idempotencyKey: strings.Repeat("k", maxIdempotencyKeyLen),
+			apiKey:         strings.Repeat("a", 1000),
+			mockBehavior: func(b *mock_service.MockBooksManager, i *mock_service.MockIdempotency) {
+				key := scopedKey(strings.Repeat("a", 1000), strings.Repeat("k", maxIdempotencyKeyLen))
+				assert.LessOrEqual(t, len(key), 300)
+				i.EXPECT().Begin(key, gomock.Any()).Return(models.IdempotencyRecord{}, false, nil)
+				b.EXPECT().CreateBook(gomock.Any()).Return(1, nil)
+				i.EXPECT().Complete(key, http.StatusOK, gomock.Any(), []byte(`{"id":1}`)).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			mockIdempotency := mock_service.NewMockIdempotency(c)
+			test.mockBehavior(mockManager, mockIdempotency)
+
+			handler := Handler{service: service.NewService(mockManager), idempotencyKeys: mockIdempotency}
+
+			r := gin.New()
+			r.Use(gin.CustomRecoveryWithWriter(io.Discard, gin.RecoveryFunc(func(ctx *gin.Context, _ interface{}) {
+				ctx.AbortWithStatus(http.StatusInternalServerError)
+			})))
+			r.POST("/books", handler.idempotency, handler.CreateBook)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/books", bytes.NewBufferString(test.inputBody))
+			if test.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", test.idempotencyKey)
+			}
+			if test.apiKey != "" {
+				req.Header.Set("X-API-Key", test.apiKey)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+			if test.expectedHeader != nil {
+				assert.Equal(t, test.expectedHeader, w.Header())
+			}
+		})
+	}
+}
+
+// scopedKey is the stored idempotency key of a request from the default httptest client address.
+func scopedKey(apiKey, key string) string {
+	sum := sha256.Sum256([]byte("ip:192.0.2.1|" + apiKey))
+	return base64.RawURLEncoding.EncodeToString(sum[:]) + "|" + key
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	tests := []struct {
+		name                string
+		target              string
+		expectedDeprecation string
+		expectedSunset      string
+		expectedLink        string
+	}{
+		{
+			name:   "Versioned route",
+			target: "/api/v1/books",
+		},
+		{
+			name:                "Legacy route",
+			target:              "/books",
+			expectedDeprecation: "true",
+			expectedSunset:      "Thu, 30 Jun 2022 00:00:00 GMT",
+			expectedLink:        `</api/v1/books>; rel="successor-version"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			mockManager.EXPECT().GetBooks(gomock.Any()).Return([]models.Book{}, nil)
+
+			handler := Handler{
+				service:      service.NewService(mockManager),
+				legacySunset: time.Date(2022, 6, 30, 0, 0, 0, 0, time.UTC),
+			}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.target, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `[]`, w.Body.String())
+			assert.Equal(t, test.expectedDeprecation, w.Header().Get("Deprecation"))
+			assert.Equal(t, test.expectedSunset, w.Header().Get("Sunset"))
+			assert.Equal(t, test.expectedLink, w.Header().Get("Link"))
+		})
+	}
+}
+
+// readStreamBlock reads one event or comment from a Server-Sent Events response.
+func readStreamBlock(r *bufio.Reader) (string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return strings.Join(lines, "\n"), err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamBooks(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              string
+		lastEventID        string
+		heartbeat          time.Duration
+		expectedStatusCode int
+		expectedBlocks     []string
+	}{
+		{
+			name:               "Resume",
+			lastEventID:        "1",
+			expectedStatusCode: http.StatusOK,
+			expectedBlocks:     []string{"id:2\nevent:book.updated", "id:3\nevent:book.deleted", "id:4\nevent:book.created"},
+		},
+		{
+			name:               "Genre filter",
+			query:              "?genre=1",
+			lastEventID:        "1",
+			expectedStatusCode: http.StatusOK,
+			expectedBlocks:     []string{"id:3\nevent:book.deleted", "id:4\nevent:book.created"},
+		},
+		{
+			name:               "Heartbeat",
+			heartbeat:          10 * time.Millisecond,
+			expectedStatusCode: http.StatusOK,
+			expectedBlocks:     []string{": heartbeat"},
+		},
+		{
+			name:               "Invalid genre",
+			query:              "?genre=4",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid last event id",
+			lastEventID:        "abc",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broadcaster := service.NewBroadcaster(10, 10)
+			broadcaster.HandleEvent(models.Event{Type: models.EventBookCreated, BookID: 1, Book: &models.Book{ID: 1, Genre: 2}})
+			broadcaster.HandleEvent(models.Event{Type: models.EventBookUpdated, BookID: 1, Book: &models.Book{ID: 1, Genre: 2}})
+			broadcaster.HandleEvent(models.Event{Type: models.EventBookDeleted, BookID: 1})
+
+			heartbeat := test.heartbeat
+			if heartbeat == 0 {
+				heartbeat = time.Hour
+			}
+			handler := Handler{stream: broadcaster, streamHeartbeat: heartbeat}
+			srv := httptest.NewServer(handler.InitRoutes())
+			defer srv.Close()
+
+			req, err := http.NewRequest("GET", srv.URL+"/api/v1/books/stream"+test.query, nil)
+			assert.NoError(t, err)
+			if test.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, test.expectedStatusCode, resp.StatusCode)
+			if test.expectedStatusCode != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+			body := bufio.NewReader(resp.Body)
+			for i, expected := range test.expectedBlocks {
+				if i > 0 && i == len(test.expectedBlocks)-1 && test.lastEventID != "" {
+					// The replay has been read, so the stream is subscribed to live events.
+					broadcaster.HandleEvent(models.Event{Type: models.EventBookCreated, BookID: 2, Book: &models.Book{ID: 2, Genre: 1}})
+				}
+				block, err := readStreamBlock(body)
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(block, expected), "unexpected block %q", block)
+			}
+		})
+	}
+}
+
+func TestPrices(t *testing.T) {
+	type mockBehavior func(b *mock_service.MockBooksManager, p *mock_service.MockPricing)
+	effectivePrice := models.Money(6788)
+	book := models.Book{ID: 1, Name: "hello", Price: 6788, Currency: "USD", Genre: 1, Amount: 7, EffectivePrice: &effectivePrice}
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "Books in currency",
+			method: "GET",
+			target: "/api/v1/books?genre=1&currency=EUR",
+			mockBehavior: func(b *mock_service.MockBooksManager, p *mock_service.MockPricing) {
+				b.EXPECT().GetBooks(url.Values{"genre": {"1"}, "availability": {models.AvailabilityInStock}}).Return([]models.Book{{ID: 1, Name: "hello", Price: 6788, Currency: "USD", Genre: 1, Amount: 7}}, nil)
+				convertedPrice := models.Money(5973)
+				converted := book
+				converted.Price, converted.EffectivePrice, converted.Currency = convertedPrice, &convertedPrice, "EUR"
+				p.EXPECT().Convert([]models.Book{book}, "EUR").Return([]models.Book{converted}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"id":1,"name":"hello","price":"59.73","currency":"EUR","genre":1,"amount":7,"reorder_threshold":0,"avg_rating":0,"review_count":0,"effective_price":"59.73","available":true}]`,
+		},
+		{
+			name:   "Book in unknown currency",
+			method: "GET",
+			target: "/api/v1/books/1?currency=XXX",
+			mockBehavior: func(b *mock_service.MockBooksManager, p *mock_service.MockPricing) {
+				b.EXPECT().GetBookByID(1).Return(models.Book{ID: 1, Name: "hello", Price: 6788, Currency: "USD", Genre: 1, Amount: 7}, nil)
+				p.EXPECT().Convert([]models.Book{book}, "XXX").Return(nil, service.ErrUnknownCurrency)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"unknown currency"}`,
+		},
+		{
+			name:      "Set price",
+			method:    "PUT",
+			target:    "/api/v1/books/1/price-list/EUR",
+			inputBody: `{"price": "10.00"}`,
+			mockBehavior: func(b *mock_service.MockBooksManager, p *mock_service.MockPricing) {
+				p.EXPECT().SetPrice(models.BookPrice{BookID: 1, Currency: "EUR", Price: 1000}).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"book_id":1,"currency":"EUR","price":"10.00"}`,
+		},
+		{
+			name:                 "Set price of other currency",
+			method:               "PUT",
+			target:               "/api/v1/books/1/price-list/EUR",
+			inputBody:            `{"currency": "GBP", "price": "10.00"}`,
+			mockBehavior:         func(b *mock_service.MockBooksManager, p *mock_service.MockPricing) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:                 "Set price of invalid currency",
+			method:               "PUT",
+			target:               "/api/v1/books/1/price-list/euro",
+			inputBody:            `{"price": "10.00"}`,
+			mockBehavior:         func(b *mock_service.MockBooksManager, p *mock_service.MockPricing) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			mockPricing := mock_service.NewMockPricing(c)
+			test.mockBehavior(mockManager, mockPricing)
+
+			handler := Handler{service: service.NewService(mockManager), pricing: mockPricing}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestCreatePromotion(t *testing.T) {
+	promotion := models.Promotion{
+		Name:     "Fantasy weekend",
+		Type:     models.PromotionPercentage,
+		Value:    2000,
+		Scope:    models.PromotionScopeGenre,
+		Target:   "3",
+		StartsAt: time.Date(2021, 11, 20, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2021, 11, 22, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         func(p *mock_service.MockPromotions)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Ok",
+			inputBody: `{"name": "Fantasy weekend", "type": "percentage", "value": "20", "scope": "genre", "target": "3",
+				"starts_at": "2021-11-20T00:00:00Z", "ends_at": "2021-11-22T00:00:00Z"}`,
+			mockBehavior: func(p *mock_service.MockPromotions) {
+				p.EXPECT().CreatePromotion(promotion).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name: "Ends before start",
+			inputBody: `{"name": "Fantasy weekend", "type": "percentage", "value": "20", "scope": "genre", "target": "3",
+				"starts_at": "2021-11-22T00:00:00Z", "ends_at": "2021-11-20T00:00:00Z"}`,
+			mockBehavior:         func(p *mock_service.MockPromotions) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name: "Invalid promotion",
+			inputBody: `{"name": "Fantasy weekend", "type": "percentage", "value": "20", "scope": "genre", "target": "3",
+				"starts_at": "2021-11-20T00:00:00Z", "ends_at": "2021-11-22T00:00:00Z"}`,
+			mockBehavior: func(p *mock_service.MockPromotions) {
+				p.EXPECT().CreatePromotion(promotion).Return(0, service.ErrInvalidPromotion)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid promotion"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockPromotions := mock_service.NewMockPromotions(c)
+			test.mockBehavior(mockPromotions)
+
+			handler := Handler{promotions: mockPromotions}
+			r := gin.New()
+			r.POST("/promotions", handler.CreatePromotion)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/promotions", bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestScheduledPrices(t *testing.T) {
+	effectiveAt := time.Date(2030, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         func(p *mock_service.MockPriceHistory)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "Price history",
+			method: "GET",
+			target: "/api/v1/books/1/prices",
+			mockBehavior: func(p *mock_service.MockPriceHistory) {
+				p.EXPECT().GetPriceHistory(1).Return([]models.PriceChange{
+					{BookID: 1, Price: 4599, Currency: "USD", ChangedAt: effectiveAt},
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"book_id":1,"price":"45.99","currency":"USD","changed_at":"2030-01-02T10:00:00Z"}]`,
+		},
+		{
+			name:      "Schedule price",
+			method:    "POST",
+			target:    "/api/v1/books/1/scheduled-prices",
+			inputBody: `{"price": "39.99", "effective_at": "2030-01-02T10:00:00Z"}`,
+			mockBehavior: func(p *mock_service.MockPriceHistory) {
+				p.EXPECT().SchedulePrice(models.ScheduledPrice{BookID: 1, Price: 3999, EffectiveAt: effectiveAt}).Return(3, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":3}`,
+		},
+		{
+			name:      "Schedule price in the past",
+			method:    "POST",
+			target:    "/api/v1/books/1/scheduled-prices",
+			inputBody: `{"price": "39.99", "effective_at": "2020-01-02T10:00:00Z"}`,
+			mockBehavior: func(p *mock_service.MockPriceHistory) {
+				p.EXPECT().SchedulePrice(gomock.Any()).Return(0, service.ErrPriceNotInFuture)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"effective time must be in the future"}`,
+		},
+		{
+			name:                 "Schedule price without time",
+			method:               "POST",
+			target:               "/api/v1/books/1/scheduled-prices",
+			inputBody:            `{"price": "39.99"}`,
+			mockBehavior:         func(p *mock_service.MockPriceHistory) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:   "Cancel scheduled price",
+			method: "DELETE",
+			target: "/api/v1/books/1/scheduled-prices/3",
+			mockBehavior: func(p *mock_service.MockPriceHistory) {
+				p.EXPECT().CancelScheduledPrice(1, 3).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockPriceHistory := mock_service.NewMockPriceHistory(c)
+			test.mockBehavior(mockPriceHistory)
+
+			handler := Handler{priceHistory: mockPriceHistory}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetLowStockBooks(t *testing.T) {
+	tests := []struct {
+		name                 string
+		mockBehavior         func(i *mock_service.MockInventory)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Ok",
+			mockBehavior: func(i *mock_service.MockInventory) {
+				i.EXPECT().GetLowStockBooks().Return([]models.Book{
+					{ID: 2, Name: "hello", Price: 432, Currency: "USD", Genre: 1, Amount: 1, ReorderThreshold: 5},
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"id":2,"name":"hello","price":"4.32","currency":"USD","genre":1,"amount":1,"reorder_threshold":5,"avg_rating":0,"review_count":0,"available":true}]`,
+		},
+		{
+			name: "Service error",
+			mockBehavior: func(i *mock_service.MockInventory) {
+				i.EXPECT().GetLowStockBooks().Return(nil, errors.New("something went wrong"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"error":"something went wrong"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockInventory := mock_service.NewMockInventory(c)
+			test.mockBehavior(mockInventory)
+
+			handler := Handler{inventory: mockInventory}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/inventory/low-stock", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestCarts(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockCarts)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "Create",
+			method: "POST",
+			target: "/api/v1/carts",
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().CreateCart().Return(models.Cart{ID: 1, Status: models.CartOpen, Token: "secret", TokenHash: "hash"}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1,"token":"secret"}`,
+		},
+		{
+			name:   "Get with other token",
+			method: "GET",
+			target: "/api/v1/carts/1",
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().GetCartByID(1, "secret").Return(models.Cart{}, service.ErrInvalidCartToken)
+			},
+			expectedStatusCode:   http.StatusForbidden,
+			expectedResponseBody: `{"error":"invalid cart token"}`,
+		},
+		{
+			name:      "Set item",
+			method:    "PUT",
+			target:    "/api/v1/carts/1/items/2",
+			inputBody: `{"quantity": 3}`,
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().SetCartItem(1, "secret", models.CartItem{BookID: 2, Quantity: 3}).
+					Return(models.CartItem{CartID: 1, BookID: 2, Quantity: 3, ExpiresAt: expiresAt}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"book_id":2,"quantity":3,"expires_at":"2030-01-01T00:15:00Z"}`,
+		},
+		{
+			name:      "Set item over stock",
+			method:    "PUT",
+			target:    "/api/v1/carts/1/items/2",
+			inputBody: `{"quantity": 30}`,
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().SetCartItem(1, "secret", models.CartItem{BookID: 2, Quantity: 30}).Return(models.CartItem{}, service.ErrInsufficientStock)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"insufficient stock"}`,
+		},
+		{
+			name:      "Set item over cart maximum",
+			method:    "PUT",
+			target:    "/api/v1/carts/1/items/2",
+			inputBody: `{"quantity": 21}`,
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().SetCartItem(1, "secret", models.CartItem{BookID: 2, Quantity: 21}).Return(models.CartItem{}, service.ErrCartFull)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"cart holds too many books"}`,
+		},
+		{
+			name:                 "Set item without quantity",
+			method:               "PUT",
+			target:               "/api/v1/carts/1/items/2",
+			inputBody:            `{"quantity": 0}`,
+			mockBehavior:         func(s *mock_service.MockCarts) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:   "Checkout",
+			method: "POST",
+			target: "/api/v1/carts/1/checkout",
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().Checkout(1, "secret").Return(models.Cart{
+					ID:           1,
+					Status:       models.CartCheckedOut,
+					Items:        []models.CartItem{{CartID: 1, BookID: 2, Quantity: 3, ExpiresAt: expiresAt}},
+					CreatedAt:    expiresAt,
+					CheckedOutAt: &expiresAt,
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":1,"status":"checked_out","items":[{"book_id":2,"quantity":3,"expires_at":"2030-01-01T00:15:00Z"}],` +
+				`"created_at":"2030-01-01T00:15:00Z","checked_out_at":"2030-01-01T00:15:00Z"}`,
+		},
+		{
+			name:   "Checkout twice",
+			method: "POST",
+			target: "/api/v1/carts/1/checkout",
+			mockBehavior: func(s *mock_service.MockCarts) {
+				s.EXPECT().Checkout(1, "secret").Return(models.Cart{}, service.ErrCartNotOpen)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"cart is checked out"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockCarts := mock_service.NewMockCarts(c)
+			test.mockBehavior(mockCarts)
+
+			handler := Handler{carts: mockCarts}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			req.Header.Set("Cart-Token", "secret")
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestReviews(t *testing.T) {
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		token                string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockReviews)
+		expectedStatusCode   int
+		expectedTotalCount   string
+		expectedResponseBody string
+	}{
+		{
+			name:      "Create",
+			method:    "POST",
+			target:    "/api/v1/books/2/reviews",
+			inputBody: `{"author":"Ann","rating":5,"text":"Great"}`,
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().CreateReview(models.Review{BookID: 2, Author: "Ann", Rating: 5, Text: "Great"}).
+					Return(models.Review{ID: 1, BookID: 2, Author: "Ann", Rating: 5, Text: "Great", Status: models.ReviewPending,
+						Token: "secret", TokenHash: "hash", CreatedAt: createdAt, UpdatedAt: createdAt}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":1,"book_id":2,"author":"Ann","rating":5,"text":"Great","status":"pending","token":"secret",` +
+				`"created_at":"2030-01-01T00:00:00Z","updated_at":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name:                 "Create with rating out of range",
+			method:               "POST",
+			target:               "/api/v1/books/2/reviews",
+			inputBody:            `{"author":"Ann","rating":6}`,
+			mockBehavior:         func(s *mock_service.MockReviews) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:   "List page",
+			method: "GET",
+			target: "/api/v1/books/2/reviews?limit=1&offset=1",
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().GetReviews(2, models.Page{Limit: 1, Offset: 1}).
+					Return([]models.Review{{ID: 1, BookID: 2, Author: "Ann", Rating: 4, Status: models.ReviewApproved,
+						CreatedAt: createdAt, UpdatedAt: createdAt}}, int64(2), nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedTotalCount: "2",
+			expectedResponseBody: `[{"id":1,"book_id":2,"author":"Ann","rating":4,"text":"","status":"approved",` +
+				`"created_at":"2030-01-01T00:00:00Z","updated_at":"2030-01-01T00:00:00Z"}]`,
+		},
+		{
+			name:                 "List with limit over max",
+			method:               "GET",
+			target:               "/api/v1/books/2/reviews?limit=101",
+			mockBehavior:         func(s *mock_service.MockReviews) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid limit"}`,
+		},
+		{
+			name:   "Delete with wrong token",
+			method: "DELETE",
+			target: "/api/v1/books/2/reviews/1",
+			token:  "wrong",
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().DeleteReview(2, 1, "wrong").Return(service.ErrInvalidReviewToken)
+			},
+			expectedStatusCode:   http.StatusForbidden,
+			expectedResponseBody: `{"error":"invalid review token"}`,
+		},
+		{
+			name:      "Update",
+			method:    "PUT",
+			target:    "/api/v1/books/2/reviews/1",
+			token:     "secret",
+			inputBody: `{"author":"Ann","rating":3}`,
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().UpdateReview(2, 1, "secret", models.Review{Author: "Ann", Rating: 3}).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:   "Pending",
+			method: "GET",
+			target: "/admin/reviews/pending",
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().GetPendingReviews(models.Page{Limit: 20}).Return([]models.Review{}, int64(0), nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedTotalCount:   "0",
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:   "Approve",
+			method: "POST",
+			target: "/admin/reviews/1/approve",
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().ModerateReview(1, models.ReviewApproved).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:   "Reject",
+			method: "POST",
+			target: "/admin/reviews/1/reject",
+			mockBehavior: func(s *mock_service.MockReviews) {
+				s.EXPECT().ModerateReview(1, models.ReviewRejected).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:                 "Moderation is not public",
+			method:               "POST",
+			target:               "/api/v1/reviews/1/approve",
+			mockBehavior:         func(s *mock_service.MockReviews) {},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `404 page not found`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockReviews := mock_service.NewMockReviews(c)
+			test.mockBehavior(mockReviews)
+
+			handler := Handler{reviews: mockReviews}
+			r := handler.InitRoutes()
+			if strings.HasPrefix(test.target, "/admin/") {
+				r = handler.InitAdminRoutes()
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			if test.token != "" {
+				req.Header.Set("Review-Token", test.token)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedTotalCount, w.Header().Get("X-Total-Count"))
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestLocations(t *testing.T) {
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockLocations)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Create",
+			method:    "POST",
+			target:    "/api/v1/locations",
+			inputBody: `{"name":"Shop"}`,
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().CreateLocation(models.Location{Name: "Shop"}).Return(2, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":2}`,
+		},
+		{
+			name:                 "Create without name",
+			method:               "POST",
+			target:               "/api/v1/locations",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_service.MockLocations) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:      "Update",
+			method:    "PUT",
+			target:    "/api/v1/locations/1",
+			inputBody: `{"name":"Main warehouse","is_default":false}`,
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().UpdateLocation(1, models.Location{Name: "Main warehouse"}).Return(nil)
+				s.EXPECT().GetLocationByID(1).
+					Return(models.Location{ID: 1, Name: "Main warehouse", IsDefault: true, CreatedAt: createdAt}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1,"name":"Main warehouse","is_default":true,"created_at":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name:   "Book stock",
+			method: "GET",
+			target: "/api/v1/books/3/stock",
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().GetBookStock(3).Return([]models.StockLevel{
+					{BookID: 3, LocationID: 1, Amount: 4},
+					{BookID: 3, LocationID: 2, Amount: 1},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `[{"book_id":3,"location_id":1,"amount":4},` +
+				`{"book_id":3,"location_id":2,"amount":1}]`,
+		},
+		{
+			name:   "Location stock",
+			method: "GET",
+			target: "/api/v1/locations/2/stock",
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().GetLocationStock(2).Return([]models.StockLevel{{BookID: 3, LocationID: 2, Amount: 1}}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"book_id":3,"location_id":2,"amount":1}]`,
+		},
+		{
+			name:      "Transfer",
+			method:    "POST",
+			target:    "/api/v1/transfers",
+			inputBody: `{"book_id":3,"from_location_id":1,"to_location_id":2,"quantity":2}`,
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().Transfer(models.StockTransfer{BookID: 3, FromLocationID: 1, ToLocationID: 2, Quantity: 2}).
+					Return(models.StockTransfer{ID: 1, BookID: 3, FromLocationID: 1, ToLocationID: 2, Quantity: 2,
+						CreatedAt: createdAt}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":1,"book_id":3,"from_location_id":1,"to_location_id":2,"quantity":2,` +
+				`"created_at":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name:      "Transfer more than in stock",
+			method:    "POST",
+			target:    "/api/v1/transfers",
+			inputBody: `{"book_id":3,"from_location_id":2,"to_location_id":1,"quantity":5}`,
+			mockBehavior: func(s *mock_service.MockLocations) {
+				s.EXPECT().Transfer(models.StockTransfer{BookID: 3, FromLocationID: 2, ToLocationID: 1, Quantity: 5}).
+					Return(models.StockTransfer{}, service.ErrInsufficientStock)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"insufficient stock"}`,
+		},
+		{
+			name:                 "Transfer to the same location",
+			method:               "POST",
+			target:               "/api/v1/transfers",
+			inputBody:            `{"book_id":3,"from_location_id":1,"to_location_id":1,"quantity":1}`,
+			mockBehavior:         func(s *mock_service.MockLocations) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockLocations := mock_service.NewMockLocations(c)
+			test.mockBehavior(mockLocations)
+
+			handler := Handler{locations: mockLocations}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestPurchaseOrders(t *testing.T) {
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cost := models.Money(290)
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Create supplier",
+			method:    "POST",
+			target:    "/api/v1/suppliers",
+			inputBody: `{"name":"Books Ltd","email":"orders@books.local"}`,
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				s.EXPECT().CreateSupplier(models.Supplier{Name: "Books Ltd", Email: "orders@books.local"}).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:      "Create",
+			method:    "POST",
+			target:    "/api/v1/purchase-orders",
+			inputBody: `{"supplier_id":1,"items":[{"book_id":2,"quantity":5,"unit_cost":"3.00"}]}`,
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().CreatePurchaseOrder(models.PurchaseOrder{SupplierID: 1,
+					Items: []models.PurchaseOrderItem{{BookID: 2, Quantity: 5, UnitCost: 300}}}).Return(4, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":4}`,
+		},
+		{
+			name:      "Create with a book twice",
+			method:    "POST",
+			target:    "/api/v1/purchase-orders",
+			inputBody: `{"supplier_id":1,"items":[{"book_id":2,"quantity":5},{"book_id":2,"quantity":1}]}`,
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().CreatePurchaseOrder(gomock.Any()).Return(0, service.ErrInvalidPurchaseOrder)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"book is ordered twice"}`,
+		},
+		{
+			name:                 "Create without items",
+			method:               "POST",
+			target:               "/api/v1/purchase-orders",
+			inputBody:            `{"supplier_id":1,"items":[]}`,
+			mockBehavior:         func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:                 "List in an unknown status",
+			method:               "GET",
+			target:               "/api/v1/purchase-orders?status=lost",
+			mockBehavior:         func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid filter condition"}`,
+		},
+		{
+			name:   "Send received",
+			method: "POST",
+			target: "/api/v1/purchase-orders/4/send",
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().SendPurchaseOrder(4).Return(service.ErrInvalidStatusChange)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"purchase order cannot change to the status"}`,
+		},
+		{
+			name:      "Receive",
+			method:    "POST",
+			target:    "/api/v1/purchase-orders/4/receive",
+			inputBody: `{"items":[{"book_id":2,"quantity":2,"unit_cost":"2.90"}]}`,
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().Receive(4, models.Delivery{Items: []models.PurchaseOrderReceipt{{BookID: 2, Quantity: 2, UnitCost: &cost}}}).
+					Return(models.PurchaseOrder{ID: 4, SupplierID: 1, Status: models.PurchaseOrderPartiallyReceived,
+						Items: []models.PurchaseOrderItem{{BookID: 2, Quantity: 5, Received: 2, UnitCost: 300}},
+						Receipts: []models.PurchaseOrderReceipt{{ID: 1, BookID: 2, LocationID: 1, Quantity: 2, UnitCost: &cost,
+							ReceivedAt: at}},
+						History: []models.PurchaseOrderStatusChange{
+							{Status: models.PurchaseOrderDraft, ChangedAt: at},
+							{Status: models.PurchaseOrderPartiallyReceived, ChangedAt: at},
+						},
+						CreatedAt: at, UpdatedAt: at}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":4,"supplier_id":1,"status":"partially_received",` +
+				`"items":[{"book_id":2,"quantity":5,"received":2,"unit_cost":"3.00"}],` +
+				`"receipts":[{"id":1,"book_id":2,"location_id":1,"quantity":2,"unit_cost":"2.90","received_at":"2030-01-01T00:00:00Z"}],` +
+				`"history":[{"status":"draft","changed_at":"2030-01-01T00:00:00Z"},{"status":"partially_received","changed_at":"2030-01-01T00:00:00Z"}],` +
+				`"created_at":"2030-01-01T00:00:00Z","updated_at":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name:      "Receive more than ordered",
+			method:    "POST",
+			target:    "/api/v1/purchase-orders/4/receive",
+			inputBody: `{"items":[{"book_id":2,"quantity":9}]}`,
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().Receive(4, models.Delivery{Items: []models.PurchaseOrderReceipt{{BookID: 2, Quantity: 9}}}).
+					Return(models.PurchaseOrder{}, service.ErrOverReceived)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"received more than ordered"}`,
+		},
+		{
+			name:   "Outstanding orders",
+			method: "GET",
+			target: "/api/v1/inventory/outstanding-orders",
+			mockBehavior: func(s *mock_service.MockSuppliers, o *mock_service.MockPurchaseOrders) {
+				o.EXPECT().GetOutstandingOrders().Return([]models.OutstandingOrders{
+					{SupplierID: 1, SupplierName: "Books Ltd", Orders: 1, Books: 3, Cost: 900},
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"supplier_id":1,"supplier_name":"Books Ltd","orders":1,"books":3,"cost":"9.00"}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockSuppliers := mock_service.NewMockSuppliers(c)
+			mockPurchaseOrders := mock_service.NewMockPurchaseOrders(c)
+			test.mockBehavior(mockSuppliers, mockPurchaseOrders)
+
+			handler := Handler{suppliers: mockSuppliers, purchaseOrders: mockPurchaseOrders}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestStocktakes(t *testing.T) {
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	location, counted := 2, 3
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockStocktakes)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Create for a location",
+			method:    "POST",
+			target:    "/api/v1/stocktakes",
+			inputBody: `{"location_id":2}`,
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().CreateStocktake(models.Stocktake{LocationID: &location}).Return(1, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:                 "Create without scope",
+			method:               "POST",
+			target:               "/api/v1/stocktakes",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_service.MockStocktakes) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:      "Counts",
+			method:    "PUT",
+			target:    "/api/v1/stocktakes/1/counts",
+			inputBody: `{"counts":[{"book_id":2,"quantity":0},{"book_id":3,"quantity":7}]}`,
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().SetCounts(1, models.StocktakeCounts{Counts: []models.StocktakeCount{
+					{BookID: 2, Quantity: 0}, {BookID: 3, Quantity: 7},
+				}}).Return(nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"status":"ok"}`,
+		},
+		{
+			name:      "Scan",
+			method:    "POST",
+			target:    "/api/v1/stocktakes/1/scans",
+			inputBody: `{"book_id":2}`,
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().AddCount(1, models.StocktakeCount{BookID: 2, Quantity: 1}).
+					Return(models.StocktakeItem{StocktakeID: 1, BookID: 2, Expected: 4, Counted: &counted}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"book_id":2,"expected":4,"counted":3}`,
+		},
+		{
+			name:      "Scan a book out of the stocktake",
+			method:    "POST",
+			target:    "/api/v1/stocktakes/1/scans",
+			inputBody: `{"book_id":9,"quantity":2}`,
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().AddCount(1, models.StocktakeCount{BookID: 9, Quantity: 2}).
+					Return(models.StocktakeItem{}, service.ErrNotInStocktake)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"book is not counted in the stocktake"}`,
+		},
+		{
+			name:   "Variances",
+			method: "GET",
+			target: "/api/v1/stocktakes/1/variances",
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().GetVariances(1).Return([]models.StocktakeVariance{{BookID: 2, Expected: 4, Counted: 3, Variance: -1}}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `[{"book_id":2,"expected":4,"counted":3,"variance":-1}]`,
+		},
+		{
+			name:   "Finalize",
+			method: "POST",
+			target: "/api/v1/stocktakes/1/finalize",
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().FinalizeStocktake(1).Return(models.Stocktake{ID: 1, LocationID: &location, Status: models.StocktakeFinalized,
+					Adjustments: []models.StocktakeAdjustment{{BookID: 2, Expected: 4, Counted: 3, PreviousAmount: 4, Amount: 3, CreatedAt: at}},
+					CreatedAt:   at, FinalizedAt: &at}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"id":1,"location_id":2,"status":"finalized",` +
+				`"adjustments":[{"book_id":2,"expected":4,"counted":3,"previous_amount":4,"amount":3,"created_at":"2030-01-01T00:00:00Z"}],` +
+				`"created_at":"2030-01-01T00:00:00Z","finalized_at":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name:   "Finalize twice",
+			method: "POST",
+			target: "/api/v1/stocktakes/1/finalize",
+			mockBehavior: func(s *mock_service.MockStocktakes) {
+				s.EXPECT().FinalizeStocktake(1).Return(models.Stocktake{}, service.ErrStocktakeFinalized)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"error":"stocktake is finalized"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockStocktakes := mock_service.NewMockStocktakes(c)
+			test.mockBehavior(mockStocktakes)
+
+			handler := Handler{stocktakes: mockStocktakes}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		headers              map[string]string
+		mockBehavior         func(s *mock_service.MockBooksManager)
+		expectedStatusCode   int
+		expectedAllowOrigin  string
+		expectedAllowHeaders string
+	}{
+		{
+			name:    "Allowed origin",
+			method:  "GET",
+			headers: map[string]string{"Origin": "https://shop.example"},
+			mockBehavior: func(s *mock_service.MockBooksManager) {
+				s.EXPECT().GetBooks(gomock.Any()).Return([]models.Book{}, nil)
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedAllowOrigin: "https://shop.example",
+		},
+		{
+			name:    "Other origin",
+			method:  "GET",
+			headers: map[string]string{"Origin": "https://other.example"},
+			mockBehavior: func(s *mock_service.MockBooksManager) {
+				s.EXPECT().GetBooks(gomock.Any()).Return([]models.Book{}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "Preflight",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://shop.example",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "Content-Type, Idempotency-Key",
+			},
+			mockBehavior:         func(s *mock_service.MockBooksManager) {},
+			expectedStatusCode:   http.StatusNoContent,
+			expectedAllowOrigin:  "https://shop.example",
+			expectedAllowHeaders: "Content-Type, Idempotency-Key",
+		},
+		{
+			name:   "Preflight of other origin",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://other.example",
+				"Access-Control-Request-Method": "POST",
+			},
+			mockBehavior:       func(s *mock_service.MockBooksManager) {},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			test.mockBehavior(mockManager)
+
+			handler := Handler{service: service.NewService(mockManager)}
+			handler.SetRuntime(Runtime{AllowedOrigins: []string{"https://shop.example"}})
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "/api/v1/books", nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedAllowOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, test.expectedAllowHeaders, w.Header().Get("Access-Control-Allow-Headers"))
+		})
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		target               string
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Stream",
+			method:               "GET",
+			target:               "/api/v1/books/stream",
+			expectedResponseBody: `{"error":"stream is disabled"}`,
+		},
+		{
+			name:                 "GraphQL",
+			method:               "POST",
+			target:               "/graphql",
+			expectedResponseBody: `{"error":"graphql is disabled"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Handler{}
+			handler.SetRuntime(Runtime{Features: map[string]bool{FeatureStream: false, FeatureGraphQL: false}})
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.target, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+type configSource map[string]interface{}
+
+func (s configSource) Effective() map[string]interface{} {
+	return s
+}
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		name                 string
+		config               ConfigSource
+		public               bool
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Ok",
+			config:               configSource{"log_level": "info", "db": map[string]interface{}{"password": "[redacted]"}},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"db":{"password":"[redacted]"},"log_level":"info"}`,
+		},
+		{
+			name:                 "No config",
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"error":"config is not available"}`,
+		},
+		{
+			name:                 "Not public",
+			config:               configSource{"log_level": "info"},
+			public:               true,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `404 page not found`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Handler{config: test.config}
+			r := handler.InitAdminRoutes()
+			if test.public {
+				r = handler.InitRoutes()
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/admin/config", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	tests := []struct {
+		name               string
+		admin              bool
+		expectedStatusCode int
+	}{
+		{name: "Public listener", expectedStatusCode: http.StatusNotFound},
+		{name: "Admin listener", admin: true, expectedStatusCode: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Handler{}
+			r := handler.InitRoutes()
+			if test.admin {
+				r = handler.InitAdminRoutes()
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/debug/vars", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestAddToWaitlist(t *testing.T) {
+	tests := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         func(s *mock_service.MockWaitlist)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Email",
+			inputBody: `{"email":"reader@books.local"}`,
+			mockBehavior: func(s *mock_service.MockWaitlist) {
+				s.EXPECT().AddToWaitlist(models.WaitlistEntry{BookID: 2, Email: "reader@books.local"}).
+					Return(models.WaitlistEntry{ID: 1, BookID: 2, Email: "reader@books.local"}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":1}`,
+		},
+		{
+			name:      "Webhook",
+			inputBody: `{"url":"https://example.com/hook"}`,
+			mockBehavior: func(s *mock_service.MockWaitlist) {
+				s.EXPECT().AddToWaitlist(models.WaitlistEntry{BookID: 2, URL: "https://example.com/hook"}).
+					Return(models.WaitlistEntry{ID: 2, BookID: 2, URL: "https://example.com/hook", Secret: "s3cret"}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":2,"secret":"s3cret"}`,
+		},
+		{
+			name:                 "Plain http webhook",
+			inputBody:            `{"url":"http://example.com/hook"}`,
+			mockBehavior:         func(s *mock_service.MockWaitlist) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:      "Missing book",
+			inputBody: `{"email":"reader@books.local"}`,
+			mockBehavior: func(s *mock_service.MockWaitlist) {
+				s.EXPECT().AddToWaitlist(models.WaitlistEntry{BookID: 2, Email: "reader@books.local"}).
+					Return(models.WaitlistEntry{}, service.ErrBookNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"error":"book not found"}`,
+		},
+		{
+			name:                 "Email and webhook",
+			inputBody:            `{"email":"reader@books.local","url":"https://example.com/hook"}`,
+			mockBehavior:         func(s *mock_service.MockWaitlist) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:                 "Neither",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_service.MockWaitlist) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+		{
+			name:                 "Invalid email",
+			inputBody:            `{"email":"reader"}`,
+			mockBehavior:         func(s *mock_service.MockWaitlist) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"error":"invalid input"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockWaitlist := mock_service.NewMockWaitlist(c)
+			test.mockBehavior(mockWaitlist)
+
+			handler := Handler{waitlist: mockWaitlist}
+			r := handler.InitRoutes()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/books/2/waitlist", bytes.NewBufferString(test.inputBody))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+// coverForm returns a multipart body with the cover field and its content type.
+func coverForm(t *testing.T, contentType, content string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="cover"; filename="cover"`)
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+	return &body, form.FormDataContentType()
+}
+
+func TestSetCover(t *testing.T) {
+	tests := []struct {
+		name                 string
+		contentType          string
+		content              string
+		mockBehavior         func(s *mock_service.MockCovers)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Ok",
+			contentType: "image/png",
+			content:     "png",
+			mockBehavior: func(s *mock_service.MockCovers) {
+				s.EXPECT().SetCover(2, gomock.Any()).DoAndReturn(func(bookID int, image io.Reader) (string, error) {
+					data, _ := io.ReadAll(image)
+					assert.Equal(t, "png", string(data))
+					return "0123456789abcdef.png", nil
+				})
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `{"cover_thumbnails":{"medium":"/api/v1/books/2/cover/0123456789abcdef-medium.png",` +
+				`"small":"/api/v1/books/2/cover/0123456789abcdef-small.png"},"cover_url":"/api/v1/books/2/cover/0123456789abcdef.png"}`,
+		},
+		{
+			name:                 "Unsupported content type",
+			contentType:          "image/gif",
+			content:              "gif",
+			mockBehavior:         func(s *mock_service.MockCovers) {},
+			expectedStatusCode:   http.StatusUnsupportedMediaType,
+			expectedResponseBody: `{"error":"cover must be a JPEG or PNG image"}`,
+		},
+		{
+			name:        "Not an image",
+			contentType: "image/jpeg",
+			content:     "text",
+			mockBehavior: func(s *mock_service.MockCovers) {
+				s.EXPECT().SetCover(2, gomock.Any()).Return("", service.ErrUnsupportedCover)
+			},
+			expectedStatusCode:   http.StatusUnsupportedMediaType,
+			expectedResponseBody: `{"error":"cover must be a JPEG or PNG image"}`,
+		},
+		{
+			name:        "Missing book",
+			contentType: "image/png",
+			content:     "png",
+			mockBehavior: func(s *mock_service.MockCovers) {
+				s.EXPECT().SetCover(2, gomock.Any()).Return("", service.ErrBookNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: `{"error":"book not found"}`,
+		},
+		{
+			name:                 "Too large",
+			contentType:          "image/jpeg",
+			content:              strings.Repeat("x", multipartOverhead+2048),
+			mockBehavior:         func(s *mock_service.MockCovers) {},
+			expectedStatusCode:   http.StatusRequestEntityTooLarge,
+			expectedResponseBody: `{"error":"cover is too large"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockCovers := mock_service.NewMockCovers(c)
+			test.mockBehavior(mockCovers)
+
+			handler := Handler{covers: mockCovers, coverMaxSize: 1024}
+			r := handler.InitRoutes()
+
+			body, contentType := coverForm(t, test.contentType, test.content)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/api/v1/books/2/cover", body)
+			req.Header.Set("Content-Type", contentType)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expectedStatusCode, w.Code)
+			assert.Equal(t, test.expectedResponseBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetCover(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockCovers := mock_service.NewMockCovers(c)
+	mockCovers.EXPECT().GetCover(2, "0123456789abcdef-small.png").Return(storage.Blob{
+		Content:     io.NopCloser(strings.NewReader("png")),
+		ContentType: "image/png",
+		Size:        3,
+	}, nil)
+	mockCovers.EXPECT().GetCover(2, "missing.png").Return(storage.Blob{}, storage.ErrNotFound)
+
+	handler := Handler{covers: mockCovers}
+	r := handler.InitRoutes()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/books/2/cover/0123456789abcdef-small.png", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "png", w.Body.String())
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/books/2/cover/missing.png", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
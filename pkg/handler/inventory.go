@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h *Handler) initInventoryRoutes(group *gin.RouterGroup) {
+	inventory := group.Group("/inventory")
+	{
+		inventory.GET("/low-stock", h.GetLowStockBooks)
+		inventory.GET("/outstanding-orders", h.GetOutstandingOrders)
+	}
+}
+
+func (h *Handler) GetLowStockBooks(ctx *gin.Context) {
+	books, err := h.inventory.GetLowStockBooks()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, presenter(ctx).Books(books))
+}
+
+// GetOutstandingOrders reports the books still to be received from each supplier.
+func (h *Handler) GetOutstandingOrders(ctx *gin.Context) {
+	report, err := h.purchaseOrders.GetOutstandingOrders()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initLocationsRoutes(group *gin.RouterGroup) {
+	locations := group.Group("/locations")
+	{
+		locations.GET("", h.GetLocations)
+		locations.POST("", h.CreateLocation)
+		locations.GET("/:id", h.GetLocationByID)
+		locations.PUT("/:id", h.UpdateLocation)
+		locations.GET("/:id/stock", h.GetLocationStock)
+	}
+	group.GET("/books/:id/stock", h.rateLimit("books"), h.GetBookStock)
+	group.POST("/transfers", h.Transfer)
+}
+
+func (h *Handler) GetLocations(ctx *gin.Context) {
+	locations, err := h.locations.GetLocations()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, locations)
+}
+
+func (h *Handler) CreateLocation(ctx *gin.Context) {
+	var location models.Location
+	if err := ctx.BindJSON(&location); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	id, err := h.locations.CreateLocation(location)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) GetLocationByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	location, err := h.locations.GetLocationByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, location)
+}
+
+// UpdateLocation renames the location, which location is the default cannot be changed.
+func (h *Handler) UpdateLocation(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var location models.Location
+	if err = ctx.BindJSON(&location); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if err = h.locations.UpdateLocation(id, location); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The stored location is returned as is_default and created_at do not come from the request.
+	location, err = h.locations.GetLocationByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, location)
+}
+
+func (h *Handler) GetLocationStock(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	levels, err := h.locations.GetLocationStock(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, levels)
+}
+
+// GetBookStock lists the stock of the book at every location holding it, they add up to its amount.
+func (h *Handler) GetBookStock(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	levels, err := h.locations.GetBookStock(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, levels)
+}
+
+// Transfer moves stock of a book between two locations, the amount of the book stays the same.
+func (h *Handler) Transfer(ctx *gin.Context) {
+	var transfer models.StockTransfer
+	if err := ctx.BindJSON(&transfer); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	transfer, err := h.locations.Transfer(transfer)
+	if errors.Is(err, service.ErrInsufficientStock) {
+		NewErrorResponse(ctx, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, transfer)
+}
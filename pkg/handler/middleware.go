@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+const (
+	apiKeyHeader         = "X-API-Key"
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// clientKey identifies the caller by client IP. The API key and user ID headers are not authenticated,
+// so they are not used: a client could get a fresh rate limit by changing them. The IP is only taken from
+// X-Forwarded-For behind the trusted proxies of api.trusted_proxies.
+func clientKey(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// idempotencyScope keeps the idempotency keys of clients apart, by client IP and API key. It is hashed to
+// 43 characters, so with a key of maxIdempotencyKeyLen it fits the 300 characters of the stored key.
+func idempotencyScope(ctx *gin.Context) string {
+	sum := sha256.Sum256([]byte(clientKey(ctx) + "|" + ctx.GetHeader(apiKeyHeader)))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (h *Handler) rateLimit(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if h.limiter == nil {
+			return
+		}
+		res, limited, err := h.limiter.Take(ctx.Request.Context(), group, clientKey(ctx))
+		if err != nil {
+			logging.Errorf("rate limiter: %s", err)
+			return
+		}
+		if !limited {
+			return
+		}
+		ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(int(res.Reset.Seconds())))
+		if !res.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(res.RetryAfter.Seconds())))
+			NewErrorResponse(ctx, http.StatusTooManyRequests, "rate limit exceeded")
+		}
+	}
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotency stores the first response to a POST with an Idempotency-Key header
+// and replays it for retries of the same request.
+func (h *Handler) idempotency(ctx *gin.Context) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if h.idempotencyKeys == nil || ctx.Request.Method != http.MethodPost || key == "" {
+		return
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid idempotency key")
+		return
+	}
+	body, err := ctx.GetRawData()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n"))
+	hash.Write(body)
+	key = idempotencyScope(ctx) + "|" + key
+
+	record, replay, err := h.idempotencyKeys.Begin(key, hex.EncodeToString(hash.Sum(nil)))
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		NewErrorResponse(ctx, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyInUse):
+		NewErrorResponse(ctx, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	case replay:
+		for name, values := range record.Header {
+			ctx.Writer.Header()[name] = values
+		}
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.Status(record.StatusCode)
+		if _, err = ctx.Writer.Write(record.Body); err != nil {
+			logging.Errorf("idempotency: %s", err)
+		}
+		ctx.Abort()
+		return
+	}
+
+	// A panic is recovered further out, the key is released so retries are not refused until it expires.
+	defer func() {
+		if r := recover(); r != nil {
+			if err := h.idempotencyKeys.Abort(key); err != nil {
+				logging.Errorf("idempotency: %s", err)
+			}
+			panic(r)
+		}
+	}()
+	before := ctx.Writer.Header().Clone()
+	recorder := bodyRecorder{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+	ctx.Writer = recorder
+	ctx.Next()
+
+	// Requests that failed or were rate limited can be retried with the key.
+	if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusTooManyRequests {
+		err = h.idempotencyKeys.Abort(key)
+	} else {
+		err = h.idempotencyKeys.Complete(key, recorder.Status(), handlerHeader(before, recorder.Header()), recorder.body.Bytes())
+	}
+	if err != nil {
+		logging.Errorf("idempotency: %s", err)
+	}
+}
+
+// unreplayedHeaders describe the request that was served rather than its response.
+var unreplayedHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// handlerHeader returns the headers set after before was taken, they are replayed with the response.
+func handlerHeader(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for name, values := range after {
+		if !reflect.DeepEqual(before[name], values) {
+			header[name] = values
+		}
+	}
+	for _, name := range unreplayedHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// exposedHeaders are the response headers browsers show to scripts of allowed origins.
+const exposedHeaders = "Deprecation, Idempotent-Replayed, Link, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, " +
+	"Retry-After, Sunset, X-Total-Count"
+
+// cors lets scripts of the allowed origins call the API and answers their preflight requests.
+// Requests of other origins are served without CORS headers, so browsers hide the responses.
+func (h *Handler) cors(ctx *gin.Context) {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" || !h.currentRuntime().allowsOrigin(origin) {
+		return
+	}
+	ctx.Writer.Header().Add("Vary", "Origin")
+	ctx.Header("Access-Control-Allow-Origin", origin)
+	ctx.Header("Access-Control-Expose-Headers", exposedHeaders)
+	if ctx.Request.Method != http.MethodOptions || ctx.GetHeader("Access-Control-Request-Method") == "" {
+		return
+	}
+	ctx.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+	if headers := ctx.GetHeader("Access-Control-Request-Headers"); headers != "" {
+		ctx.Header("Access-Control-Allow-Headers", headers)
+	}
+	ctx.Header("Access-Control-Max-Age", "600")
+	ctx.AbortWithStatus(http.StatusNoContent)
+}
+
+// feature serves the routes only while the feature is enabled.
+func (h *Handler) feature(name string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !h.currentRuntime().enabled(name) {
+			NewErrorResponse(ctx, http.StatusNotFound, name+" is disabled")
+		}
+	}
+}
+
+var accessLogger = gin.Logger()
+
+// accessLog logs requests at the info level.
+func accessLog(ctx *gin.Context) {
+	if logging.Enabled(logging.Info) {
+		accessLogger(ctx)
+	}
+}
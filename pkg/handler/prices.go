@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initPricesRoutes(group *gin.RouterGroup) {
+	book := group.Group("/books/:id", h.rateLimit("books"))
+	{
+		book.GET("/price-list", h.GetBookPrices)
+		book.PUT("/price-list/:currency", h.SetBookPrice)
+		book.DELETE("/price-list/:currency", h.DeleteBookPrice)
+		book.GET("/prices", h.GetPriceHistory)
+		book.GET("/scheduled-prices", h.GetScheduledPrices)
+		book.POST("/scheduled-prices", h.SchedulePrice)
+		book.DELETE("/scheduled-prices/:schedule_id", h.CancelScheduledPrice)
+	}
+}
+
+// convertPrices converts the books to the currency of the currency query parameter if
+// there is one. It responds with an error and returns false when the conversion fails.
+func (h *Handler) convertPrices(ctx *gin.Context, books []models.Book) ([]models.Book, bool) {
+	currency := ctx.Query("currency")
+	if currency == "" {
+		return books, true
+	}
+	books, err := h.pricing.Convert(books, currency)
+	if errors.Is(err, service.ErrUnknownCurrency) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return books, true
+}
+
+func (h *Handler) GetBookPrices(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	prices, err := h.pricing.GetPrices(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, prices)
+}
+
+func (h *Handler) SetBookPrice(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	// The book and currency come from the path, the body may repeat them.
+	price := models.BookPrice{BookID: id, Currency: ctx.Param("currency")}
+	if err = ctx.BindJSON(&price); err != nil || price.BookID != id || price.Currency != ctx.Param("currency") {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	err = h.pricing.SetPrice(price)
+	if errors.Is(err, service.ErrUnknownCurrency) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, price)
+}
+
+func (h *Handler) DeleteBookPrice(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.pricing.DeletePrice(id, ctx.Param("currency")); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
+
+func (h *Handler) GetPriceHistory(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	changes, err := h.priceHistory.GetPriceHistory(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, changes)
+}
+
+func (h *Handler) GetScheduledPrices(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	prices, err := h.priceHistory.GetScheduledPrices(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, prices)
+}
+
+func (h *Handler) SchedulePrice(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var price models.ScheduledPrice
+	if err = ctx.BindJSON(&price); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	price.BookID = id
+	scheduleID, err := h.priceHistory.SchedulePrice(price)
+	if errors.Is(err, service.ErrPriceNotInFuture) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": scheduleID,
+	})
+}
+
+// CancelScheduledPrice removes a scheduled price that has not been applied yet.
+func (h *Handler) CancelScheduledPrice(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	scheduleID, err := strconv.Atoi(ctx.Param("schedule_id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.priceHistory.CancelScheduledPrice(id, scheduleID); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initPromotionsRoutes(group *gin.RouterGroup) {
+	promotions := group.Group("/promotions")
+	{
+		promotions.GET("", h.GetPromotions)
+		promotions.GET("/:id", h.GetPromotionByID)
+		promotions.POST("", h.CreatePromotion)
+		promotions.PUT("/:id", h.UpdatePromotionByID)
+		promotions.DELETE("/:id", h.DeletePromotionByID)
+	}
+}
+
+func (h *Handler) GetPromotions(ctx *gin.Context) {
+	promotions, err := h.promotions.GetPromotions()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, promotions)
+}
+
+func (h *Handler) GetPromotionByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	promotion, err := h.promotions.GetPromotionByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, promotion)
+}
+
+func (h *Handler) CreatePromotion(ctx *gin.Context) {
+	var promotion models.Promotion
+	if err := ctx.BindJSON(&promotion); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	id, err := h.promotions.CreatePromotion(promotion)
+	if errors.Is(err, service.ErrInvalidPromotion) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) UpdatePromotionByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var promotion models.Promotion
+	if err = ctx.BindJSON(&promotion); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	err = h.promotions.UpdatePromotion(id, promotion)
+	if errors.Is(err, service.ErrInvalidPromotion) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	promotion.ID = id
+	ctx.JSON(http.StatusOK, promotion)
+}
+
+func (h *Handler) DeletePromotionByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.promotions.DeletePromotion(id); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initPurchaseOrdersRoutes(group *gin.RouterGroup) {
+	suppliers := group.Group("/suppliers")
+	{
+		suppliers.GET("", h.GetSuppliers)
+		suppliers.POST("", h.CreateSupplier)
+		suppliers.GET("/:id", h.GetSupplierByID)
+		suppliers.PUT("/:id", h.UpdateSupplier)
+	}
+	orders := group.Group("/purchase-orders")
+	{
+		orders.GET("", h.GetPurchaseOrders)
+		orders.POST("", h.CreatePurchaseOrder)
+		orders.GET("/:id", h.GetPurchaseOrderByID)
+		orders.POST("/:id/send", h.SendPurchaseOrder)
+		orders.POST("/:id/cancel", h.CancelPurchaseOrder)
+		orders.POST("/:id/receive", h.ReceivePurchaseOrder)
+	}
+}
+
+func (h *Handler) GetSuppliers(ctx *gin.Context) {
+	suppliers, err := h.suppliers.GetSuppliers()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, suppliers)
+}
+
+func (h *Handler) CreateSupplier(ctx *gin.Context) {
+	var supplier models.Supplier
+	if err := ctx.BindJSON(&supplier); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	id, err := h.suppliers.CreateSupplier(supplier)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) GetSupplierByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	supplier, err := h.suppliers.GetSupplierByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, supplier)
+}
+
+func (h *Handler) UpdateSupplier(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var supplier models.Supplier
+	if err = ctx.BindJSON(&supplier); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if err = h.suppliers.UpdateSupplier(id, supplier); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	supplier.ID = id
+	ctx.JSON(http.StatusOK, supplier)
+}
+
+// GetPurchaseOrders lists the purchase orders, optionally of a supplier and in a status.
+func (h *Handler) GetPurchaseOrders(ctx *gin.Context) {
+	var supplierID int
+	if supplier := ctx.Query("supplier"); supplier != "" {
+		var err error
+		if supplierID, err = strconv.Atoi(supplier); err != nil || supplierID < 1 {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
+			return
+		}
+	}
+	status := ctx.Query("status")
+	if status != "" && !models.ValidPurchaseOrderStatus(status) {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
+		return
+	}
+	orders, err := h.purchaseOrders.GetPurchaseOrders(supplierID, status)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, orders)
+}
+
+func (h *Handler) CreatePurchaseOrder(ctx *gin.Context) {
+	var order models.PurchaseOrder
+	if err := ctx.BindJSON(&order); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	id, err := h.purchaseOrders.CreatePurchaseOrder(order)
+	if errors.Is(err, service.ErrInvalidPurchaseOrder) {
+		NewErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) GetPurchaseOrderByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	order, err := h.purchaseOrders.GetPurchaseOrderByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (h *Handler) SendPurchaseOrder(ctx *gin.Context) {
+	h.changePurchaseOrderStatus(ctx, h.purchaseOrders.SendPurchaseOrder)
+}
+
+func (h *Handler) CancelPurchaseOrder(ctx *gin.Context) {
+	h.changePurchaseOrderStatus(ctx, h.purchaseOrders.CancelPurchaseOrder)
+}
+
+func (h *Handler) changePurchaseOrderStatus(ctx *gin.Context, change func(id int) error) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = change(id); err != nil {
+		purchaseOrderErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{"ok"})
+}
+
+// ReceivePurchaseOrder adds books of a sent purchase order to the stock, all of them or a part.
+func (h *Handler) ReceivePurchaseOrder(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var delivery models.Delivery
+	if err = ctx.BindJSON(&delivery); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	order, err := h.purchaseOrders.Receive(id, delivery)
+	if err != nil {
+		purchaseOrderErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, order)
+}
+
+// purchaseOrderErrorResponse responds with a conflict when the status or the items of the purchase order prevent the change.
+func purchaseOrderErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidStatusChange),
+		errors.Is(err, service.ErrNotOrdered),
+		errors.Is(err, service.ErrOverReceived):
+		NewErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,8 +1,9 @@
 package handler
 
 import (
+	"github.com/TenderLimbo/rest-api/pkg/logging"
 	"github.com/gin-gonic/gin"
-	"log"
+	"net/http"
 )
 
 type ErrorResponse struct {
@@ -14,6 +15,10 @@ type StatusResponse struct {
 }
 
 func NewErrorResponse(ctx *gin.Context, statusCode int, message string) {
-	log.Println(message)
+	if statusCode >= http.StatusInternalServerError {
+		logging.Errorf("%s", message)
+	} else {
+		logging.Infof("%s", message)
+	}
 	ctx.AbortWithStatusJSON(statusCode, ErrorResponse{message})
 }
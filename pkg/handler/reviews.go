@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func (h *Handler) initReviewsRoutes(group *gin.RouterGroup) {
+	bookReviews := group.Group("/books/:id/reviews", h.rateLimit("books"))
+	{
+		bookReviews.GET("", h.GetReviews)
+		bookReviews.POST("", h.CreateReview)
+		bookReviews.PUT("/:review_id", h.UpdateReview)
+		bookReviews.DELETE("/:review_id", h.DeleteReview)
+	}
+}
+
+// initModerationRoutes mounts the moderation of reviews, it is for staff only.
+func (h *Handler) initModerationRoutes(group *gin.RouterGroup) {
+	reviews := group.Group("/reviews")
+	{
+		reviews.GET("/pending", h.GetPendingReviews)
+		reviews.POST("/:id/approve", h.moderateReview(models.ReviewApproved))
+		reviews.POST("/:id/reject", h.moderateReview(models.ReviewRejected))
+	}
+}
+
+// GetReviews lists the approved reviews of the book, the number of all of them is in X-Total-Count.
+func (h *Handler) GetReviews(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	reviews, total, err := h.reviews.GetReviews(id, page)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// CreateReview queues the review for moderation. The response has the token to edit or delete it.
+func (h *Handler) CreateReview(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var review models.Review
+	if err = ctx.BindJSON(&review); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	review.BookID = id
+	review, err = h.reviews.CreateReview(review)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, review)
+}
+
+func (h *Handler) UpdateReview(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	reviewID, err := strconv.Atoi(ctx.Param("review_id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var review models.Review
+	if err = ctx.BindJSON(&review); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if err = h.reviews.UpdateReview(id, reviewID, ctx.GetHeader("Review-Token"), review); err != nil {
+		reviewErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{"ok"})
+}
+
+func (h *Handler) DeleteReview(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	reviewID, err := strconv.Atoi(ctx.Param("review_id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.reviews.DeleteReview(id, reviewID, ctx.GetHeader("Review-Token")); err != nil {
+		reviewErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
+
+// GetPendingReviews lists the moderation queue, the number of all pending reviews is in X-Total-Count.
+func (h *Handler) GetPendingReviews(ctx *gin.Context) {
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	reviews, total, err := h.reviews.GetPendingReviews(page)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+func (h *Handler) moderateReview(status string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+			return
+		}
+		if err = h.reviews.ModerateReview(id, status); err != nil {
+			NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, StatusResponse{"ok"})
+	}
+}
+
+// parsePage reads limit and offset from the query, it responds with an error when they are invalid.
+func parsePage(ctx *gin.Context) (models.Page, bool) {
+	page := models.Page{Limit: defaultPageLimit}
+	var err error
+	if limit := ctx.Query("limit"); limit != "" {
+		if page.Limit, err = strconv.Atoi(limit); err != nil || page.Limit < 1 || page.Limit > maxPageLimit {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid limit")
+			return page, false
+		}
+	}
+	if offset := ctx.Query("offset"); offset != "" {
+		if page.Offset, err = strconv.Atoi(offset); err != nil || page.Offset < 0 {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid offset")
+			return page, false
+		}
+	}
+	return page, true
+}
+
+func reviewErrorResponse(ctx *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidReviewToken) {
+		NewErrorResponse(ctx, http.StatusForbidden, err.Error())
+		return
+	}
+	NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initStocktakesRoutes(group *gin.RouterGroup) {
+	stocktakes := group.Group("/stocktakes")
+	{
+		stocktakes.GET("", h.GetStocktakes)
+		stocktakes.POST("", h.CreateStocktake)
+		stocktakes.GET("/:id", h.GetStocktakeByID)
+		stocktakes.PUT("/:id/counts", h.SetStocktakeCounts)
+		stocktakes.POST("/:id/scans", h.ScanStocktakeBook)
+		stocktakes.GET("/:id/variances", h.GetStocktakeVariances)
+		stocktakes.POST("/:id/finalize", h.FinalizeStocktake)
+	}
+}
+
+func (h *Handler) GetStocktakes(ctx *gin.Context) {
+	stocktakes, err := h.stocktakes.GetStocktakes()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, stocktakes)
+}
+
+// CreateStocktake opens a stocktake of the books of a genre, of the books at a location or of both.
+func (h *Handler) CreateStocktake(ctx *gin.Context) {
+	var stocktake models.Stocktake
+	if err := ctx.BindJSON(&stocktake); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	id, err := h.stocktakes.CreateStocktake(stocktake)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (h *Handler) GetStocktakeByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	stocktake, err := h.stocktakes.GetStocktakeByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, stocktake)
+}
+
+// SetStocktakeCounts replaces the counts of the books with the counted quantities.
+func (h *Handler) SetStocktakeCounts(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var counts models.StocktakeCounts
+	if err = ctx.BindJSON(&counts); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if err = h.stocktakes.SetCounts(id, counts); err != nil {
+		stocktakeErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, StatusResponse{"ok"})
+}
+
+// ScanStocktakeBook adds a scanned book to its count, the quantity is 1 unless one is sent.
+func (h *Handler) ScanStocktakeBook(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	count := models.StocktakeCount{Quantity: 1}
+	if err = ctx.BindJSON(&count); err != nil || count.Quantity < 1 {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	item, err := h.stocktakes.AddCount(id, count)
+	if err != nil {
+		stocktakeErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) GetStocktakeVariances(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	variances, err := h.stocktakes.GetVariances(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, variances)
+}
+
+// FinalizeStocktake corrects the stock of the counted books, the response lists the corrections.
+func (h *Handler) FinalizeStocktake(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	stocktake, err := h.stocktakes.FinalizeStocktake(id)
+	if err != nil {
+		stocktakeErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, stocktake)
+}
+
+// stocktakeErrorResponse responds with a conflict when the stocktake is finalized or does not count the book.
+func stocktakeErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrStocktakeFinalized),
+		errors.Is(err, service.ErrNotInStocktake):
+		NewErrorResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
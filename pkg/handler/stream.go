@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	lastEventIDHeader      = "Last-Event-ID"
+	defaultStreamHeartbeat = 15 * time.Second
+)
+
+// StreamBooks sends book events as Server-Sent Events. Clients that reconnect with
+// Last-Event-ID first receive the buffered events they missed.
+func (h *Handler) StreamBooks(ctx *gin.Context) {
+	var genreID int
+	var err error
+	if genre, ok := ctx.GetQuery("genre"); ok {
+		genreID, err = strconv.Atoi(genre)
+		if err != nil || genreID < 1 || genreID > 3 {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid filter condition")
+			return
+		}
+	}
+	var lastEventID int64
+	if header := ctx.GetHeader(lastEventIDHeader); header != "" {
+		lastEventID, err = strconv.ParseInt(header, 10, 64)
+		if err != nil || lastEventID < 0 {
+			NewErrorResponse(ctx, http.StatusBadRequest, "invalid last event id")
+			return
+		}
+	}
+
+	replay, events, cancel := h.stream.Subscribe(lastEventID)
+	defer cancel()
+	heartbeat := time.NewTicker(h.streamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	for _, event := range replay {
+		renderStreamEvent(ctx, event, genreID)
+	}
+	ctx.Writer.Flush()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			renderStreamEvent(ctx, event, genreID)
+			return true
+		case <-heartbeat.C:
+			// Comment lines are ignored by clients but keep proxies from closing the connection.
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
+
+// renderStreamEvent skips books of other genres, deleted books carry no genre and are always sent.
+func renderStreamEvent(ctx *gin.Context, event models.StreamEvent, genreID int) {
+	if genreID != 0 && event.Book != nil && event.Book.Genre != genreID {
+		return
+	}
+	ctx.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.ID, 10),
+		Event: event.Type,
+		Data:  event.Event,
+	})
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const (
+	presenterCtx    = "presenter"
+	availabilityCtx = "availability"
+)
+
+// BookPresenter converts books to the representation of an API version.
+// A new version registers the same handlers with its own presenter, so the service layer is shared.
+type BookPresenter interface {
+	Book(book models.Book) interface{}
+	Books(books []models.Book) interface{}
+}
+
+type v1Presenter struct{}
+
+func (v1Presenter) Book(book models.Book) interface{} {
+	book.Available = book.InStock()
+	setCoverURLs(&book)
+	return book
+}
+
+func (v1Presenter) Books(books []models.Book) interface{} {
+	for i := range books {
+		books[i].Available = books[i].InStock()
+		setCoverURLs(&books[i])
+	}
+	return books
+}
+
+func withPresenter(p BookPresenter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(presenterCtx, p)
+	}
+}
+
+func presenter(ctx *gin.Context) BookPresenter {
+	if p, ok := ctx.Get(presenterCtx); ok {
+		return p.(BookPresenter)
+	}
+	return v1Presenter{}
+}
+
+// withAvailability sets the availability filter of book lists in the route group when they have none.
+func (h *Handler) withAvailability(group string) gin.HandlerFunc {
+	availability := h.availability[group]
+	if availability == "" {
+		availability = models.AvailabilityInStock
+	}
+	return func(ctx *gin.Context) {
+		ctx.Set(availabilityCtx, availability)
+	}
+}
+
+func defaultAvailability(ctx *gin.Context) string {
+	if availability, ok := ctx.Get(availabilityCtx); ok {
+		return availability.(string)
+	}
+	return models.AvailabilityInStock
+}
+
+// deprecated marks routes mounted before versioning, they point clients to /api/v1.
+func (h *Handler) deprecated(ctx *gin.Context) {
+	ctx.Header("Deprecation", "true")
+	if !h.legacySunset.IsZero() {
+		ctx.Header("Sunset", h.legacySunset.UTC().Format(http.TimeFormat))
+	}
+	if !strings.HasPrefix(ctx.Request.URL.Path, "/api/") {
+		ctx.Header("Link", "</api/v1"+ctx.Request.URL.Path+`>; rel="successor-version"`)
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initWaitlistRoutes(group *gin.RouterGroup) {
+	waitlist := group.Group("/books/:id/waitlist", h.rateLimit("books"))
+	{
+		waitlist.POST("", h.AddToWaitlist)
+	}
+}
+
+// AddToWaitlist subscribes an email address or a webhook URL to the book coming back in stock.
+// The secret events to a URL are signed with is in the response, it is not shown again.
+func (h *Handler) AddToWaitlist(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var entry models.WaitlistEntry
+	if err = ctx.BindJSON(&entry); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	entry.BookID = id
+	entry, err = h.waitlist.AddToWaitlist(entry)
+	if errors.Is(err, service.ErrBookNotFound) {
+		NewErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := map[string]interface{}{
+		"id": entry.ID,
+	}
+	if entry.Secret != "" {
+		response["secret"] = entry.Secret
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initWebhooksRoutes(group *gin.RouterGroup) {
+	webhooks := group.Group("/webhooks")
+	{
+		webhooks.GET("", h.GetWebhooks)
+		webhooks.GET("/:id", h.GetWebhookByID)
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.PUT("/:id", h.UpdateWebhookByID)
+		webhooks.DELETE("/:id", h.DeleteWebhookByID)
+		webhooks.GET("/:id/deliveries", h.GetWebhookDeliveries)
+	}
+}
+
+func (h *Handler) GetWebhooks(ctx *gin.Context) {
+	subscriptions, err := h.webhooks.GetSubscriptions()
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, subscriptions)
+}
+
+func (h *Handler) GetWebhookByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	subscription, err := h.webhooks.GetSubscriptionByID(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+// CreateWebhook responds with the signing secret, it is not shown again.
+func (h *Handler) CreateWebhook(ctx *gin.Context) {
+	var subscription models.WebhookSubscription
+	if err := ctx.BindJSON(&subscription); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	subscription, err := h.webhooks.CreateSubscription(subscription)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+func (h *Handler) UpdateWebhookByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var subscription models.WebhookSubscription
+	if err = ctx.BindJSON(&subscription); err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid input")
+		return
+	}
+	if err = h.webhooks.UpdateSubscription(id, subscription); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if subscription, err = h.webhooks.GetSubscriptionByID(id); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+func (h *Handler) DeleteWebhookByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err = h.webhooks.DeleteSubscription(id); err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusNoContent, StatusResponse{"ok"})
+}
+
+func (h *Handler) GetWebhookDeliveries(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusBadRequest, "invalid id")
+		return
+	}
+	deliveries, err := h.webhooks.GetDeliveries(id)
+	if err != nil {
+		NewErrorResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
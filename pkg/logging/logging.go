@@ -0,0 +1,75 @@
+// Package logging writes leveled messages through the standard logger. The level can be changed
+// while the server runs.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+var level = int32(Info)
+
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+func CurrentLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+// Enabled reports whether messages of the level are written.
+func Enabled(l Level) bool {
+	return l >= CurrentLevel()
+}
+
+func Debugf(format string, args ...interface{}) {
+	logf(Debug, format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	logf(Info, format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	logf(Warn, format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	logf(Error, format, args...)
+}
+
+func logf(l Level, format string, args ...interface{}) {
+	if !Enabled(l) {
+		return
+	}
+	_ = log.Output(3, strings.ToUpper(l.String())+" "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	writer, flags := log.Writer(), log.Flags()
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+		SetLevel(Info)
+	}()
+
+	level, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	SetLevel(level)
+	Infof("dropped %d", 1)
+	Warnf("written %d", 2)
+	Errorf("written %d", 3)
+	assert.Equal(t, "WARN written 2\nERROR written 3\n", out.String())
+
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+// Package mail sends plain text emails through SMTP. Fake stands in for a mail server
+// in tests and local runs.
+package mail
+
+import (
+	"fmt"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(msg Message) error
+}
+
+// SMTPSender delivers messages to an SMTP server, authenticating when a username is set.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(addr, username, password string) *SMTPSender {
+	s := &SMTPSender{addr: addr}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	data, err := format(msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, msg.From, msg.To, data)
+}
+
+// format renders msg as an email. Addresses with line breaks are refused, and the subject is
+// Q-encoded when needed, so no value can end its header and start another.
+func format(msg Message) ([]byte, error) {
+	for _, addr := range append([]string{msg.From}, msg.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("address %q has a line break", addr)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
+
+// Fake keeps the messages it is given and logs them instead of sending.
+type Fake struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Send(msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+	logging.Infof("mail: to %s: %s", strings.Join(msg.To, ", "), msg.Subject)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (f *Fake) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.messages...)
+}
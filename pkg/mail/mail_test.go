@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		msg           Message
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "Plain",
+			msg:  Message{From: "shop@example.com", To: []string{"a@example.com", "b@example.com"}, Subject: "Go is back", Body: "Hi\nthere"},
+			expected: "From: shop@example.com\r\nTo: a@example.com, b@example.com\r\nSubject: Go is back\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nHi\r\nthere",
+		},
+		{
+			name: "Subject with line break encoded",
+			msg:  Message{From: "shop@example.com", To: []string{"a@example.com"}, Subject: "Go\r\nBcc: all@example.com is back"},
+			expected: "From: shop@example.com\r\nTo: a@example.com\r\n" +
+				"Subject: =?utf-8?q?Go=0D=0ABcc:_all@example.com_is_back?=\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		},
+		{
+			name:          "Address with line break",
+			msg:           Message{From: "shop@example.com", To: []string{"a@example.com\r\nBcc: all@example.com"}, Subject: "Go is back"},
+			expectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := format(test.msg)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Package migrate applies the SQL migrations of the migrations directory. The version is kept in
+// the schema_migrations table of golang-migrate, so databases migrated with either tool stay in step.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// lockID is the advisory lock held while a migration is applied, so migrations of two
+// processes take turns.
+const lockID = 7283910452
+
+var (
+	ErrDirty   = errors.New("database is dirty, fix the failed migration and force its version with golang-migrate")
+	ErrNoDown  = errors.New("migration has no down file")
+	ErrUnknown = errors.New("database version has no migration")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Up      string `json:"-"`
+	Down    string `json:"-"`
+}
+
+// Read reads the migrations of dir named like 1_create_tables.up.sql and 1_create_tables.down.sql,
+// sorted by version.
+func Read(dir fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid version of %s", entry.Name())
+		}
+		content, err := fs.ReadFile(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Version is the version of the database, 0 before the first migration.
+type Version struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) Version() (Version, error) {
+	if err := m.createTable(); err != nil {
+		return Version{}, err
+	}
+	return currentVersion(m.db)
+}
+
+// Up applies up to steps migrations after the version of the database, all of them when steps is 0.
+// Every migration is applied in a transaction with the version.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	return m.apply(steps, func(version uint) (Migration, string, uint, bool) {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				return migration, migration.Up, migration.Version, true
+			}
+		}
+		return Migration{}, "", 0, false
+	})
+}
+
+// Down reverts up to steps migrations from the version of the database, all of them when steps is 0.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	return m.apply(steps, func(version uint) (Migration, string, uint, bool) {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if m.migrations[i].Version == version {
+				var previous uint
+				if i > 0 {
+					previous = m.migrations[i-1].Version
+				}
+				return m.migrations[i], m.migrations[i].Down, previous, true
+			}
+		}
+		return Migration{}, "", 0, false
+	})
+}
+
+// next finds the migration to apply at the version with its SQL and the version it leads to.
+type next func(version uint) (migration Migration, sql string, to uint, ok bool)
+
+func (m *Migrator) apply(steps int, next next) ([]Migration, error) {
+	if err := m.createTable(); err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for steps == 0 || len(applied) < steps {
+		done := true
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+				return err
+			}
+			// The version is read under the lock, another process may have migrated meanwhile.
+			version, err := currentVersion(tx)
+			if err != nil {
+				return err
+			}
+			if version.Dirty {
+				return ErrDirty
+			}
+			migration, sql, to, ok := next(version.Version)
+			if !ok {
+				if version.Version != 0 && !m.known(version.Version) {
+					return fmt.Errorf("%w: %d", ErrUnknown, version.Version)
+				}
+				return nil
+			}
+			if sql == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, migration.Version, migration.Name)
+			}
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if err := setVersion(tx, to); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+			done = false
+			return nil
+		})
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			break
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) known(version uint) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) createTable() error {
+	return m.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)").Error
+}
+
+func currentVersion(tx *gorm.DB) (Version, error) {
+	var version Version
+	err := tx.Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version).Error
+	return version, err
+}
+
+// setVersion keeps the single row of golang-migrate, there is none at version 0.
+func setVersion(tx *gorm.DB, version uint) error {
+	if err := tx.Exec("DELETE FROM schema_migrations").Error; err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	return tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, false).Error
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+var migrations = []Migration{
+	{Version: 1, Name: "create_tables", Up: "CREATE TABLE books (id SERIAL);", Down: "DROP TABLE books;"},
+	{Version: 3, Name: "create_genres", Up: "CREATE TABLE genres (id SERIAL);", Down: "DROP TABLE genres;"},
+}
+
+func TestRead(t *testing.T) {
+	dir := fstest.MapFS{
+		"3_create_genres.up.sql":   {Data: []byte("CREATE TABLE genres (id SERIAL);")},
+		"3_create_genres.down.sql": {Data: []byte("DROP TABLE genres;")},
+		"1_create_tables.up.sql":   {Data: []byte("CREATE TABLE books (id SERIAL);")},
+		"1_create_tables.down.sql": {Data: []byte("DROP TABLE books;")},
+		"README.md":                {Data: []byte("not a migration")},
+	}
+	read, err := Read(dir)
+	require.NoError(t, err)
+	assert.Equal(t, migrations, read)
+
+	_, err = Read(fstest.MapFS{"2_add_column.down.sql": {Data: []byte("ALTER TABLE books DROP COLUMN a;")}})
+	assert.Error(t, err)
+}
+
+func expectStep(mock sqlmock.Sqlmock, version uint, dirty bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version != 0 {
+		rows.AddRow(version, dirty)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations LIMIT 1`)).WillReturnRows(rows)
+}
+
+func expectSetVersion(mock sqlmock.Sqlmock, version uint) {
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if version != 0 {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`)).
+			WithArgs(version, false).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestUp(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStep(mock, 1, false)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE genres (id SERIAL);`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSetVersion(mock, 3)
+	expectStep(mock, 3, false)
+	mock.ExpectCommit()
+
+	applied, err := NewMigrator(db, migrations).Up(0)
+	assert.NoError(t, err)
+	assert.Equal(t, migrations[1:], applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpDirty(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStep(mock, 1, true)
+	mock.ExpectRollback()
+
+	_, err := NewMigrator(db, migrations).Up(0)
+	assert.ErrorIs(t, err, ErrDirty)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDown(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectStep(mock, 3, false)
+	mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE genres;`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSetVersion(mock, 1)
+	expectStep(mock, 1, false)
+	mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE books;`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSetVersion(mock, 0)
+
+	applied, err := NewMigrator(db, migrations).Down(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{migrations[1], migrations[0]}, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
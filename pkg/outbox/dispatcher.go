@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"strconv"
+	"time"
+)
+
+// maxBackoffDoublings keeps the backoff of messages that keep failing from overflowing.
+const maxBackoffDoublings = 16
+
+type Config struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batch_size"`
+	// A batch is published again by another dispatcher once its claim is older than ClaimTimeout.
+	ClaimTimeout time.Duration `mapstructure:"claim_timeout"`
+	// MaxAttempts is the number of times a message fails to publish before it is given up on.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff is the wait after the first failure of a message, it doubles with every further failure.
+	Backoff time.Duration `mapstructure:"backoff"`
+}
+
+// Dispatcher publishes pending outbox messages. Batches are claimed in a short transaction and
+// published without holding it. A message that fails to publish holds back the later messages
+// of its aggregate until it is retried after a backoff, so they are delivered in order. After
+// MaxAttempts failures the message is marked failed with its last error, and the later messages go on.
+type Dispatcher struct {
+	repo      repository.Outbox
+	publisher EventPublisher
+	cfg       Config
+	done      chan struct{}
+}
+
+func NewDispatcher(repo repository.Outbox, publisher EventPublisher, cfg Config) *Dispatcher {
+	return &Dispatcher{repo: repo, publisher: publisher, cfg: cfg, done: make(chan struct{})}
+}
+
+// Start polls the outbox until ctx is done.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			published, err := d.dispatch(ctx)
+			if err != nil {
+				logging.Errorf("outbox: %s", err)
+			}
+			// A fully published batch means more messages may be pending, they are taken at once.
+			if err == nil && published == d.cfg.BatchSize && ctx.Err() == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the dispatcher started by Start returns.
+func (d *Dispatcher) Wait() {
+	<-d.done
+}
+
+// dispatch publishes a claimed batch and returns the number of published messages.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	messages, err := d.repo.ClaimPending(d.cfg.BatchSize, time.Now().UTC(), d.cfg.ClaimTimeout)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	claimed := make([]int64, 0, len(messages))
+	for _, msg := range messages {
+		claimed = append(claimed, msg.ID)
+	}
+	published, failures := d.publish(ctx, messages)
+	return len(published), d.repo.Release(claimed, published, failures, d.cfg.MaxAttempts)
+}
+
+// retryAt returns when a message that failed attempts times before is tried again.
+func (d *Dispatcher) retryAt(attempts int) time.Time {
+	if attempts > maxBackoffDoublings {
+		attempts = maxBackoffDoublings
+	}
+	return time.Now().UTC().Add(d.cfg.Backoff << attempts)
+}
+
+// publish publishes the messages and returns the ids of the published ones and the failures.
+// Messages held back behind a failure of their aggregate are neither.
+func (d *Dispatcher) publish(ctx context.Context, messages []models.OutboxMessage) ([]int64, []models.OutboxFailure) {
+	published := make([]int64, 0, len(messages))
+	var failures []models.OutboxFailure
+	blocked := make(map[string]bool)
+	for _, msg := range messages {
+		aggregate := msg.AggregateType + ":" + strconv.Itoa(msg.AggregateID)
+		if blocked[aggregate] || ctx.Err() != nil {
+			continue
+		}
+		if err := d.publisher.Publish(ctx, msg); err != nil {
+			if msg.Attempts+1 >= d.cfg.MaxAttempts {
+				logging.Errorf("outbox: message %d failed %d times, giving up: %s", msg.ID, msg.Attempts+1, err)
+			} else {
+				logging.Errorf("outbox: message %d: %s", msg.ID, err)
+				blocked[aggregate] = true
+			}
+			failures = append(failures, models.OutboxFailure{ID: msg.ID, Error: err.Error(), RetryAt: d.retryAt(msg.Attempts)})
+			continue
+		}
+		published = append(published, msg.ID)
+	}
+	return published, failures
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type publisherFunc func(msg models.OutboxMessage) error
+
+func (f publisherFunc) Publish(_ context.Context, msg models.OutboxMessage) error {
+	return f(msg)
+}
+
+// outboxRepo keeps messages in memory.
+type outboxRepo struct {
+	mu       sync.Mutex
+	messages []models.OutboxMessage
+}
+
+func (r *outboxRepo) ClaimPending(limit int, now time.Time, lease time.Duration) ([]models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []models.OutboxMessage
+	for _, msg := range r.messages {
+		if msg.PublishedAt == nil && msg.ClaimedUntil != nil && msg.ClaimedUntil.After(now) {
+			return nil, nil
+		}
+	}
+	until := now.Add(lease)
+	waiting := make(map[int]bool)
+	for i, msg := range r.messages {
+		if msg.PublishedAt != nil || msg.FailedAt != nil {
+			continue
+		}
+		if waiting[msg.AggregateID] || msg.RetryAt != nil && msg.RetryAt.After(now) {
+			waiting[msg.AggregateID] = true
+			continue
+		}
+		if len(pending) < limit {
+			r.messages[i].ClaimedUntil = &until
+			pending = append(pending, msg)
+		}
+	}
+	return pending, nil
+}
+
+func (r *outboxRepo) Release(claimed, published []int64, failures []models.OutboxFailure, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i := range r.messages {
+		for _, failure := range failures {
+			if msg := &r.messages[i]; msg.ID == failure.ID {
+				msg.Attempts++
+				msg.LastError = &failure.Error
+				msg.RetryAt = &failure.RetryAt
+				if msg.Attempts >= maxAttempts {
+					msg.FailedAt = &now
+				}
+			}
+		}
+		for _, id := range published {
+			if r.messages[i].ID == id {
+				r.messages[i].PublishedAt = &now
+			}
+		}
+		for _, id := range claimed {
+			if r.messages[i].ID == id {
+				r.messages[i].ClaimedUntil = nil
+			}
+		}
+	}
+	return nil
+}
+
+func TestDispatcherKeepsAggregateOrder(t *testing.T) {
+	repo := &outboxRepo{messages: []models.OutboxMessage{
+		{ID: 1, AggregateType: models.AggregateBook, AggregateID: 1, EventType: models.EventBookCreated},
+		{ID: 2, AggregateType: models.AggregateBook, AggregateID: 2, EventType: models.EventBookCreated},
+		{ID: 3, AggregateType: models.AggregateBook, AggregateID: 1, EventType: models.EventBookUpdated},
+		{ID: 4, AggregateType: models.AggregateBook, AggregateID: 2, EventType: models.EventBookUpdated},
+	}}
+
+	var mu sync.Mutex
+	var published []int64
+	failing := true
+	publisher := publisherFunc(func(msg models.OutboxMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.ID == 1 && failing {
+			failing = false
+			return errors.New("broker is unavailable")
+		}
+		published = append(published, msg.ID)
+		return nil
+	})
+
+	d := NewDispatcher(repo, publisher, Config{Interval: 5 * time.Millisecond, BatchSize: 10, ClaimTimeout: time.Minute, MaxAttempts: 3})
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Start(ctx)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(published) == 4
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	d.Wait()
+
+	assert.Equal(t, []int64{2, 4, 1, 3}, published)
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	repo := &outboxRepo{messages: []models.OutboxMessage{
+		{ID: 1, AggregateType: models.AggregateBook, AggregateID: 1, EventType: models.EventBookCreated},
+		{ID: 2, AggregateType: models.AggregateBook, AggregateID: 1, EventType: models.EventBookUpdated},
+	}}
+
+	var mu sync.Mutex
+	var published []int64
+	publisher := publisherFunc(func(msg models.OutboxMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.ID == 1 {
+			return errors.New("payload is rejected")
+		}
+		published = append(published, msg.ID)
+		return nil
+	})
+
+	d := NewDispatcher(repo, publisher, Config{
+		Interval: 5 * time.Millisecond, BatchSize: 10, ClaimTimeout: time.Minute, MaxAttempts: 3, Backoff: time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Start(ctx)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(published) == 1
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	d.Wait()
+
+	// The later event of the book is published once the failing one is given up on.
+	assert.Equal(t, []int64{2}, published)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	failed := repo.messages[0]
+	assert.Equal(t, 3, failed.Attempts)
+	assert.NotNil(t, failed.FailedAt)
+	assert.Equal(t, "payload is rejected", *failed.LastError)
+}
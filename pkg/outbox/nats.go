@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSConn is the part of a NATS connection the publisher needs, *nats.Conn satisfies it.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+	Flush() error
+}
+
+// NATSPublisher publishes messages to "<prefix>.<event type>" and flushes every message,
+// so that an error is returned when the server did not receive it.
+type NATSPublisher struct {
+	conn   NATSConn
+	prefix string
+}
+
+func NewNATSPublisher(conn NATSConn, prefix string) *NATSPublisher {
+	return &NATSPublisher{conn: conn, prefix: prefix}
+}
+
+func (p *NATSPublisher) Publish(_ context.Context, msg models.OutboxMessage) error {
+	if err := p.conn.Publish(p.prefix+"."+msg.EventType, msg.Payload); err != nil {
+		return err
+	}
+	return p.conn.Flush()
+}
+
+// NATSClient is a minimal client of the NATS text protocol supporting publishing only.
+type NATSClient struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+func DialNATS(addr string, timeout time.Duration) (*NATSClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &NATSClient{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+	if err = c.handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *NATSClient) handshake() error {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	_, err = c.conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"restapi\"}\r\n"))
+	return err
+}
+
+func (c *NATSClient) Publish(subject string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := fmt.Fprintf(c.conn, "PUB %s %d\r\n%s\r\n", subject, len(data), data)
+	return err
+}
+
+// Flush waits for the server to answer a PING, it processes everything sent before.
+func (c *NATSClient) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "PONG"):
+			return nil
+		case strings.HasPrefix(line, "PING"):
+			if _, err = c.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (c *NATSClient) Close() error {
+	return c.conn.Close()
+}
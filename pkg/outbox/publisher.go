@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"net/http"
+	"strconv"
+)
+
+// EventPublisher sends an outbox message to a broker. A message may be published more than once,
+// consumers deduplicate by message id.
+type EventPublisher interface {
+	Publish(ctx context.Context, msg models.OutboxMessage) error
+}
+
+// LogPublisher writes messages to the standard logger.
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(_ context.Context, msg models.OutboxMessage) error {
+	logging.Infof("outbox: %s %s %d: %s", msg.EventType, msg.AggregateType, msg.AggregateID, msg.Payload)
+	return nil
+}
+
+// HTTPPublisher posts messages to a single endpoint, any 2xx status is an acknowledgement.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPublisher(url string, client *http.Client) *HTTPPublisher {
+	return &HTTPPublisher{url: url, client: client}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, msg models.OutboxMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(msg.ID, 10))
+	req.Header.Set("X-Event-Type", msg.EventType)
+	req.Header.Set("X-Aggregate-ID", msg.AggregateType+":"+strconv.Itoa(msg.AggregateID))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added every second up to Burst.
+type Limit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// Result is the state of a bucket after a single take.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets. MemoryStore serves a single replica, RedisStore is shared between replicas.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type Limiter struct {
+	store  Store
+	limits atomic.Value // map[string]Limit
+}
+
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	l := &Limiter{store: store}
+	l.SetLimits(limits)
+	return l
+}
+
+// SetLimits replaces the limits of all groups. Buckets keep their tokens, so a client
+// that was limited stays limited until it gets tokens under the new limit.
+func (l *Limiter) SetLimits(limits map[string]Limit) {
+	copied := make(map[string]Limit, len(limits))
+	for group, limit := range limits {
+		copied[group] = limit
+	}
+	l.limits.Store(copied)
+}
+
+// Take consumes a token of the group's bucket for the client key.
+// Groups without a configured limit are not limited.
+func (l *Limiter) Take(ctx context.Context, group, key string) (Result, bool, error) {
+	limit, ok := l.limits.Load().(map[string]Limit)[group]
+	if !ok || limit.Rate <= 0 || limit.Burst <= 0 {
+		return Result{}, false, nil
+	}
+	res, err := l.store.Take(ctx, group+":"+key, limit)
+	return res, true, err
+}
+
+// bucketState computes the outcome of taking a token from a bucket holding tokens.
+func bucketState(tokens float64, limit Limit) (Result, float64) {
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+	return res, tokens
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limiter := NewLimiter(store, map[string]Limit{"books": {Rate: 1, Burst: 2}})
+
+	tests := []struct {
+		name              string
+		advance           time.Duration
+		key               string
+		expectedAllowed   bool
+		expectedRemaining int
+		expectedRetry     time.Duration
+	}{
+		{name: "First request", key: "ip:1", expectedAllowed: true, expectedRemaining: 1},
+		{name: "Burst used", key: "ip:1", expectedAllowed: true, expectedRemaining: 0},
+		{name: "Limited", key: "ip:1", expectedAllowed: false, expectedRemaining: 0, expectedRetry: time.Second},
+		{name: "Other client", key: "ip:2", expectedAllowed: true, expectedRemaining: 1},
+		{name: "Refilled", advance: time.Second, key: "ip:1", expectedAllowed: true, expectedRemaining: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now = now.Add(test.advance)
+			res, limited, err := limiter.Take(context.Background(), "books", test.key)
+			assert.NoError(t, err)
+			assert.True(t, limited)
+			assert.Equal(t, test.expectedAllowed, res.Allowed)
+			assert.Equal(t, test.expectedRemaining, res.Remaining)
+			assert.Equal(t, test.expectedRetry, res.RetryAfter)
+			assert.Equal(t, 2, res.Limit)
+		})
+	}
+}
+
+func TestLimiterUnknownGroup(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{"books": {Rate: 1, Burst: 1}})
+	_, limited, err := limiter.Take(context.Background(), "genres", "ip:1")
+	assert.NoError(t, err)
+	assert.False(t, limited)
+}
+
+func TestLimiterSetLimits(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{"books": {Rate: 1, Burst: 1}})
+	limiter.SetLimits(map[string]Limit{"genres": {Rate: 1, Burst: 5}})
+
+	_, limited, err := limiter.Take(context.Background(), "books", "ip:1")
+	assert.NoError(t, err)
+	assert.False(t, limited)
+	res, limited, err := limiter.Take(context.Background(), "genres", "ip:1")
+	assert.NoError(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 5, res.Limit)
+}
+
+func TestMemoryStoreEvict(t *testing.T) {
+	now := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limiter := NewLimiter(store, map[string]Limit{"books": {Rate: 1, Burst: 2}, "covers": {Rate: 0.01, Burst: 2}})
+
+	for _, group := range []string{"books", "covers"} {
+		_, _, err := limiter.Take(context.Background(), group, "ip:1")
+		assert.NoError(t, err)
+	}
+	assert.Len(t, store.buckets, 2)
+
+	// The books bucket refilled after a second, the covers one needs 100 seconds.
+	now = now.Add(sweepInterval)
+	_, _, err := limiter.Take(context.Background(), "books", "ip:2")
+	assert.NoError(t, err)
+	assert.Contains(t, store.buckets, "covers:ip:1")
+	assert.Contains(t, store.buckets, "books:ip:2")
+	assert.NotContains(t, store.buckets, "books:ip:1")
+
+	now = now.Add(sweepInterval)
+	_, _, err = limiter.Take(context.Background(), "books", "ip:2")
+	assert.NoError(t, err)
+	assert.NotContains(t, store.buckets, "covers:ip:1")
+}
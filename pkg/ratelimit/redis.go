@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisClient is the subset of a Redis client the store needs.
+// Any Redis-protocol client can be adapted to it with a few lines.
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// takeScript refills the bucket, takes a token if there is one and
+// returns the amount of tokens that were available before the take.
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local available = tokens
+if tokens >= 1 then
+	tokens = tokens - 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("EXPIRE", KEYS[1], math.ceil(burst / rate) + 1)
+return tostring(available)
+`
+
+// RedisStore keeps buckets in Redis so that all replicas share the limits.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := float64(s.now().UnixNano()) / float64(time.Second)
+	reply, err := s.client.Eval(ctx, takeScript, []string{s.prefix + key},
+		limit.Rate, limit.Burst, strconv.FormatFloat(now, 'f', 6, 64))
+	if err != nil {
+		return Result{}, err
+	}
+	str, ok := reply.(string)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	available, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	res, _ := bucketState(available, limit)
+	return res, nil
+}
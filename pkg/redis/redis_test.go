@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	got := encode([]interface{}{"SET", []byte("key"), 42, ""})
+	assert.Equal(t, "*4\r\n$3\r\nSET\r\n$3\r\nkey\r\n$2\r\n42\r\n$0\r\n\r\n", string(got))
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      interface{}
+		expectedError error
+	}{
+		{name: "Simple string", input: "+OK\r\n", expected: "OK"},
+		{name: "Error", input: "-ERR unknown command\r\n", expectedError: Error("ERR unknown command")},
+		{name: "Integer", input: ":-7\r\n", expected: int64(-7)},
+		{name: "Bulk string", input: "$5\r\na\r\nbc\r\n", expected: "a\r\nbc"},
+		{name: "Empty bulk string", input: "$0\r\n\r\n", expected: ""},
+		{name: "Nil bulk string", input: "$-1\r\n", expectedError: ErrNil},
+		{name: "Array", input: "*3\r\n:1\r\n$1\r\nx\r\n$-1\r\n", expected: []interface{}{int64(1), "x", nil}},
+		{name: "Nested array", input: "*1\r\n*1\r\n+OK\r\n", expected: []interface{}{[]interface{}{"OK"}}},
+		{name: "Nil array", input: "*-1\r\n", expectedError: ErrNil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reply, err := read(bufio.NewReader(strings.NewReader(test.input)))
+			if test.expectedError != nil {
+				assert.Equal(t, test.expectedError, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, reply)
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	for _, input := range []string{"\r\n", "?1\r\n", ":x\r\n", "$3\r\nab", "$x\r\n", "*2\r\n:1\r\n"} {
+		_, err := read(bufio.NewReader(strings.NewReader(input)))
+		assert.Errorf(t, err, "input %q", input)
+	}
+}
+
+// serve answers the commands of every connection with the replies returned by reply.
+func serve(t *testing.T, reply func(cmd []interface{}) string) (string, func() int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	accepted := make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					cmd, err := read(r)
+					if err != nil {
+						return
+					}
+					if _, err = conn.Write([]byte(reply(cmd.([]interface{})))); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), func() int { return len(accepted) }
+}
+
+func TestClientDo(t *testing.T) {
+	addr, connections := serve(t, func(cmd []interface{}) string {
+		switch cmd[0] {
+		case "GET":
+			return "$-1\r\n"
+		case "INCR":
+			return ":1\r\n"
+		case "EVAL":
+			script := cmd[1].(string)
+			return "*2\r\n$" + strconv.Itoa(len(script)) + "\r\n" + script + "\r\n:" + cmd[2].(string) + "\r\n"
+		default:
+			return "-ERR unknown command\r\n"
+		}
+	})
+	client := NewClient(addr, 1, time.Second)
+	defer client.Close()
+	ctx := context.Background()
+
+	reply, err := client.Do(ctx, "INCR", "hits")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), reply)
+
+	_, err = client.Do(ctx, "GET", "missing")
+	assert.True(t, errors.Is(err, ErrNil))
+
+	_, err = client.Do(ctx, "PING")
+	assert.Equal(t, Error("ERR unknown command"), err)
+
+	reply, err = client.Eval(ctx, "return", []string{"a"}, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"return", int64(1)}, reply)
+
+	// Error and nil replies leave the connection usable, so it is reused.
+	assert.Equal(t, 1, connections())
+}
+
+func TestClientDoBrokenConnection(t *testing.T) {
+	calls := 0
+	addr, connections := serve(t, func(cmd []interface{}) string {
+		calls++
+		if calls == 1 {
+			return "?\r\n"
+		}
+		return "+PONG\r\n"
+	})
+	client := NewClient(addr, 1, time.Second)
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "PING")
+	assert.Error(t, err)
+	// The connection with a malformed reply is dropped and a new one is dialed.
+	reply, err := client.Do(context.Background(), "PING")
+	assert.NoError(t, err)
+	assert.Equal(t, "PONG", reply)
+	assert.Equal(t, 2, connections())
+}
+
+func TestClientTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		// Accepts without ever replying.
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+	client := NewClient(listener.Addr().String(), 1, 50*time.Millisecond)
+	defer client.Close()
+
+	start := time.Now()
+	_, err = client.Do(context.Background(), "PING")
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrCartNotOpen       = errors.New("cart is checked out")
+	ErrCartEmpty         = errors.New("cart is empty")
+	ErrCartFull          = errors.New("cart holds too many books")
+)
+
+type Carts interface {
+	CreateCart(tokenHash string) (int, error)
+	GetCartByID(id int) (models.Cart, error)
+	DeleteCart(id int) error
+	SetItem(item models.CartItem, at time.Time, maxQuantity int) error
+	DeleteItem(cartID, bookID int) error
+	ReleaseExpiredHolds(at time.Time) ([]int, error)
+	NextHoldExpiry() (time.Time, error)
+	Checkout(id int, at time.Time) ([]models.StockChange, error)
+}
+
+type CartsPostgres struct {
+	db *gorm.DB
+}
+
+func NewCartsPostgres(db *gorm.DB) *CartsPostgres {
+	return &CartsPostgres{db: db}
+}
+
+func (r *CartsPostgres) CreateCart(tokenHash string) (int, error) {
+	cart := models.Cart{Status: models.CartOpen, TokenHash: tokenHash}
+	err := r.db.Select("status", "token_hash").Create(&cart).Error
+	return cart.ID, err
+}
+
+func (r *CartsPostgres) GetCartByID(id int) (models.Cart, error) {
+	var cart models.Cart
+	err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("book_id")
+	}).First(&cart, id).Error
+	if cart.Items == nil {
+		cart.Items = []models.CartItem{}
+	}
+	return cart, err
+}
+
+func (r *CartsPostgres) DeleteCart(id int) error {
+	res := r.db.Delete(&models.Cart{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetItem holds the quantity of the book in the cart until the expiry of the item, replacing
+// the hold the cart already has. The book must have enough stock beyond the holds of other carts,
+// and the cart may hold no more than maxQuantity books.
+func (r *CartsPostgres) SetItem(item models.CartItem, at time.Time, maxQuantity int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenCart(tx, item.CartID); err != nil {
+			return err
+		}
+		var inCart int
+		if err := tx.Model(&models.CartItem{}).Where("cart_id = ? AND book_id <> ?", item.CartID, item.BookID).
+			Select("COALESCE(SUM(quantity), 0)").Scan(&inCart).Error; err != nil {
+			return err
+		}
+		if inCart+item.Quantity > maxQuantity {
+			return ErrCartFull
+		}
+		var book models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("amount").First(&book, item.BookID).Error; err != nil {
+			return err
+		}
+		held, err := heldStock(tx, item.BookID, item.CartID, at)
+		if err != nil {
+			return err
+		}
+		if book.Amount-held < item.Quantity {
+			return ErrInsufficientStock
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cart_id"}, {Name: "book_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity", "expires_at"}),
+		}).Create(&item).Error
+	})
+}
+
+func (r *CartsPostgres) DeleteItem(cartID, bookID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenCart(tx, cartID); err != nil {
+			return err
+		}
+		res := tx.Where("cart_id = ? AND book_id = ?", cartID, bookID).Delete(&models.CartItem{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected < 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// ReleaseExpiredHolds removes the items of open carts that expired at the time and returns
+// the ids of their books.
+func (r *CartsPostgres) ReleaseExpiredHolds(at time.Time) ([]int, error) {
+	var items []models.CartItem
+	err := r.db.Clauses(clause.Returning{Columns: []clause.Column{{Name: "book_id"}}}).
+		Where("expires_at <= ? AND cart_id IN (?)", at,
+			r.db.Model(&models.Cart{}).Select("id").Where("status = ?", models.CartOpen)).
+		Delete(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(items))
+	var bookIDs []int
+	for _, item := range items {
+		if !seen[item.BookID] {
+			seen[item.BookID] = true
+			bookIDs = append(bookIDs, item.BookID)
+		}
+	}
+	return bookIDs, nil
+}
+
+// NextHoldExpiry returns when the first hold of an open cart expires, the zero time without holds.
+func (r *CartsPostgres) NextHoldExpiry() (time.Time, error) {
+	var next sql.NullTime
+	err := r.db.Model(&models.CartItem{}).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("carts.status = ?", models.CartOpen).
+		Select("MIN(cart_items.expires_at)").Scan(&next).Error
+	return next.Time, err
+}
+
+// Checkout takes the books of the cart out of stock and closes the cart in one transaction.
+// Items whose hold expired are taken as long as the stock not held by other carts allows.
+func (r *CartsPostgres) Checkout(id int, at time.Time) ([]models.StockChange, error) {
+	var changes []models.StockChange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenCart(tx, id); err != nil {
+			return err
+		}
+		var items []models.CartItem
+		// Books are locked in the order of their ids, so concurrent checkouts cannot deadlock.
+		if err := tx.Where("cart_id = ?", id).Order("book_id").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrCartEmpty
+		}
+		for _, item := range items {
+			var book models.Book
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, item.BookID).Error; err != nil {
+				return err
+			}
+			held, err := heldStock(tx, item.BookID, id, at)
+			if err != nil {
+				return err
+			}
+			if book.Amount-held < item.Quantity {
+				return ErrInsufficientStock
+			}
+			if err = takeStock(tx, book.ID, item.Quantity); err != nil {
+				return err
+			}
+			previous := book.Amount
+			book.Amount -= item.Quantity
+			if err = saveAmount(tx, previous, &book); err != nil {
+				return err
+			}
+			changes = append(changes, models.StockChange{PreviousAmount: previous, Book: book})
+		}
+		return tx.Model(&models.Cart{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": models.CartCheckedOut, "checked_out_at": at}).Error
+	})
+	return changes, err
+}
+
+func lockOpenCart(tx *gorm.DB, id int) error {
+	var cart models.Cart
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("status").First(&cart, id).Error; err != nil {
+		return err
+	}
+	if cart.Status != models.CartOpen {
+		return ErrCartNotOpen
+	}
+	return nil
+}
+
+// heldStock returns the amount of the book held by open carts other than exceptCartID at the time.
+func heldStock(tx *gorm.DB, bookID, exceptCartID int, at time.Time) (int, error) {
+	var held int
+	err := tx.Model(&models.CartItem{}).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("cart_items.book_id = ? AND cart_items.cart_id <> ? AND carts.status = ? AND cart_items.expires_at > ?",
+			bookID, exceptCartID, models.CartOpen, at).
+		Select("COALESCE(SUM(cart_items.quantity), 0)").Scan(&held).Error
+	return held, err
+}
+
+// heldBooks is the subquery of the amount of books.id held by open carts at the time.
+func heldBooks(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Model(&models.CartItem{}).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("cart_items.book_id = books.id AND carts.status = ? AND cart_items.expires_at > ?", models.CartOpen, at).
+		Select("COALESCE(SUM(cart_items.quantity), 0)")
+}
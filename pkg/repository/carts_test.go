@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCheckout(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewCartsPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		held            int
+		mockBehavior    func(held int)
+		expectedChanges []models.StockChange
+		expectedError   error
+	}{
+		{
+			name: "Ok",
+			held: 1,
+			mockBehavior: func(held int) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR UPDATE`)).
+					WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, 5))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(cart_items.quantity), 0) FROM "cart_items" JOIN carts`)).
+					WithArgs(2, 1, models.CartOpen, at).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(held))
+				expectTakeStock(mock, 2, 5, 3)
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "amount"=$1 WHERE id = $2`)).
+					WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 2, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "carts" SET "checked_out_at"=$1,"status"=$2 WHERE id = $3`)).
+					WithArgs(at, models.CartCheckedOut, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedChanges: []models.StockChange{{PreviousAmount: 5, Book: models.Book{ID: 2, Amount: 2}}},
+		},
+		{
+			name: "Held by other carts",
+			held: 3,
+			mockBehavior: func(held int) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books"`)).
+					WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, 5))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(cart_items.quantity), 0)`)).
+					WithArgs(2, 1, models.CartOpen, at).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(held))
+				mock.ExpectRollback()
+			},
+			expectedError: ErrInsufficientStock,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "status" FROM "carts" WHERE "carts"."id" = $1 ORDER BY "carts"."id" LIMIT 1 FOR UPDATE`)).
+				WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.CartOpen))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "cart_items" WHERE cart_id = $1 ORDER BY book_id`)).
+				WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"cart_id", "book_id", "quantity", "expires_at"}).
+				AddRow(1, 2, 3, at.Add(-time.Minute)))
+			test.mockBehavior(test.held)
+
+			changes, err := repo.Checkout(1, at)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedChanges, changes)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReleaseExpiredHolds(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewCartsPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM "cart_items" WHERE expires_at <= $1 AND cart_id IN (SELECT "id" FROM "carts" WHERE status = $2) RETURNING "book_id"`)).
+		WithArgs(at, models.CartOpen).WillReturnRows(sqlmock.NewRows([]string{"book_id"}).AddRow(2).AddRow(3).AddRow(2))
+	mock.ExpectCommit()
+
+	bookIDs, err := repo.ReleaseExpiredHolds(at)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, bookIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
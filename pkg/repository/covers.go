@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Covers interface {
+	GetCover(bookID int) (string, error)
+	SetCover(bookID int, cover string) (string, error)
+}
+
+type CoversPostgres struct {
+	db *gorm.DB
+}
+
+func NewCoversPostgres(db *gorm.DB) *CoversPostgres {
+	return &CoversPostgres{db: db}
+}
+
+// GetCover returns the file name of the cover of the book, ErrBookNotFound is returned for a missing book.
+func (r *CoversPostgres) GetCover(bookID int) (string, error) {
+	var book models.Book
+	err := r.db.Select("cover").First(&book, bookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrBookNotFound
+	}
+	return book.Cover, err
+}
+
+// SetCover stores the file name of the cover of the book and returns the previous one.
+func (r *CoversPostgres) SetCover(bookID int, cover string) (string, error) {
+	var previous models.Book
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("cover").First(&previous, bookID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrBookNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Book{}).Where("id = ?", bookID).Update("cover", cover).Error
+	})
+	return previous.Cover, err
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+)
+
+type Genres interface {
+	GetGenres() ([]models.Genre, error)
+	GetGenreByID(id int) (models.Genre, error)
+	GetGenresByIDs(ids []int) ([]models.Genre, error)
+	CreateGenre(genre models.Genre) (int, error)
+}
+
+type GenresPostgres struct {
+	db *gorm.DB
+}
+
+func NewGenresPostgres(db *gorm.DB) *GenresPostgres {
+	return &GenresPostgres{db: db}
+}
+
+func (r *GenresPostgres) GetGenres() ([]models.Genre, error) {
+	var genres []models.Genre
+	err := r.db.Order("id").Find(&genres).Error
+	return genres, err
+}
+
+func (r *GenresPostgres) GetGenreByID(id int) (models.Genre, error) {
+	var genre models.Genre
+	err := r.db.First(&genre, id).Error
+	return genre, err
+}
+
+func (r *GenresPostgres) GetGenresByIDs(ids []int) ([]models.Genre, error) {
+	var genres []models.Genre
+	err := r.db.Where("id IN ?", ids).Find(&genres).Error
+	return genres, err
+}
+
+func (r *GenresPostgres) CreateGenre(genre models.Genre) (int, error) {
+	err := r.db.Select("name").Create(&genre).Error
+	return genre.ID, err
+}
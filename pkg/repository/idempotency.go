@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type IdempotencyKeys interface {
+	GetRecord(key string) (models.IdempotencyRecord, error)
+	CreateRecord(record models.IdempotencyRecord) (bool, error)
+	SaveResponse(key string, statusCode int, header models.ResponseHeader, body []byte) error
+	DeleteRecord(key string) error
+	DeleteExpired(now time.Time) error
+}
+
+type IdempotencyKeysPostgres struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeysPostgres(db *gorm.DB) *IdempotencyKeysPostgres {
+	return &IdempotencyKeysPostgres{db: db}
+}
+
+func (r *IdempotencyKeysPostgres) GetRecord(key string) (models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.db.Where("key = ?", key).First(&record).Error
+	return record, err
+}
+
+// CreateRecord inserts the record unless the key is already taken and reports whether it was inserted.
+func (r *IdempotencyKeysPostgres) CreateRecord(record models.IdempotencyRecord) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *IdempotencyKeysPostgres) SaveResponse(key string, statusCode int, header models.ResponseHeader, body []byte) error {
+	return r.db.Model(&models.IdempotencyRecord{}).Where("key = ?", key).
+		Updates(map[string]interface{}{"status_code": statusCode, "header": header, "body": body}).Error
+}
+
+func (r *IdempotencyKeysPostgres) DeleteRecord(key string) error {
+	return r.db.Where("key = ?", key).Delete(&models.IdempotencyRecord{}).Error
+}
+
+func (r *IdempotencyKeysPostgres) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&models.IdempotencyRecord{}).Error
+}
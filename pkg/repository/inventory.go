@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+)
+
+type Inventory interface {
+	GetLowStockBooks() ([]models.Book, error)
+}
+
+type InventoryPostgres struct {
+	db *gorm.DB
+}
+
+func NewInventoryPostgres(db *gorm.DB) *InventoryPostgres {
+	return &InventoryPostgres{db: db}
+}
+
+// GetLowStockBooks returns the books at or below their reorder threshold, out of stock ones included,
+// the emptiest first.
+func (r *InventoryPostgres) GetLowStockBooks() ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("amount <= reorder_threshold").Order("amount").Order("id").Find(&books).Error
+	return books, err
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Locations interface {
+	GetLocations() ([]models.Location, error)
+	GetLocationByID(id int) (models.Location, error)
+	CreateLocation(location models.Location) (int, error)
+	UpdateLocation(id int, location models.Location) error
+	GetBookStock(bookID int) ([]models.StockLevel, error)
+	GetLocationStock(locationID int) ([]models.StockLevel, error)
+	Transfer(transfer models.StockTransfer) (models.StockTransfer, error)
+}
+
+type LocationsPostgres struct {
+	db *gorm.DB
+}
+
+func NewLocationsPostgres(db *gorm.DB) *LocationsPostgres {
+	return &LocationsPostgres{db: db}
+}
+
+func (r *LocationsPostgres) GetLocations() ([]models.Location, error) {
+	var locations []models.Location
+	err := r.db.Order("id").Find(&locations).Error
+	return locations, err
+}
+
+func (r *LocationsPostgres) GetLocationByID(id int) (models.Location, error) {
+	var location models.Location
+	err := r.db.First(&location, id).Error
+	return location, err
+}
+
+// CreateLocation adds a location that is not the default one.
+func (r *LocationsPostgres) CreateLocation(location models.Location) (int, error) {
+	err := r.db.Select("name").Create(&location).Error
+	return location.ID, err
+}
+
+func (r *LocationsPostgres) UpdateLocation(id int, location models.Location) error {
+	res := r.db.Model(&models.Location{}).Where("id = ?", id).Update("name", location.Name)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *LocationsPostgres) GetBookStock(bookID int) ([]models.StockLevel, error) {
+	var levels []models.StockLevel
+	err := r.db.Where("book_id = ?", bookID).Order("location_id").Find(&levels).Error
+	return levels, err
+}
+
+func (r *LocationsPostgres) GetLocationStock(locationID int) ([]models.StockLevel, error) {
+	var levels []models.StockLevel
+	err := r.db.Where("location_id = ?", locationID).Order("book_id").Find(&levels).Error
+	return levels, err
+}
+
+// Transfer moves the stock and records the transfer. The total amount of the book stays the same.
+func (r *LocationsPostgres) Transfer(transfer models.StockTransfer) (models.StockTransfer, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Stock levels are only changed with their book locked, so changes of a book take turns.
+		var book models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&book, transfer.BookID).Error; err != nil {
+			return err
+		}
+		if err := adjustStock(tx, transfer.BookID, transfer.FromLocationID, -transfer.Quantity); err != nil {
+			return err
+		}
+		if err := adjustStock(tx, transfer.BookID, transfer.ToLocationID, transfer.Quantity); err != nil {
+			return err
+		}
+		return tx.Create(&transfer).Error
+	})
+	return transfer, err
+}
+
+// adjustStock adds delta to the stock of the book at the location. The caller keeps the amount of the book,
+// which is the total of its stock levels, in step.
+func adjustStock(tx *gorm.DB, bookID, locationID, delta int) error {
+	amount, err := stockAt(tx, bookID, locationID)
+	if err != nil {
+		return err
+	}
+	if amount+delta < 0 {
+		return ErrInsufficientStock
+	}
+	return setStock(tx, bookID, locationID, amount+delta)
+}
+
+// stockAt locks the stock of the book at the location and returns it, 0 when the location holds none.
+func stockAt(tx *gorm.DB, bookID, locationID int) (int, error) {
+	var level models.StockLevel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND location_id = ?", bookID, locationID).Take(&level).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return level.Amount, err
+}
+
+func setStock(tx *gorm.DB, bookID, locationID, amount int) error {
+	level := models.StockLevel{BookID: bookID, LocationID: locationID, Amount: amount}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}, {Name: "location_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount"}),
+	}).Create(&level).Error
+}
+
+// addStock adds stock of the book to the default location.
+func addStock(tx *gorm.DB, bookID, quantity int) error {
+	locationID, err := defaultLocation(tx)
+	if err != nil {
+		return err
+	}
+	return adjustStock(tx, bookID, locationID, quantity)
+}
+
+func defaultLocation(tx *gorm.DB) (int, error) {
+	var location models.Location
+	err := tx.Select("id").Where("is_default").Take(&location).Error
+	return location.ID, err
+}
+
+// takeStock takes stock of the book from the default location first, then from the others in the order of their ids.
+func takeStock(tx *gorm.DB, bookID, quantity int) error {
+	var levels []models.StockLevel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "stock_levels"}}).
+		Select("stock_levels.*").
+		Joins("JOIN locations ON locations.id = stock_levels.location_id").
+		Where("stock_levels.book_id = ? AND stock_levels.amount > 0", bookID).
+		Order("locations.is_default DESC").Order("stock_levels.location_id").
+		Find(&levels).Error
+	if err != nil {
+		return err
+	}
+	for _, level := range levels {
+		if quantity == 0 {
+			break
+		}
+		taken := level.Amount
+		if taken > quantity {
+			taken = quantity
+		}
+		err = tx.Model(&models.StockLevel{}).Where("book_id = ? AND location_id = ?", bookID, level.LocationID).
+			Update("amount", level.Amount-taken).Error
+		if err != nil {
+			return err
+		}
+		quantity -= taken
+	}
+	if quantity > 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
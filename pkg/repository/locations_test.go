@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"regexp"
+	"testing"
+)
+
+// expectAddStock expects quantity to be added to the stock of the book at the default location 1, which holds amount.
+func expectAddStock(mock sqlmock.Sqlmock, bookID, amount, quantity int) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "locations" WHERE is_default LIMIT 1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAdjustStock(mock, bookID, 1, amount, amount+quantity)
+}
+
+// expectAdjustStock expects the stock of the book at the location to be set from amount to next.
+func expectAdjustStock(mock sqlmock.Sqlmock, bookID, locationID, amount, next int) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stock_levels" WHERE book_id = $1 AND location_id = $2 LIMIT 1 FOR UPDATE`)).
+		WithArgs(bookID, locationID).
+		WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}).AddRow(bookID, locationID, amount))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "stock_levels" ("book_id","location_id","amount") VALUES ($1,$2,$3) ON CONFLICT ("book_id","location_id") DO UPDATE SET "amount"="excluded"."amount"`)).
+		WithArgs(bookID, locationID, next).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectTakeStock expects quantity to be taken from the stock of the book at the default location 1, which holds amount.
+func expectTakeStock(mock sqlmock.Sqlmock, bookID, amount, quantity int) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT stock_levels.* FROM "stock_levels" JOIN locations ON locations.id = stock_levels.location_id ` +
+		`WHERE stock_levels.book_id = $1 AND stock_levels.amount > 0 ORDER BY locations.is_default DESC,stock_levels.location_id FOR UPDATE OF "stock_levels"`)).
+		WithArgs(bookID).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}).AddRow(bookID, 1, amount))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "stock_levels" SET "amount"=$1 WHERE book_id = $2 AND location_id = $3`)).
+		WithArgs(amount-quantity, bookID, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestTakeStock(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	take := func(quantity int) error {
+		return books.db.Transaction(func(tx *gorm.DB) error {
+			return takeStock(tx, 2, quantity)
+		})
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT stock_levels.* FROM "stock_levels"`)).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}).
+		AddRow(2, 1, 2).
+		AddRow(2, 3, 4).
+		AddRow(2, 4, 4))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "stock_levels" SET "amount"=$1 WHERE book_id = $2 AND location_id = $3`)).
+		WithArgs(0, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "stock_levels" SET "amount"=$1 WHERE book_id = $2 AND location_id = $3`)).
+		WithArgs(1, 2, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, take(5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT stock_levels.* FROM "stock_levels"`)).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}))
+	mock.ExpectRollback()
+	assert.ErrorIs(t, take(1), ErrInsufficientStock)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransfer(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewLocationsPostgres(books.db)
+	transfer := models.StockTransfer{BookID: 2, FromLocationID: 1, ToLocationID: 3, Quantity: 2}
+	tests := []struct {
+		name          string
+		fromAmount    int
+		mockBehavior  func(fromAmount int)
+		expectedError error
+	}{
+		{
+			name:       "Ok",
+			fromAmount: 5,
+			mockBehavior: func(fromAmount int) {
+				expectAdjustStock(mock, 2, 1, fromAmount, fromAmount-2)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stock_levels" WHERE book_id = $1 AND location_id = $2 LIMIT 1 FOR UPDATE`)).
+					WithArgs(2, 3).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "stock_levels"`)).
+					WithArgs(2, 3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "stock_transfers" ("book_id","from_location_id","to_location_id","quantity","created_at")`)).
+					WithArgs(2, 1, 3, 2, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "Insufficient stock",
+			fromAmount: 1,
+			mockBehavior: func(fromAmount int) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stock_levels"`)).
+					WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}).AddRow(2, 1, fromAmount))
+				mock.ExpectRollback()
+			},
+			expectedError: ErrInsufficientStock,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR UPDATE`)).
+				WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			test.mockBehavior(test.fromAmount)
+
+			created, err := repo.Transfer(transfer)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 7, created.ID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
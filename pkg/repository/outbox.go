@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"encoding/json"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// outboxLockID is the advisory lock taken by dispatchers while they claim messages.
+const outboxLockID = 4242
+
+type Outbox interface {
+	ClaimPending(limit int, now time.Time, lease time.Duration) ([]models.OutboxMessage, error)
+	Release(claimed, published []int64, failures []models.OutboxFailure, maxAttempts int) error
+}
+
+type OutboxPostgres struct {
+	db *gorm.DB
+}
+
+func NewOutboxPostgres(db *gorm.DB) *OutboxPostgres {
+	return &OutboxPostgres{db: db}
+}
+
+// ClaimPending claims up to limit unpublished messages that are not waiting for a retry in insertion order for lease, so they can be
+// published outside of a transaction. Nothing is claimed while another claim on pending messages is
+// held, the messages of an aggregate are published in order by one replica at a time.
+func (r *OutboxPostgres) ClaimPending(limit int, now time.Time, lease time.Duration) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockID).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		var claimed bool
+		if err := tx.Raw("SELECT EXISTS (SELECT 1 FROM outbox WHERE published_at IS NULL AND failed_at IS NULL AND claimed_until > ?)", now).
+			Scan(&claimed).Error; err != nil {
+			return err
+		}
+		if claimed {
+			return nil
+		}
+		// Messages waiting for a retry hold back the later messages of their aggregate.
+		waiting := tx.Table("outbox AS earlier").Select("1").
+			Where("earlier.aggregate_type = outbox.aggregate_type AND earlier.aggregate_id = outbox.aggregate_id").
+			Where("earlier.id <= outbox.id AND earlier.published_at IS NULL AND earlier.failed_at IS NULL AND earlier.retry_at > ?", now)
+		if err := tx.Where("published_at IS NULL AND failed_at IS NULL AND NOT EXISTS (?)", waiting).
+			Order("id").Limit(limit).Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		ids := make([]int64, 0, len(messages))
+		for _, msg := range messages {
+			ids = append(ids, msg.ID)
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).
+			Update("claimed_until", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Release marks the published messages of a claim as published and returns the others to the pending ones.
+// Each failure counts an attempt of its message with the error and its retry time, a message failed
+// maxAttempts times is marked failed and not claimed anymore.
+func (r *OutboxPostgres) Release(claimed, published []int64, failures []models.OutboxFailure, maxAttempts int) error {
+	now := time.Now().UTC()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(published) > 0 {
+			if err := tx.Model(&models.OutboxMessage{}).Where("id IN ?", published).
+				Update("published_at", now).Error; err != nil {
+				return err
+			}
+		}
+		for _, failure := range failures {
+			if err := tx.Model(&models.OutboxMessage{}).Where("id = ?", failure.ID).Updates(map[string]interface{}{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": failure.Error,
+				"retry_at":   failure.RetryAt,
+				"failed_at":  gorm.Expr("CASE WHEN attempts + 1 >= ? THEN CAST(? AS TIMESTAMP) END", maxAttempts, now),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", claimed).
+			Update("claimed_until", nil).Error
+	})
+}
+
+// addToOutbox stores the event in the transaction of the change it describes.
+func addToOutbox(tx *gorm.DB, eventType string, id int, book *models.Book) error {
+	payload, err := json.Marshal(models.Event{
+		Type:       eventType,
+		BookID:     id,
+		Book:       book,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Omit("created_at", "claimed_until", "attempts", "last_error", "retry_at", "failed_at").Create(&models.OutboxMessage{
+		AggregateType: models.AggregateBook,
+		AggregateID:   id,
+		EventType:     eventType,
+		Payload:       payload,
+	}).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestClaimPending(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewOutboxPostgres(books.db)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		mockBehavior     func()
+		expectedMessages []models.OutboxMessage
+	}{
+		{
+			name: "Ok",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+					WithArgs(outboxLockID).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM outbox WHERE published_at IS NULL AND failed_at IS NULL AND claimed_until > $1)`)).
+					WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox" WHERE published_at IS NULL AND failed_at IS NULL AND NOT EXISTS (SELECT 1 FROM outbox AS earlier ` +
+					`WHERE (earlier.aggregate_type = outbox.aggregate_type AND earlier.aggregate_id = outbox.aggregate_id) ` +
+					`AND (earlier.id <= outbox.id AND earlier.published_at IS NULL AND earlier.failed_at IS NULL AND earlier.retry_at > $1)) ORDER BY id LIMIT 10`)).
+					WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id", "aggregate_id"}).AddRow(1, 2).AddRow(3, 4))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "claimed_until"=$1 WHERE id IN ($2,$3)`)).
+					WithArgs(now.Add(time.Minute), 1, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedMessages: []models.OutboxMessage{{ID: 1, AggregateID: 2}, {ID: 3, AggregateID: 4}},
+		},
+		{
+			name: "Claimed by another dispatcher",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+					WithArgs(outboxLockID).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).
+					WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Locked",
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+					WithArgs(outboxLockID).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+				mock.ExpectCommit()
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			test.mockBehavior()
+
+			messages, err := repo.ClaimPending(10, now, time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedMessages, messages)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewOutboxPostgres(books.db)
+	retryAt := time.Date(2030, 1, 1, 0, 0, 1, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "published_at"=$1 WHERE id IN ($2)`)).
+		WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "attempts"=attempts + 1,"failed_at"=CASE WHEN attempts + 1 >= $1 THEN CAST($2 AS TIMESTAMP) END,`+
+		`"last_error"=$3,"retry_at"=$4 WHERE id = $5`)).
+		WithArgs(5, sqlmock.AnyArg(), "broker is unavailable", retryAt, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "claimed_until"=$1 WHERE id IN ($2,$3)`)).
+		WithArgs(nil, 1, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	failures := []models.OutboxFailure{{ID: 3, Error: "broker is unavailable", RetryAt: retryAt}}
+	assert.NoError(t, repo.Release([]int64{1, 3}, []int64{1}, failures, 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
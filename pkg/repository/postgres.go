@@ -6,9 +6,19 @@ import (
 	"gorm.io/gorm"
 )
 
-func NewPostgresDB(config map[string]string, password string) (*gorm.DB, error) {
+// Config is the connection to PostgreSQL. The password is a secret and never comes from the config file.
+type Config struct {
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"-"`
+	DBName   string `mapstructure:"dbname"`
+	SSLMode  string `mapstructure:"sslmode"`
+}
+
+func NewPostgresDB(config Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		config["host"], config["user"], password, config["dbname"], config["port"], config["sslmode"])
+		config.Host, config.User, config.Password, config.DBName, config.Port, config.SSLMode)
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type PriceHistory interface {
+	GetPriceHistory(bookID int) ([]models.PriceChange, error)
+	GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error)
+	CreateScheduledPrice(price models.ScheduledPrice) (int, error)
+	DeleteScheduledPrice(bookID, id int) error
+	GetDuePrices(at time.Time) ([]models.ScheduledPrice, error)
+	ApplyPrice(price models.ScheduledPrice, at time.Time) (models.StockChange, error)
+}
+
+// ErrPriceApplied is returned for a scheduled price applied before.
+var ErrPriceApplied = errors.New("scheduled price is applied")
+
+type PriceHistoryPostgres struct {
+	db *gorm.DB
+}
+
+func NewPriceHistoryPostgres(db *gorm.DB) *PriceHistoryPostgres {
+	return &PriceHistoryPostgres{db: db}
+}
+
+func (r *PriceHistoryPostgres) GetPriceHistory(bookID int) ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	err := r.db.Where("book_id = ?", bookID).Order("changed_at DESC").Order("id DESC").Find(&changes).Error
+	return changes, err
+}
+
+func (r *PriceHistoryPostgres) GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error) {
+	var prices []models.ScheduledPrice
+	err := r.db.Where("book_id = ?", bookID).Order("effective_at").Find(&prices).Error
+	return prices, err
+}
+
+func (r *PriceHistoryPostgres) CreateScheduledPrice(price models.ScheduledPrice) (int, error) {
+	err := r.db.Select("book_id", "price", "effective_at").Create(&price).Error
+	return price.ID, err
+}
+
+// DeleteScheduledPrice cancels a price change that has not been applied yet.
+func (r *PriceHistoryPostgres) DeleteScheduledPrice(bookID, id int) error {
+	res := r.db.Where("id = ? AND book_id = ? AND applied_at IS NULL", id, bookID).Delete(&models.ScheduledPrice{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetDuePrices returns the pending price changes effective at the time, oldest first.
+func (r *PriceHistoryPostgres) GetDuePrices(at time.Time) ([]models.ScheduledPrice, error) {
+	var prices []models.ScheduledPrice
+	err := r.db.Where("applied_at IS NULL AND effective_at <= ?", at).
+		Order("effective_at").Order("id").Find(&prices).Error
+	return prices, err
+}
+
+// ApplyPrice sets the scheduled price of the book under its lock and marks it applied, with the price change
+// and the event of the update. Only the price is changed, the amount of the returned change is unchanged.
+func (r *PriceHistoryPostgres) ApplyPrice(price models.ScheduledPrice, at time.Time) (models.StockChange, error) {
+	var change models.StockChange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.ScheduledPrice{}).Where("id = ? AND applied_at IS NULL", price.ID).
+			Update("applied_at", at)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected < 1 {
+			return ErrPriceApplied
+		}
+		var book models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, price.BookID).Error; err != nil {
+			return err
+		}
+		if book.Price != price.Price {
+			if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Update("price", price.Price).Error; err != nil {
+				return err
+			}
+			if err := addPriceChange(tx, book.ID, price.Price, book.Currency); err != nil {
+				return err
+			}
+			book.Price = price.Price
+		}
+		change = models.StockChange{PreviousAmount: book.Amount, Book: book}
+		return addToOutbox(tx, models.EventBookUpdated, book.ID, &book)
+	})
+	return change, err
+}
+
+// addPriceChange records the price of the book within the transaction that sets it.
+func addPriceChange(tx *gorm.DB, bookID int, price models.Money, currency string) error {
+	return tx.Omit("id", "changed_at").Create(&models.PriceChange{
+		BookID:   bookID,
+		Price:    price,
+		Currency: currency,
+	}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestApplyPrice(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewPriceHistoryPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := models.ScheduledPrice{ID: 3, BookID: 1, Price: 3999}
+	tests := []struct {
+		name           string
+		mockBehavior   func()
+		expectedChange models.StockChange
+		expectedError  error
+	}{
+		{
+			name: "Ok",
+			mockBehavior: func() {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "scheduled_prices" SET "applied_at"=$1 WHERE id = $2 AND applied_at IS NULL`)).
+					WithArgs(at, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR UPDATE`)).
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "price", "currency", "amount"}).
+					AddRow(1, "45.99", "USD", 5))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "price"=$1 WHERE id = $2`)).
+					WithArgs(models.Money(3999), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"price_history\"").
+					WithArgs(1, models.Money(3999), "USD").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 1, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+			expectedChange: models.StockChange{
+				PreviousAmount: 5,
+				Book:           models.Book{ID: 1, Price: 3999, Currency: "USD", Amount: 5},
+			},
+		},
+		{
+			name: "Applied by another replica",
+			mockBehavior: func() {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "scheduled_prices" SET "applied_at"=$1`)).
+					WithArgs(at, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedError: ErrPriceApplied,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			test.mockBehavior()
+
+			change, err := repo.ApplyPrice(price, at)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedChange, change)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Prices interface {
+	GetPrices(bookID int) ([]models.BookPrice, error)
+	GetPricesByBooks(bookIDs []int, currency string) ([]models.BookPrice, error)
+	SetPrice(price models.BookPrice) error
+	DeletePrice(bookID int, currency string) error
+}
+
+type PricesPostgres struct {
+	db *gorm.DB
+}
+
+func NewPricesPostgres(db *gorm.DB) *PricesPostgres {
+	return &PricesPostgres{db: db}
+}
+
+func (r *PricesPostgres) GetPrices(bookID int) ([]models.BookPrice, error) {
+	var prices []models.BookPrice
+	err := r.db.Where("book_id = ?", bookID).Order("currency").Find(&prices).Error
+	return prices, err
+}
+
+func (r *PricesPostgres) GetPricesByBooks(bookIDs []int, currency string) ([]models.BookPrice, error) {
+	var prices []models.BookPrice
+	err := r.db.Where("book_id IN ? AND currency = ?", bookIDs, currency).Find(&prices).Error
+	return prices, err
+}
+
+// SetPrice inserts the price or replaces the one the book already has in the currency.
+func (r *PricesPostgres) SetPrice(price models.BookPrice) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price"}),
+	}).Create(&price).Error
+}
+
+func (r *PricesPostgres) DeletePrice(bookID int, currency string) error {
+	res := r.db.Where("book_id = ? AND currency = ?", bookID, currency).Delete(&models.BookPrice{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
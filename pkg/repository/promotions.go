@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type Promotions interface {
+	GetPromotions() ([]models.Promotion, error)
+	GetPromotionByID(id int) (models.Promotion, error)
+	GetActivePromotions(at time.Time) ([]models.Promotion, error)
+	CreatePromotion(promotion models.Promotion) (int, error)
+	UpdatePromotion(id int, promotion models.Promotion) error
+	DeletePromotion(id int) error
+}
+
+type PromotionsPostgres struct {
+	db *gorm.DB
+}
+
+func NewPromotionsPostgres(db *gorm.DB) *PromotionsPostgres {
+	return &PromotionsPostgres{db: db}
+}
+
+func (r *PromotionsPostgres) GetPromotions() ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.Order("id").Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *PromotionsPostgres) GetPromotionByID(id int) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := r.db.First(&promotion, id).Error
+	return promotion, err
+}
+
+// GetActivePromotions returns the promotions running at the time, highest priority first.
+func (r *PromotionsPostgres) GetActivePromotions(at time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.Where("starts_at <= ? AND ends_at > ?", at, at).
+		Order("priority DESC").Order("id").Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *PromotionsPostgres) CreatePromotion(promotion models.Promotion) (int, error) {
+	err := r.db.Omit("id").Create(&promotion).Error
+	return promotion.ID, err
+}
+
+func (r *PromotionsPostgres) UpdatePromotion(id int, promotion models.Promotion) error {
+	res := r.db.Where("id = ?", id).Select("*").Omit("id").Updates(promotion)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PromotionsPostgres) DeletePromotion(id int) error {
+	res := r.db.Delete(&models.Promotion{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidStatusChange = errors.New("purchase order cannot change to the status")
+	ErrNotOrdered          = errors.New("book is not on the purchase order")
+	ErrOverReceived        = errors.New("received more than ordered")
+)
+
+type PurchaseOrders interface {
+	GetPurchaseOrders(supplierID int, status string) ([]models.PurchaseOrder, error)
+	GetPurchaseOrderByID(id int) (models.PurchaseOrder, error)
+	CreatePurchaseOrder(order models.PurchaseOrder) (int, error)
+	SetPurchaseOrderStatus(id int, status string, at time.Time) error
+	Receive(id int, receipts []models.PurchaseOrderReceipt, at time.Time) ([]models.StockChange, error)
+	GetOutstandingOrders() ([]models.OutstandingOrders, error)
+}
+
+type PurchaseOrdersPostgres struct {
+	db *gorm.DB
+}
+
+func NewPurchaseOrdersPostgres(db *gorm.DB) *PurchaseOrdersPostgres {
+	return &PurchaseOrdersPostgres{db: db}
+}
+
+// GetPurchaseOrders lists the purchase orders, of the supplier and in the status when they are set.
+func (r *PurchaseOrdersPostgres) GetPurchaseOrders(supplierID int, status string) ([]models.PurchaseOrder, error) {
+	query := preloadPurchaseOrder(r.db)
+	if supplierID != 0 {
+		query = query.Where("supplier_id = ?", supplierID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var orders []models.PurchaseOrder
+	err := query.Order("id").Find(&orders).Error
+	return orders, err
+}
+
+func (r *PurchaseOrdersPostgres) GetPurchaseOrderByID(id int) (models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := preloadPurchaseOrder(r.db).First(&order, id).Error
+	return order, err
+}
+
+func preloadPurchaseOrder(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("book_id")
+		}).
+		Preload("Receipts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		})
+}
+
+// CreatePurchaseOrder adds a draft purchase order created at its CreatedAt with its items, nothing of them is received.
+func (r *PurchaseOrdersPostgres) CreatePurchaseOrder(order models.PurchaseOrder) (int, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		order.Status = models.PurchaseOrderDraft
+		if err := tx.Select("supplier_id", "status", "created_at", "updated_at").Create(&order).Error; err != nil {
+			return err
+		}
+		items := make([]models.PurchaseOrderItem, len(order.Items))
+		for i, item := range order.Items {
+			item.PurchaseOrderID = order.ID
+			item.Received = 0
+			items[i] = item
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		return addStatusChange(tx, order.ID, order.Status, order.CreatedAt)
+	})
+	return order.ID, err
+}
+
+// SetPurchaseOrderStatus moves the purchase order to the status if staff can move it there from its status.
+func (r *PurchaseOrdersPostgres) SetPurchaseOrderStatus(id int, status string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if !models.CanChangePurchaseOrderStatus(order.Status, status) {
+			return ErrInvalidStatusChange
+		}
+		return changeStatus(tx, id, status, at)
+	})
+}
+
+// Receive adds the received books to the stock at their locations and records what they cost, in one transaction.
+// The purchase order is received once all of its books are.
+func (r *PurchaseOrdersPostgres) Receive(id int, receipts []models.PurchaseOrderReceipt, at time.Time) ([]models.StockChange, error) {
+	var changes []models.StockChange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if !models.CanReceive(order.Status) {
+			return ErrInvalidStatusChange
+		}
+		var items []models.PurchaseOrderItem
+		if err = tx.Where("purchase_order_id = ?", id).Find(&items).Error; err != nil {
+			return err
+		}
+		ordered := make(map[int]*models.PurchaseOrderItem, len(items))
+		for i := range items {
+			ordered[items[i].BookID] = &items[i]
+		}
+		// Books are locked in the order of their ids, so concurrent stock changes cannot deadlock.
+		receipts = append([]models.PurchaseOrderReceipt(nil), receipts...)
+		sort.SliceStable(receipts, func(i, j int) bool {
+			return receipts[i].BookID < receipts[j].BookID
+		})
+		for _, receipt := range receipts {
+			item, ok := ordered[receipt.BookID]
+			if !ok {
+				return ErrNotOrdered
+			}
+			if item.Received+receipt.Quantity > item.Quantity {
+				return ErrOverReceived
+			}
+			item.Received += receipt.Quantity
+			err = tx.Model(&models.PurchaseOrderItem{}).Where("purchase_order_id = ? AND book_id = ?", id, item.BookID).
+				Update("received", item.Received).Error
+			if err != nil {
+				return err
+			}
+			if receipt.LocationID == 0 {
+				if receipt.LocationID, err = defaultLocation(tx); err != nil {
+					return err
+				}
+			}
+			var book models.Book
+			if err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, receipt.BookID).Error; err != nil {
+				return err
+			}
+			if err = adjustStock(tx, book.ID, receipt.LocationID, receipt.Quantity); err != nil {
+				return err
+			}
+			previous := book.Amount
+			book.Amount += receipt.Quantity
+			if err = saveAmount(tx, previous, &book); err != nil {
+				return err
+			}
+			if receipt.UnitCost == nil {
+				cost := item.UnitCost
+				receipt.UnitCost = &cost
+			}
+			receipt.PurchaseOrderID = id
+			receipt.ReceivedAt = at
+			if err = tx.Create(&receipt).Error; err != nil {
+				return err
+			}
+			changes = append(changes, models.StockChange{PreviousAmount: previous, Book: book})
+		}
+		status := models.PurchaseOrderReceived
+		for _, item := range items {
+			if item.Received < item.Quantity {
+				status = models.PurchaseOrderPartiallyReceived
+			}
+		}
+		if status == order.Status {
+			return tx.Model(&models.PurchaseOrder{}).Where("id = ?", id).Update("updated_at", at).Error
+		}
+		return changeStatus(tx, id, status, at)
+	})
+	return changes, err
+}
+
+// GetOutstandingOrders sums up the books still to be received from each supplier with sent purchase orders.
+func (r *PurchaseOrdersPostgres) GetOutstandingOrders() ([]models.OutstandingOrders, error) {
+	var report []models.OutstandingOrders
+	err := r.db.Model(&models.PurchaseOrderItem{}).
+		Select("suppliers.id AS supplier_id, suppliers.name AS supplier_name, "+
+			"COUNT(DISTINCT purchase_orders.id) AS orders, "+
+			"SUM(purchase_order_items.quantity - purchase_order_items.received) AS books, "+
+			"SUM((purchase_order_items.quantity - purchase_order_items.received) * purchase_order_items.unit_cost) AS cost").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_items.purchase_order_id").
+		Joins("JOIN suppliers ON suppliers.id = purchase_orders.supplier_id").
+		Where("purchase_orders.status IN ? AND purchase_order_items.received < purchase_order_items.quantity",
+			[]string{models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived}).
+		Group("suppliers.id, suppliers.name").Order("suppliers.id").
+		Scan(&report).Error
+	return report, err
+}
+
+func lockPurchaseOrder(tx *gorm.DB, id int) (models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&order, id).Error
+	return order, err
+}
+
+func changeStatus(tx *gorm.DB, id int, status string, at time.Time) error {
+	err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "updated_at": at}).Error
+	if err != nil {
+		return err
+	}
+	return addStatusChange(tx, id, status, at)
+}
+
+func addStatusChange(tx *gorm.DB, id int, status string, at time.Time) error {
+	return tx.Create(&models.PurchaseOrderStatusChange{PurchaseOrderID: id, Status: status, ChangedAt: at}).Error
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestReceive(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewPurchaseOrdersPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cost := models.Money(450)
+	tests := []struct {
+		name            string
+		status          string
+		receipts        []models.PurchaseOrderReceipt
+		mockBehavior    func()
+		expectedChanges []models.StockChange
+		expectedError   error
+	}{
+		{
+			name:   "Partially received",
+			status: models.PurchaseOrderSent,
+			receipts: []models.PurchaseOrderReceipt{
+				{BookID: 3, LocationID: 2, Quantity: 1, UnitCost: &cost},
+				// Without a cost, the cost agreed for the item is recorded.
+				{BookID: 2, Quantity: 4},
+			},
+			mockBehavior: func() {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "purchase_order_items" SET "received"=$1 WHERE purchase_order_id = $2 AND book_id = $3`)).
+					WithArgs(4, 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "locations" WHERE is_default LIMIT 1`)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR UPDATE`)).
+					WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, 0))
+				expectAdjustStock(mock, 2, 1, 0, 4)
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "amount"=$1 WHERE id = $2`)).
+					WithArgs(4, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 2, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 2, models.EventBookBackInStock, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchase_order_receipts" ("purchase_order_id","book_id","location_id","quantity","unit_cost","received_at")`)).
+					WithArgs(1, 2, 1, 4, models.Money(300), at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "purchase_order_items" SET "received"=$1`)).
+					WithArgs(1, 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books"`)).
+					WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(3, 6))
+				expectAdjustStock(mock, 3, 2, 0, 1)
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "amount"=$1 WHERE id = $2`)).
+					WithArgs(7, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 3, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchase_order_receipts"`)).
+					WithArgs(1, 3, 2, 1, models.Money(450), at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "purchase_orders" SET "status"=$1,"updated_at"=$2 WHERE id = $3`)).
+					WithArgs(models.PurchaseOrderPartiallyReceived, at, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "purchase_order_status_changes" ("purchase_order_id","status","changed_at")`)).
+					WithArgs(1, models.PurchaseOrderPartiallyReceived, at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectCommit()
+			},
+			expectedChanges: []models.StockChange{
+				{PreviousAmount: 0, Book: models.Book{ID: 2, Amount: 4}},
+				{PreviousAmount: 6, Book: models.Book{ID: 3, Amount: 7}},
+			},
+		},
+		{
+			name:     "More than ordered",
+			status:   models.PurchaseOrderPartiallyReceived,
+			receipts: []models.PurchaseOrderReceipt{{BookID: 3, Quantity: 3}},
+			mockBehavior: func() {
+				mock.ExpectRollback()
+			},
+			expectedError: ErrOverReceived,
+		},
+		{
+			name:     "Not ordered",
+			status:   models.PurchaseOrderSent,
+			receipts: []models.PurchaseOrderReceipt{{BookID: 4, Quantity: 1}},
+			mockBehavior: func() {
+				mock.ExpectRollback()
+			},
+			expectedError: ErrNotOrdered,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","status" FROM "purchase_orders" WHERE "purchase_orders"."id" = $1 ORDER BY "purchase_orders"."id" LIMIT 1 FOR UPDATE`)).
+				WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, test.status))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "purchase_order_items" WHERE purchase_order_id = $1`)).
+				WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"purchase_order_id", "book_id", "quantity", "received", "unit_cost"}).
+				AddRow(1, 2, 5, 0, "3.00").
+				AddRow(1, 3, 2, 0, "4.50"))
+			test.mockBehavior()
+
+			changes, err := repo.Receive(1, test.receipts, at)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedChanges, changes)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReceiveDraft(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewPurchaseOrdersPostgres(books.db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","status" FROM "purchase_orders"`)).
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, models.PurchaseOrderDraft))
+	mock.ExpectRollback()
+
+	_, err = repo.Receive(1, []models.PurchaseOrderReceipt{{BookID: 2, Quantity: 1}}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidStatusChange)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOutstandingOrders(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewPurchaseOrdersPostgres(books.db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT suppliers.id AS supplier_id, suppliers.name AS supplier_name, `+
+		`COUNT(DISTINCT purchase_orders.id) AS orders, `)+`.*`+
+		regexp.QuoteMeta(`WHERE purchase_orders.status IN ($1,$2) AND purchase_order_items.received < purchase_order_items.quantity `+
+			`GROUP BY suppliers.id, suppliers.name ORDER BY suppliers.id`)).
+		WithArgs(models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived).
+		WillReturnRows(sqlmock.NewRows([]string{"supplier_id", "supplier_name", "orders", "books", "cost"}).
+			AddRow(1, "Books Ltd", 2, 7, "31.50"))
+
+	report, err := repo.GetOutstandingOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.OutstandingOrders{{SupplierID: 1, SupplierName: "Books Ltd", Orders: 2, Books: 7, Cost: 3150}}, report)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"github.com/TenderLimbo/rest-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type BooksManager interface {
@@ -10,7 +12,7 @@ type BooksManager interface {
 	GetBookByID(id int) (models.Book, error)
 	CreateBook(book models.Book) (int, error)
 	DeleteBookByID(id int) error
-	UpdateBookByID(id int, book models.Book) error
+	UpdateBookByID(id int, book models.Book) (models.StockChange, error)
 }
 
 type BooksManagerPostgres struct {
@@ -21,50 +23,153 @@ func NewRepository(db *gorm.DB) *BooksManagerPostgres {
 	return &BooksManagerPostgres{db: db}
 }
 
+// GetBooks lists the books of the genre and availability filters with their held amounts, books in stock
+// when there is no availability. In stock are the books with more than their held amount, with a location
+// filter the availability is the stock at the location. Books are sorted by rating with sort=rating.
 func (r *BooksManagerPostgres) GetBooks(filterCondition map[string][]string) ([]models.Book, error) {
 	var Books []models.Book
-	var err error
-	if len(filterCondition) == 0 {
-		err = r.db.Where("amount <> ?", 0).Find(&Books).Error
+	held := heldBooks(r.db, time.Now().UTC())
+	query := r.db.Select("books.*, (?) AS held", held)
+	var availability string
+	if values := filterCondition["availability"]; len(values) > 0 {
+		availability = values[0]
+	}
+	if location := filterCondition["location"]; len(location) > 0 {
+		// Availability is about the stock at the location then.
+		inStock := r.db.Model(&models.StockLevel{}).Select("1").
+			Where("stock_levels.book_id = books.id AND stock_levels.location_id = ? AND stock_levels.amount > 0", location[0])
+		switch availability {
+		case models.AvailabilityAll:
+		case models.AvailabilityOutOfStock:
+			query = query.Where("NOT EXISTS (?)", inStock)
+		default:
+			query = query.Where("EXISTS (?)", inStock)
+		}
 	} else {
-		err = r.db.Where("amount <> ?", 0).Where("genre = ?", filterCondition["genre"]).Find(&Books).Error
+		switch availability {
+		case models.AvailabilityAll:
+		case models.AvailabilityOutOfStock:
+			query = query.Where("amount <= (?)", held)
+		default:
+			query = query.Where("amount > (?)", held)
+		}
+	}
+	if genre, ok := filterCondition["genre"]; ok {
+		query = query.Where("genre = ?", genre)
+	}
+	if sort := filterCondition["sort"]; len(sort) > 0 && sort[0] == models.SortRating {
+		query = query.Order("avg_rating DESC").Order("review_count DESC").Order("id")
 	}
+	err := query.Find(&Books).Error
 	return Books, err
 }
 
 func (r *BooksManagerPostgres) GetBookByID(id int) (models.Book, error) {
 	var book models.Book
-	if err := r.db.First(&book, id).Error; err != nil {
+	if err := r.db.Select("books.*, (?) AS held", heldBooks(r.db, time.Now().UTC())).First(&book, id).Error; err != nil {
 		return book, err
 	}
 	return book, nil
 }
 
 func (r *BooksManagerPostgres) CreateBook(newBook models.Book) (int, error) {
-	if err := r.db.Debug().Select("name", "price", "genre", "amount").Create(&newBook).Error; err != nil {
-		return newBook.ID, err
-	}
-	return newBook.ID, nil
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Debug().Select("name", "author", "price", "currency", "genre", "amount", "reorder_threshold").Create(&newBook).Error; err != nil {
+			return err
+		}
+		if err := addPriceChange(tx, newBook.ID, newBook.Price, newBook.Currency); err != nil {
+			return err
+		}
+		if newBook.Amount > 0 {
+			if err := addStock(tx, newBook.ID, newBook.Amount); err != nil {
+				return err
+			}
+		}
+		return addToOutbox(tx, models.EventBookCreated, newBook.ID, &newBook)
+	})
+	return newBook.ID, err
 }
 
 func (r *BooksManagerPostgres) DeleteBookByID(id int) error {
-	res := r.db.Delete(&models.Book{}, id)
-	if res.Error != nil {
-		return res.Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Book{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected < 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return addToOutbox(tx, models.EventBookDeleted, id, nil)
+	})
+}
+
+// UpdateBookByID stores the book with the amount it replaced, read under the lock of the update.
+func (r *BooksManagerPostgres) UpdateBookByID(id int, newBook models.Book) (models.StockChange, error) {
+	var change models.StockChange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var previous models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("price", "currency", "amount").First(&previous, id).Error; err != nil {
+			return err
+		}
+		// The rating is kept by the reviews and never comes from the book.
+		res := tx.Where("id = ?", id).Select("*").Omit("id", "avg_rating", "review_count", "cover").Updates(newBook)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected < 1 {
+			return gorm.ErrRecordNotFound
+		}
+		// The amount is the total of the stock levels, so changes of it are applied to the stock levels.
+		if delta := newBook.Amount - previous.Amount; delta > 0 {
+			if err := addStock(tx, id, delta); err != nil {
+				return err
+			}
+		} else if delta < 0 {
+			if err := takeStock(tx, id, -delta); err != nil {
+				return err
+			}
+		}
+		if previous.Price != newBook.Price || previous.Currency != newBook.Currency {
+			if err := addPriceChange(tx, id, newBook.Price, newBook.Currency); err != nil {
+				return err
+			}
+		}
+		newBook.ID = id
+		if err := addToOutbox(tx, models.EventBookUpdated, id, &newBook); err != nil {
+			return err
+		}
+		change = models.StockChange{PreviousAmount: previous.Amount, Book: newBook}
+		return addStockEvents(tx, previous.Amount, &newBook)
+	})
+	return change, err
+}
+
+// saveAmount stores the amount of a locked book changed from previousAmount outside of a book update,
+// with the events of the change. The stock levels are changed by the caller.
+func saveAmount(tx *gorm.DB, previousAmount int, book *models.Book) error {
+	if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Update("amount", book.Amount).Error; err != nil {
+		return err
 	}
-	if res.RowsAffected < 1 {
-		return gorm.ErrRecordNotFound
+	if err := addToOutbox(tx, models.EventBookUpdated, book.ID, book); err != nil {
+		return err
 	}
-	return nil
+	return addStockEvents(tx, previousAmount, book)
 }
 
-func (r *BooksManagerPostgres) UpdateBookByID(id int, newBook models.Book) error {
-	res := r.db.Where("id = ?", id).Select("*").Omit("id").Updates(newBook)
-	if res.Error != nil {
-		return res.Error
+// addStockEvents stores the events of the stock transitions of the book from previousAmount.
+func addStockEvents(tx *gorm.DB, previousAmount int, book *models.Book) error {
+	if previousAmount > 0 && book.Amount == 0 {
+		if err := addToOutbox(tx, models.EventBookOutOfStock, book.ID, book); err != nil {
+			return err
+		}
+	}
+	if previousAmount == 0 && book.Amount > 0 {
+		if err := addToOutbox(tx, models.EventBookBackInStock, book.ID, book); err != nil {
+			return err
+		}
 	}
-	if res.RowsAffected < 1 {
-		return gorm.ErrRecordNotFound
+	if book.CrossedReorderThreshold(previousAmount) {
+		return addToOutbox(tx, models.EventBookLowStock, book.ID, book)
 	}
 	return nil
 }
@@ -41,32 +41,41 @@ func TestCreateBook(t *testing.T) {
 		{
 			name: "Ok",
 			inputBook: models.Book{
-				Name:   "hello",
-				Price:  45.99,
-				Genre:  1,
-				Amount: 8,
+				Name:     "hello",
+				Price:    4599,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   8,
 			},
 			returnedId: 1,
 			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO \"books\"").
-					WithArgs(book.Name, book.Price, book.Genre, book.Amount).
+					WithArgs(book.Name, book.Author, book.Price, book.Currency, book.Genre, book.Amount, book.ReorderThreshold).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(returnedId))
+				mock.ExpectQuery("INSERT INTO \"price_history\"").
+					WithArgs(returnedId, book.Price, book.Currency).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				expectAddStock(mock, returnedId, 0, book.Amount)
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, returnedId, models.EventBookCreated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectCommit()
 			},
 		},
 		{
 			name: "Empty field",
 			inputBook: models.Book{
-				Name:   "",
-				Price:  45.99,
-				Genre:  1,
-				Amount: 8,
+				Name:     "",
+				Price:    4599,
+				Currency: "USD",
+				Genre:    1,
+				Amount:   8,
 			},
 			mockBehavior: func(mock sqlmock.Sqlmock, returnedId int, book models.Book) {
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO \"books\"").
-					WithArgs(book.Name, book.Price, book.Genre, book.Amount).
+					WithArgs(book.Name, book.Author, book.Price, book.Currency, book.Genre, book.Amount, book.ReorderThreshold).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(returnedId).RowError(0, errors.New("insert error")))
 				mock.ExpectRollback()
 			},
@@ -107,6 +116,9 @@ func TestDeleteBookByID(t *testing.T) {
 				mock.ExpectBegin()
 				mock.ExpectExec(regexp.QuoteMeta("DELETE")).WithArgs(inputId).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookDeleted, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectCommit()
 			},
 		},
@@ -151,16 +163,19 @@ func TestGetBooks(t *testing.T) {
 		{
 			name: "Ok",
 			mockBehavior: func(filterCondition map[string][]string) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(0).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 3.7, 1, 1).
-						AddRow(2, "book2", 4.7, 2, 2).
-						AddRow(3, "book3", 5.7, 3, 3))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT books.*, (SELECT COALESCE(SUM(cart_items.quantity), 0) FROM "cart_items" `+
+					`JOIN carts ON carts.id = cart_items.cart_id WHERE cart_items.book_id = books.id AND carts.status = $1 AND cart_items.expires_at > $2) AS held `+
+					`FROM "books" WHERE amount > (SELECT COALESCE(SUM(cart_items.quantity), 0)`)).
+					WithArgs(models.CartOpen, sqlmock.AnyArg(), models.CartOpen, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount", "held"}).
+						AddRow(1, "book1", "3.70", "USD", 1, 1, 0).
+						AddRow(2, "book2", "4.70", "USD", 2, 2, 1).
+						AddRow(3, "book3", "5.70", "USD", 3, 3, 0))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Price: 3.7, Genre: 1, Amount: 1},
-				{ID: 2, Name: "book2", Price: 4.7, Genre: 2, Amount: 2},
-				{ID: 3, Name: "book3", Price: 5.7, Genre: 3, Amount: 3},
+				{ID: 1, Name: "book1", Price: 370, Currency: "USD", Genre: 1, Amount: 1},
+				{ID: 2, Name: "book2", Price: 470, Currency: "USD", Genre: 2, Amount: 2, Held: 1},
+				{ID: 3, Name: "book3", Price: 570, Currency: "USD", Genre: 3, Amount: 3},
 			},
 		},
 		{
@@ -168,12 +183,66 @@ func TestGetBooks(t *testing.T) {
 			filterCondition: map[string][]string{"genre": {"1"}},
 			mockBehavior: func(filterCondition map[string][]string) {
 				genreId := filterCondition["genre"][0]
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(0, genreId).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(1, "book1", 3.7, 1, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), models.CartOpen, sqlmock.AnyArg(), genreId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}).
+						AddRow(1, "book1", "3.70", "USD", 1, 1))
 			},
 			expectedBooks: []models.Book{
-				{ID: 1, Name: "book1", Price: 3.7, Genre: 1, Amount: 1},
+				{ID: 1, Name: "book1", Price: 370, Currency: "USD", Genre: 1, Amount: 1},
+			},
+		},
+		{
+			name:            "All books OK",
+			filterCondition: map[string][]string{"availability": {models.AvailabilityAll}},
+			mockBehavior: func(filterCondition map[string][]string) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT books.*`)).WithArgs(models.CartOpen, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}).
+						AddRow(1, "book1", "3.70", "USD", 1, 1).
+						AddRow(2, "book2", "4.70", "USD", 2, 0))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Price: 370, Currency: "USD", Genre: 1, Amount: 1},
+				{ID: 2, Name: "book2", Price: 470, Currency: "USD", Genre: 2, Amount: 0},
+			},
+		},
+		{
+			name:            "Out of stock books of genre OK",
+			filterCondition: map[string][]string{"availability": {models.AvailabilityOutOfStock}, "genre": {"2"}},
+			mockBehavior: func(filterCondition map[string][]string) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM "books" WHERE amount <= (SELECT COALESCE(SUM(cart_items.quantity), 0)`)).
+					WithArgs(models.CartOpen, sqlmock.AnyArg(), models.CartOpen, sqlmock.AnyArg(), "2").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}).
+						AddRow(2, "book2", "4.70", "USD", 2, 0))
+			},
+			expectedBooks: []models.Book{
+				{ID: 2, Name: "book2", Price: 470, Currency: "USD", Genre: 2, Amount: 0},
+			},
+		},
+		{
+			name:            "Sort by rating OK",
+			filterCondition: map[string][]string{"sort": {models.SortRating}},
+			mockBehavior: func(filterCondition map[string][]string) {
+				mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY avg_rating DESC,review_count DESC,id`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), models.CartOpen, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount", "avg_rating", "review_count"}).
+						AddRow(2, "book2", "4.70", "USD", 2, 2, "4.50", 2).
+						AddRow(1, "book1", "3.70", "USD", 1, 1, "0.00", 0))
+			},
+			expectedBooks: []models.Book{
+				{ID: 2, Name: "book2", Price: 470, Currency: "USD", Genre: 2, Amount: 2, AvgRating: 4.5, ReviewCount: 2},
+				{ID: 1, Name: "book1", Price: 370, Currency: "USD", Genre: 1, Amount: 1},
+			},
+		},
+		{
+			name:            "In stock at location OK",
+			filterCondition: map[string][]string{"location": {"3"}},
+			mockBehavior: func(filterCondition map[string][]string) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM "books" WHERE EXISTS (SELECT 1 FROM "stock_levels" `+
+					`WHERE stock_levels.book_id = books.id AND stock_levels.location_id = $3 AND stock_levels.amount > 0)`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), "3").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}).
+						AddRow(1, "book1", "3.70", "USD", 1, 1))
+			},
+			expectedBooks: []models.Book{
+				{ID: 1, Name: "book1", Price: 370, Currency: "USD", Genre: 1, Amount: 1},
 			},
 		},
 		{
@@ -181,8 +250,8 @@ func TestGetBooks(t *testing.T) {
 			filterCondition: map[string][]string{"genre": {"1"}},
 			mockBehavior: func(filterCondition map[string][]string) {
 				genreId := filterCondition["genre"][0]
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(0, genreId).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), models.CartOpen, sqlmock.AnyArg(), genreId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}))
 			},
 			expectedBooks: []models.Book{},
 		},
@@ -218,23 +287,24 @@ func TestGetBookByID(t *testing.T) {
 		{
 			name: "Ok",
 			mockBehavior: func(inputId int) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(inputId).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "genre", "amount"}).
-						AddRow(inputId, "book1", 1.11, 2, 9))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), inputId).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "genre", "amount"}).
+						AddRow(inputId, "book1", "1.11", "USD", 2, 9))
 			},
 			inputId: 2,
 			expectedBook: models.Book{
-				ID:     2,
-				Name:   "book1",
-				Price:  1.11,
-				Genre:  2,
-				Amount: 9,
+				ID:       2,
+				Name:     "book1",
+				Price:    111,
+				Currency: "USD",
+				Genre:    2,
+				Amount:   9,
 			},
 		},
 		{
 			name: "Id not found",
 			mockBehavior: func(inputId int) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(inputId).WillReturnError(errors.New("id not found"))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).WithArgs(models.CartOpen, sqlmock.AnyArg(), inputId).WillReturnError(errors.New("id not found"))
 			},
 			inputId:     2,
 			expectError: true,
@@ -266,42 +336,146 @@ func TestUpdateBookByID(t *testing.T) {
 		mockBehavior mockBehavior
 		inputId      int
 		inputBook    models.Book
-		expectError  bool
+		// expectedPreviousAmount is the amount the update replaced.
+		expectedPreviousAmount int
+		expectError            bool
 	}{
 		{
 			name: "Ok",
 			mockBehavior: func(inputId int, inputBook models.Book) {
 				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "price","currency","amount" FROM "books" WHERE "books"."id" = $1`)).
+					WithArgs(inputId).WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "amount"}).AddRow("1.00", "USD", 5))
 				mock.ExpectExec(regexp.QuoteMeta(`UPDATE`)).
-					WithArgs(inputBook.Name, inputBook.Price, inputBook.Genre, inputBook.Amount, inputId, inputId).
+					WithArgs(inputBook.Name, inputBook.Author, inputBook.Price, inputBook.Currency, inputBook.Genre, inputBook.Amount, inputBook.ReorderThreshold, inputId, inputId).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAddStock(mock, inputId, 5, 4)
+				mock.ExpectQuery("INSERT INTO \"price_history\"").
+					WithArgs(inputId, inputBook.Price, inputBook.Currency).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectCommit()
 			},
 			inputId: 1,
 			inputBook: models.Book{
-				ID:     1,
-				Name:   "book1",
-				Price:  1.11,
-				Genre:  2,
-				Amount: 9,
+				ID:       1,
+				Name:     "book1",
+				Price:    111,
+				Currency: "USD",
+				Genre:    2,
+				Amount:   9,
 			},
+			expectedPreviousAmount: 5,
 		},
 		{
-			name: "id not found",
+			name: "Out of stock Ok",
+			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "price","currency","amount" FROM "books"`)).
+					WithArgs(inputId).WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "amount"}).AddRow("1.11", "USD", 5))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE`)).
+					WithArgs(inputBook.Name, inputBook.Author, inputBook.Price, inputBook.Currency, inputBook.Genre, inputBook.Amount, inputBook.ReorderThreshold, inputId, inputId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTakeStock(mock, inputId, 5, 5)
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookOutOfStock, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookLowStock, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectCommit()
+			},
+			inputId: 1,
+			inputBook: models.Book{
+				ID:       1,
+				Name:     "book1",
+				Price:    111,
+				Currency: "USD",
+				Genre:    2,
+				Amount:   0,
+			},
+			expectedPreviousAmount: 5,
+		},
+		{
+			name: "Low stock Ok",
+			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "price","currency","amount" FROM "books"`)).
+					WithArgs(inputId).WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "amount"}).AddRow("1.11", "USD", 5))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE`)).
+					WithArgs(inputBook.Name, inputBook.Author, inputBook.Price, inputBook.Currency, inputBook.Genre, inputBook.Amount, inputBook.ReorderThreshold, inputId, inputId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTakeStock(mock, inputId, 5, 3)
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookLowStock, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectCommit()
+			},
+			inputId: 1,
+			inputBook: models.Book{
+				ID:               1,
+				Name:             "book1",
+				Price:            111,
+				Currency:         "USD",
+				Genre:            2,
+				Amount:           2,
+				ReorderThreshold: 3,
+			},
+			expectedPreviousAmount: 5,
+		},
+		{
+			name: "Back in stock Ok",
 			mockBehavior: func(inputId int, inputBook models.Book) {
 				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "price","currency","amount" FROM "books"`)).
+					WithArgs(inputId).WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "amount"}).AddRow("1.11", "USD", 0))
 				mock.ExpectExec(regexp.QuoteMeta(`UPDATE`)).
-					WithArgs(inputBook.Name, inputBook.Price, inputBook.Genre, inputBook.Amount, inputId, inputId).
-					WillReturnError(errors.New("id not found"))
+					WithArgs(inputBook.Name, inputBook.Author, inputBook.Price, inputBook.Currency, inputBook.Genre, inputBook.Amount, inputBook.ReorderThreshold, inputId, inputId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAddStock(mock, inputId, 0, 4)
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, inputId, models.EventBookBackInStock, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectCommit()
+			},
+			inputId: 1,
+			inputBook: models.Book{
+				ID:       1,
+				Name:     "book1",
+				Price:    111,
+				Currency: "USD",
+				Genre:    2,
+				Amount:   4,
+			},
+			expectedPreviousAmount: 0,
+		},
+		{
+			name: "id not found",
+			mockBehavior: func(inputId int, inputBook models.Book) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "price","currency","amount" FROM "books"`)).
+					WithArgs(inputId).WillReturnError(errors.New("id not found"))
 				mock.ExpectRollback()
 			},
 			inputId: 1,
 			inputBook: models.Book{
-				ID:     1,
-				Name:   "book1",
-				Price:  1.11,
-				Genre:  2,
-				Amount: 9,
+				ID:       1,
+				Name:     "book1",
+				Price:    111,
+				Currency: "USD",
+				Genre:    2,
+				Amount:   9,
 			},
 			expectError: true,
 		},
@@ -309,11 +483,12 @@ func TestUpdateBookByID(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockBehavior(test.inputId, test.inputBook)
-			err := repo.UpdateBookByID(test.inputId, test.inputBook)
+			change, err := repo.UpdateBookByID(test.inputId, test.inputBook)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, models.StockChange{PreviousAmount: test.expectedPreviousAmount, Book: test.inputBook}, change)
 			}
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
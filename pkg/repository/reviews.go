@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Reviews interface {
+	CreateReview(review models.Review) (int, error)
+	GetReviews(bookID int, status string, page models.Page) ([]models.Review, int64, error)
+	GetReviewByID(id int) (models.Review, error)
+	UpdateReview(id int, review models.Review) error
+	DeleteReview(id int) error
+	SetReviewStatus(id int, status string) (models.Review, error)
+}
+
+type ReviewsPostgres struct {
+	db *gorm.DB
+}
+
+func NewReviewsPostgres(db *gorm.DB) *ReviewsPostgres {
+	return &ReviewsPostgres{db: db}
+}
+
+func (r *ReviewsPostgres) CreateReview(review models.Review) (int, error) {
+	err := r.db.Select("book_id", "author", "rating", "text", "status", "token_hash").Create(&review).Error
+	return review.ID, err
+}
+
+// GetReviews returns a page of the reviews in the status, newest first, with the number of all of them.
+// Reviews of all books are returned when bookID is 0.
+func (r *ReviewsPostgres) GetReviews(bookID int, status string, page models.Page) ([]models.Review, int64, error) {
+	query := r.db.Model(&models.Review{}).Where("status = ?", status)
+	if bookID != 0 {
+		query = query.Where("book_id = ?", bookID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var reviews []models.Review
+	err := query.Order("created_at DESC").Order("id DESC").Limit(page.Limit).Offset(page.Offset).Find(&reviews).Error
+	return reviews, total, err
+}
+
+func (r *ReviewsPostgres) GetReviewByID(id int) (models.Review, error) {
+	var review models.Review
+	err := r.db.First(&review, id).Error
+	return review, err
+}
+
+// UpdateReview replaces the review, which goes back to moderation and leaves the rating of the book.
+func (r *ReviewsPostgres) UpdateReview(id int, review models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var previous models.Review
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("book_id").First(&previous, id).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Review{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"author": review.Author,
+			"rating": review.Rating,
+			"text":   review.Text,
+			"status": models.ReviewPending,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return updateRating(tx, previous.BookID)
+	})
+}
+
+func (r *ReviewsPostgres) DeleteReview(id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "book_id"}}}).
+			Where("id = ?", id).Delete(&review).Error; err != nil {
+			return err
+		}
+		if review.BookID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return updateRating(tx, review.BookID)
+	})
+}
+
+// SetReviewStatus moderates the review and updates the rating of its book.
+func (r *ReviewsPostgres) SetReviewStatus(id int, status string) (models.Review, error) {
+	var review models.Review
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&review).Update("status", status).Error; err != nil {
+			return err
+		}
+		return updateRating(tx, review.BookID)
+	})
+	return review, err
+}
+
+// updateRating recomputes the rating of the book from its approved reviews. The book is locked
+// first, so concurrent moderations of its reviews take turns and see each other's changes.
+func updateRating(tx *gorm.DB, bookID int) error {
+	var book models.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&book, bookID).Error; err != nil {
+		return err
+	}
+	approved := tx.Model(&models.Review{}).Where("book_id = ? AND status = ?", bookID, models.ReviewApproved)
+	return tx.Model(&models.Book{}).Where("id = ?", bookID).Updates(map[string]interface{}{
+		"avg_rating":   approved.Session(&gorm.Session{}).Select("COALESCE(ROUND(AVG(rating), 2), 0)"),
+		"review_count": approved.Session(&gorm.Session{}).Select("COUNT(*)"),
+	}).Error
+}
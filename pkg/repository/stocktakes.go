@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrStocktakeFinalized = errors.New("stocktake is finalized")
+	ErrNotInStocktake     = errors.New("book is not counted in the stocktake")
+)
+
+type Stocktakes interface {
+	GetStocktakes() ([]models.Stocktake, error)
+	GetStocktakeByID(id int) (models.Stocktake, error)
+	CreateStocktake(stocktake models.Stocktake) (int, error)
+	SetCounts(id int, counts []models.StocktakeCount) error
+	AddCount(id int, count models.StocktakeCount) (models.StocktakeItem, error)
+	GetVariances(id int) ([]models.StocktakeVariance, error)
+	Finalize(id int, at time.Time) ([]models.StockChange, error)
+}
+
+type StocktakesPostgres struct {
+	db *gorm.DB
+}
+
+func NewStocktakesPostgres(db *gorm.DB) *StocktakesPostgres {
+	return &StocktakesPostgres{db: db}
+}
+
+func (r *StocktakesPostgres) GetStocktakes() ([]models.Stocktake, error) {
+	var stocktakes []models.Stocktake
+	err := r.db.Order("id").Find(&stocktakes).Error
+	return stocktakes, err
+}
+
+func (r *StocktakesPostgres) GetStocktakeByID(id int) (models.Stocktake, error) {
+	var stocktake models.Stocktake
+	err := r.db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("book_id")
+		}).
+		Preload("Adjustments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		First(&stocktake, id).Error
+	return stocktake, err
+}
+
+// CreateStocktake opens the stocktake with the stock of the books it counts at that moment as expected.
+func (r *StocktakesPostgres) CreateStocktake(stocktake models.Stocktake) (int, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		stocktake.Status = models.StocktakeOpen
+		if err := tx.Select("genre", "location_id", "status", "created_at").Create(&stocktake).Error; err != nil {
+			return err
+		}
+		books := tx.Model(&models.Book{}).Select("?::int, books.id, books.amount", stocktake.ID)
+		if stocktake.LocationID != nil {
+			books = tx.Model(&models.Book{}).Select("?::int, books.id, COALESCE(stock_levels.amount, 0)", stocktake.ID).
+				Joins("LEFT JOIN stock_levels ON stock_levels.book_id = books.id AND stock_levels.location_id = ?",
+					*stocktake.LocationID)
+		}
+		if stocktake.Genre != 0 {
+			books = books.Where("books.genre = ?", stocktake.Genre)
+		}
+		return tx.Exec("INSERT INTO stocktake_items (stocktake_id, book_id, expected) ?", books).Error
+	})
+	return stocktake.ID, err
+}
+
+// SetCounts replaces the counts of the books.
+func (r *StocktakesPostgres) SetCounts(id int, counts []models.StocktakeCount) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenStocktake(tx, id); err != nil {
+			return err
+		}
+		for _, count := range counts {
+			if err := updateCount(tx, id, count.BookID, count.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddCount adds the quantity to the count of the book, as a scan of a barcode does.
+func (r *StocktakesPostgres) AddCount(id int, count models.StocktakeCount) (models.StocktakeItem, error) {
+	var item models.StocktakeItem
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenStocktake(tx, id); err != nil {
+			return err
+		}
+		err := updateCount(tx, id, count.BookID, gorm.Expr("COALESCE(counted, 0) + ?", count.Quantity))
+		if err != nil {
+			return err
+		}
+		return tx.Where("stocktake_id = ? AND book_id = ?", id, count.BookID).Take(&item).Error
+	})
+	return item, err
+}
+
+func updateCount(tx *gorm.DB, id, bookID int, counted interface{}) error {
+	res := tx.Model(&models.StocktakeItem{}).Where("stocktake_id = ? AND book_id = ?", id, bookID).
+		Update("counted", counted)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return ErrNotInStocktake
+	}
+	return nil
+}
+
+// GetVariances compares the counted books with their stock when the stocktake was opened.
+func (r *StocktakesPostgres) GetVariances(id int) ([]models.StocktakeVariance, error) {
+	var variances []models.StocktakeVariance
+	err := r.db.Model(&models.StocktakeItem{}).
+		Select("book_id, expected, counted, counted - expected AS variance").
+		Where("stocktake_id = ? AND counted IS NOT NULL", id).Order("book_id").
+		Scan(&variances).Error
+	return variances, err
+}
+
+// Finalize applies the variances of the counted books to their current stock in one transaction and records
+// the corrections. Books that were not counted keep their stock.
+func (r *StocktakesPostgres) Finalize(id int, at time.Time) ([]models.StockChange, error) {
+	var changes []models.StockChange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var stocktake models.Stocktake
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stocktake, id).Error; err != nil {
+			return err
+		}
+		if stocktake.Status != models.StocktakeOpen {
+			return ErrStocktakeFinalized
+		}
+		var items []models.StocktakeItem
+		// Books are locked in the order of their ids, so concurrent stock changes cannot deadlock.
+		err := tx.Where("stocktake_id = ? AND counted IS NOT NULL AND counted <> expected", id).
+			Order("book_id").Find(&items).Error
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			var book models.Book
+			if err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, item.BookID).Error; err != nil {
+				return err
+			}
+			adjustment, err := applyCount(tx, stocktake, item, book.Amount)
+			if err != nil {
+				return err
+			}
+			if adjustment.Amount == adjustment.PreviousAmount {
+				continue
+			}
+			adjustment.CreatedAt = at
+			if err = tx.Create(&adjustment).Error; err != nil {
+				return err
+			}
+			previous := book.Amount
+			book.Amount += adjustment.Amount - adjustment.PreviousAmount
+			if err = saveAmount(tx, previous, &book); err != nil {
+				return err
+			}
+			changes = append(changes, models.StockChange{PreviousAmount: previous, Book: book})
+		}
+		return tx.Model(&models.Stocktake{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": models.StocktakeFinalized, "finalized_at": at}).Error
+	})
+	return changes, err
+}
+
+// applyCount applies the variance of the counted book to its stock at the location of the stocktake,
+// or to its amount, which is bookAmount. The stock never goes below 0.
+func applyCount(tx *gorm.DB, stocktake models.Stocktake, item models.StocktakeItem, bookAmount int) (models.StocktakeAdjustment, error) {
+	adjustment := models.StocktakeAdjustment{
+		StocktakeID:    stocktake.ID,
+		BookID:         item.BookID,
+		Expected:       item.Expected,
+		Counted:        *item.Counted,
+		PreviousAmount: bookAmount,
+	}
+	if stocktake.LocationID != nil {
+		amount, err := stockAt(tx, item.BookID, *stocktake.LocationID)
+		if err != nil {
+			return adjustment, err
+		}
+		adjustment.PreviousAmount = amount
+	}
+	adjustment.Amount = adjustment.PreviousAmount + adjustment.Counted - adjustment.Expected
+	if adjustment.Amount < 0 {
+		adjustment.Amount = 0
+	}
+	delta := adjustment.Amount - adjustment.PreviousAmount
+	switch {
+	case delta == 0:
+		return adjustment, nil
+	case stocktake.LocationID != nil:
+		return adjustment, setStock(tx, item.BookID, *stocktake.LocationID, adjustment.Amount)
+	case delta > 0:
+		return adjustment, addStock(tx, item.BookID, delta)
+	default:
+		return adjustment, takeStock(tx, item.BookID, -delta)
+	}
+}
+
+// lockOpenStocktake keeps the stocktake from being finalized until the transaction ends, counts of it
+// can be submitted concurrently.
+func lockOpenStocktake(tx *gorm.DB, id int) error {
+	var stocktake models.Stocktake
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("status").First(&stocktake, id).Error; err != nil {
+		return err
+	}
+	if stocktake.Status != models.StocktakeOpen {
+		return ErrStocktakeFinalized
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCreateStocktake(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewStocktakesPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	location := 2
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "stocktakes" ("genre","location_id","status","created_at") VALUES ($1,$2,$3,$4) RETURNING "id"`)).
+		WithArgs(1, 2, models.StocktakeOpen, at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO stocktake_items (stocktake_id, book_id, expected) `+
+		`SELECT $1::int, books.id, COALESCE(stock_levels.amount, 0) FROM "books" `+
+		`LEFT JOIN stock_levels ON stock_levels.book_id = books.id AND stock_levels.location_id = $2 WHERE books.genre = $3`)).
+		WithArgs(3, 2, 1).WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	id, err := repo.CreateStocktake(models.Stocktake{Genre: 1, LocationID: &location, CreatedAt: at})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddCount(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewStocktakesPostgres(books.db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "status" FROM "stocktakes" WHERE "stocktakes"."id" = $1 ORDER BY "stocktakes"."id" LIMIT 1 FOR SHARE`)).
+		WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.StocktakeOpen))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "stocktake_items" SET "counted"=COALESCE(counted, 0) + $1 WHERE stocktake_id = $2 AND book_id = $3`)).
+		WithArgs(1, 3, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.AddCount(3, models.StocktakeCount{BookID: 5, Quantity: 1})
+	assert.ErrorIs(t, err, ErrNotInStocktake)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "status" FROM "stocktakes"`)).
+		WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.StocktakeFinalized))
+	mock.ExpectRollback()
+
+	_, err = repo.AddCount(3, models.StocktakeCount{BookID: 2, Quantity: 1})
+	assert.ErrorIs(t, err, ErrStocktakeFinalized)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFinalize(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	repo := NewStocktakesPostgres(books.db)
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		location        interface{}
+		mockBehavior    func()
+		expectedChanges []models.StockChange
+	}{
+		{
+			// Book 2 was counted 2 short of 6 while 1 of 4 left at the location was sold.
+			name:     "At location",
+			location: 4,
+			mockBehavior: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stock_levels" WHERE book_id = $1 AND location_id = $2 LIMIT 1 FOR UPDATE`)).
+					WithArgs(2, 4).WillReturnRows(sqlmock.NewRows([]string{"book_id", "location_id", "amount"}).AddRow(2, 4, 3))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "stock_levels"`)).
+					WithArgs(2, 4, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "stocktake_adjustments" ("stocktake_id","book_id","expected","counted","previous_amount","amount","created_at")`)).
+					WithArgs(3, 2, 4, 2, 3, 1, at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "amount"=$1 WHERE id = $2`)).
+					WithArgs(8, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 2, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			expectedChanges: []models.StockChange{{PreviousAmount: 10, Book: models.Book{ID: 2, Amount: 8}}},
+		},
+		{
+			name:     "Amount",
+			location: nil,
+			mockBehavior: func() {
+				expectTakeStock(mock, 2, 10, 2)
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "stocktake_adjustments"`)).
+					WithArgs(3, 2, 4, 2, 10, 8, at).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "books" SET "amount"=$1 WHERE id = $2`)).
+					WithArgs(8, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("INSERT INTO \"outbox\"").
+					WithArgs(models.AggregateBook, 2, models.EventBookUpdated, sqlmock.AnyArg(), nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			expectedChanges: []models.StockChange{{PreviousAmount: 10, Book: models.Book{ID: 2, Amount: 8}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stocktakes" WHERE "stocktakes"."id" = $1 ORDER BY "stocktakes"."id" LIMIT 1 FOR UPDATE`)).
+				WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id", "genre", "location_id", "status"}).
+				AddRow(3, 0, test.location, models.StocktakeOpen))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "stocktake_items" WHERE stocktake_id = $1 AND counted IS NOT NULL AND counted <> expected ORDER BY book_id`)).
+				WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"stocktake_id", "book_id", "expected", "counted"}).AddRow(3, 2, 4, 2))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR UPDATE`)).
+				WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(2, 10))
+			test.mockBehavior()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "stocktakes" SET "finalized_at"=$1,"status"=$2 WHERE id = $3`)).
+				WithArgs(at, models.StocktakeFinalized, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			changes, err := repo.Finalize(3, at)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedChanges, changes)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+)
+
+type Suppliers interface {
+	GetSuppliers() ([]models.Supplier, error)
+	GetSupplierByID(id int) (models.Supplier, error)
+	CreateSupplier(supplier models.Supplier) (int, error)
+	UpdateSupplier(id int, supplier models.Supplier) error
+}
+
+type SuppliersPostgres struct {
+	db *gorm.DB
+}
+
+func NewSuppliersPostgres(db *gorm.DB) *SuppliersPostgres {
+	return &SuppliersPostgres{db: db}
+}
+
+func (r *SuppliersPostgres) GetSuppliers() ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+	err := r.db.Order("id").Find(&suppliers).Error
+	return suppliers, err
+}
+
+func (r *SuppliersPostgres) GetSupplierByID(id int) (models.Supplier, error) {
+	var supplier models.Supplier
+	err := r.db.First(&supplier, id).Error
+	return supplier, err
+}
+
+func (r *SuppliersPostgres) CreateSupplier(supplier models.Supplier) (int, error) {
+	err := r.db.Select("name", "email").Create(&supplier).Error
+	return supplier.ID, err
+}
+
+func (r *SuppliersPostgres) UpdateSupplier(id int, supplier models.Supplier) error {
+	res := r.db.Model(&models.Supplier{}).Where("id = ?", id).Select("name", "email").Updates(supplier)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
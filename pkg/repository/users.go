@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/jackc/pgconn"
+	"gorm.io/gorm"
+)
+
+const uniqueViolation = "23505"
+
+var ErrUserExists = errors.New("user already exists")
+
+type Users interface {
+	CreateUser(user models.User) (models.User, error)
+}
+
+type UsersPostgres struct {
+	db *gorm.DB
+}
+
+func NewUsersPostgres(db *gorm.DB) *UsersPostgres {
+	return &UsersPostgres{db: db}
+}
+
+// CreateUser stores the user, ErrUserExists is returned when the email is taken.
+func (r *UsersPostgres) CreateUser(user models.User) (models.User, error) {
+	err := r.db.Select("email", "name").Create(&user).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return user, ErrUserExists
+	}
+	return user, err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestCreateUser(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	r := NewUsersPostgres(books.db)
+	insert := regexp.QuoteMeta(`INSERT INTO "users" ("email","name","created_at") VALUES ($1,$2,$3) RETURNING "id"`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insert).WithArgs("ada@books.local", "Ada", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	user, err := r.CreateUser(models.User{Email: "ada@books.local", Name: "Ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insert).WithArgs("ada@books.local", "", sqlmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505", Message: "duplicate key"})
+	mock.ExpectRollback()
+
+	_, err = r.CreateUser(models.User{Email: "ada@books.local"})
+	assert.ErrorIs(t, err, ErrUserExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrBookNotFound = errors.New("book not found")
+
+type Waitlist interface {
+	AddToWaitlist(entry models.WaitlistEntry) (int, error)
+	GetPendingEntries(bookID, maxAttempts int) ([]models.WaitlistEntry, error)
+	GetBooksInStockWithPendingEntries(maxAttempts int) ([]models.Book, error)
+	MarkNotified(id int, at time.Time) error
+	RecordFailure(id int) error
+}
+
+type WaitlistPostgres struct {
+	db *gorm.DB
+}
+
+func NewWaitlistPostgres(db *gorm.DB) *WaitlistPostgres {
+	return &WaitlistPostgres{db: db}
+}
+
+// AddToWaitlist adds the entry to the waitlist of its book, ErrBookNotFound is returned for a missing book.
+func (r *WaitlistPostgres) AddToWaitlist(entry models.WaitlistEntry) (int, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// The book is locked against deletion until the entry is stored.
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id").First(&models.Book{}, entry.BookID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrBookNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Select("book_id", "email", "url", "secret").Create(&entry).Error
+	})
+	return entry.ID, err
+}
+
+// GetPendingEntries returns the entries of the book that were not notified yet and failed less than
+// maxAttempts times, in the order they joined.
+func (r *WaitlistPostgres) GetPendingEntries(bookID, maxAttempts int) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	err := r.db.Where("book_id = ? AND notified_at IS NULL AND attempts < ?", bookID, maxAttempts).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// GetBooksInStockWithPendingEntries returns the books in stock that still have entries to notify.
+func (r *WaitlistPostgres) GetBooksInStockWithPendingEntries(maxAttempts int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("amount > 0 AND EXISTS (?)",
+		r.db.Model(&models.WaitlistEntry{}).Select("1").
+			Where("book_id = books.id AND notified_at IS NULL AND attempts < ?", maxAttempts)).
+		Order("id").Find(&books).Error
+	return books, err
+}
+
+func (r *WaitlistPostgres) MarkNotified(id int, at time.Time) error {
+	return r.db.Model(&models.WaitlistEntry{}).Where("id = ?", id).Update("notified_at", at).Error
+}
+
+// RecordFailure counts a failed notification of the entry.
+func (r *WaitlistPostgres) RecordFailure(id int) error {
+	return r.db.Model(&models.WaitlistEntry{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
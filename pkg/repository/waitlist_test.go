@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestAddToWaitlist(t *testing.T) {
+	books, mock, err := MockDB()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	r := NewWaitlistPostgres(books.db)
+	lockBook := regexp.QuoteMeta(`SELECT "id" FROM "books" WHERE "books"."id" = $1 ORDER BY "books"."id" LIMIT 1 FOR SHARE`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockBook).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "waitlist_entries" ("book_id","email","url","secret","created_at") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)).
+		WithArgs(2, "", "https://example.com/hook", "s3cret", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	id, err := r.AddToWaitlist(models.WaitlistEntry{BookID: 2, URL: "https://example.com/hook", Secret: "s3cret"})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockBook).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	_, err = r.AddToWaitlist(models.WaitlistEntry{BookID: 3, Email: "reader@books.local"})
+	assert.ErrorIs(t, err, ErrBookNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"gorm.io/gorm"
+)
+
+type Webhooks interface {
+	CreateSubscription(subscription models.WebhookSubscription) (int, error)
+	GetSubscriptions() ([]models.WebhookSubscription, error)
+	GetSubscriptionByID(id int) (models.WebhookSubscription, error)
+	GetActiveSubscriptions(eventType string) ([]models.WebhookSubscription, error)
+	UpdateSubscription(id int, subscription models.WebhookSubscription) error
+	DeleteSubscription(id int) error
+	RecordFailure(id int, maxFailures int) error
+	ResetFailures(id int) error
+	CreateDelivery(delivery models.WebhookDelivery) error
+	GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error)
+}
+
+type WebhooksPostgres struct {
+	db *gorm.DB
+}
+
+func NewWebhooksPostgres(db *gorm.DB) *WebhooksPostgres {
+	return &WebhooksPostgres{db: db}
+}
+
+func (r *WebhooksPostgres) CreateSubscription(subscription models.WebhookSubscription) (int, error) {
+	err := r.db.Select("url", "events", "secret", "active").Create(&subscription).Error
+	return subscription.ID, err
+}
+
+func (r *WebhooksPostgres) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *WebhooksPostgres) GetSubscriptionByID(id int) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.First(&subscription, id).Error
+	return subscription, err
+}
+
+func (r *WebhooksPostgres) GetActiveSubscriptions(eventType string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("active AND ? = ANY(string_to_array(events, ','))", eventType).
+		Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateSubscription changes url, events and, when given, active flag. Setting the flag resets the failures.
+func (r *WebhooksPostgres) UpdateSubscription(id int, subscription models.WebhookSubscription) error {
+	values := map[string]interface{}{
+		"url":    subscription.URL,
+		"events": subscription.Events,
+	}
+	if subscription.Active != nil {
+		values["active"] = *subscription.Active
+		values["failure_count"] = 0
+	}
+	res := r.db.Model(&models.WebhookSubscription{}).Where("id = ?", id).Updates(values)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WebhooksPostgres) DeleteSubscription(id int) error {
+	res := r.db.Delete(&models.WebhookSubscription{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RecordFailure counts a failed delivery and disables the subscription after maxFailures in a row.
+func (r *WebhooksPostgres) RecordFailure(id int, maxFailures int) error {
+	return r.db.Model(&models.WebhookSubscription{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"failure_count": gorm.Expr("failure_count + 1"),
+			"active":        gorm.Expr("active AND failure_count + 1 < ?", maxFailures),
+		}).Error
+}
+
+func (r *WebhooksPostgres) ResetFailures(id int) error {
+	return r.db.Model(&models.WebhookSubscription{}).Where("id = ?", id).
+		Update("failure_count", 0).Error
+}
+
+func (r *WebhooksPostgres) CreateDelivery(delivery models.WebhookDelivery) error {
+	return r.db.Omit("id", "created_at").Create(&delivery).Error
+}
+
+func (r *WebhooksPostgres) GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("subscription_id = ?", subscriptionID).Order("id DESC").Limit(100).
+		Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/rpc/pb"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+type BookServer struct {
+	pb.UnimplementedBookServiceServer
+	books service.BooksManager
+}
+
+func (s *BookServer) ListBooks(_ context.Context, req *pb.ListBooksRequest) (*pb.ListBooksResponse, error) {
+	filterCondition := map[string][]string{}
+	if req.Genre != 0 {
+		if req.Genre < 1 || req.Genre > 3 {
+			return nil, status.Error(codes.InvalidArgument, "invalid filter condition")
+		}
+		filterCondition["genre"] = []string{strconv.Itoa(int(req.Genre))}
+	}
+	if req.Availability != "" {
+		if !models.ValidAvailability(req.Availability) {
+			return nil, status.Error(codes.InvalidArgument, "invalid filter condition")
+		}
+		filterCondition["availability"] = []string{req.Availability}
+	}
+	if req.LocationId != 0 {
+		if req.LocationId < 1 {
+			return nil, status.Error(codes.InvalidArgument, "invalid filter condition")
+		}
+		filterCondition["location"] = []string{strconv.FormatInt(req.LocationId, 10)}
+	}
+	if req.Sort != "" {
+		if req.Sort != models.SortRating {
+			return nil, status.Error(codes.InvalidArgument, "invalid sort")
+		}
+		filterCondition["sort"] = []string{req.Sort}
+	}
+	books, err := s.books.GetBooks(filterCondition)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListBooksResponse{Books: make([]*pb.Book, 0, len(books))}
+	for _, book := range books {
+		resp.Books = append(resp.Books, toProtoBook(book))
+	}
+	return resp, nil
+}
+
+func (s *BookServer) GetBook(_ context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	book, err := s.books.GetBookByID(int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoBook(book), nil
+}
+
+func (s *BookServer) CreateBook(_ context.Context, req *pb.CreateBookRequest) (*pb.CreateBookResponse, error) {
+	book, err := fromProtoBook(req.Book)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.books.CreateBook(book)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.CreateBookResponse{Id: int64(id)}, nil
+}
+
+func (s *BookServer) UpdateBook(_ context.Context, req *pb.UpdateBookRequest) (*pb.Book, error) {
+	book, err := fromProtoBook(req.Book)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.books.UpdateBookByID(int(req.Id), book); err != nil {
+		return nil, toStatus(err)
+	}
+	book.ID = int(req.Id)
+	return toProtoBook(book.WithDefaults()), nil
+}
+
+func (s *BookServer) DeleteBook(_ context.Context, req *pb.DeleteBookRequest) (*pb.DeleteBookResponse, error) {
+	if err := s.books.DeleteBookByID(int(req.Id)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteBookResponse{}, nil
+}
+
+func toProtoBook(book models.Book) *pb.Book {
+	msg := &pb.Book{
+		Id:               int64(book.ID),
+		Name:             book.Name,
+		Author:           book.Author,
+		Price:            book.Price.String(),
+		Currency:         book.Currency,
+		Genre:            int32(book.Genre),
+		Amount:           int32(book.Amount),
+		ReorderThreshold: int32(book.ReorderThreshold),
+		Available:        book.InStock(),
+		AvgRating:        book.AvgRating,
+		ReviewCount:      int32(book.ReviewCount),
+	}
+	if book.EffectivePrice != nil {
+		msg.EffectivePrice = book.EffectivePrice.String()
+	}
+	return msg
+}
+
+// fromProtoBook converts the message and checks the same constraints as the REST handlers.
+func fromProtoBook(msg *pb.Book) (models.Book, error) {
+	if msg == nil {
+		return models.Book{}, status.Error(codes.InvalidArgument, "invalid input")
+	}
+	price, err := models.ParseMoney(msg.Price)
+	if err != nil {
+		return models.Book{}, status.Error(codes.InvalidArgument, "invalid input")
+	}
+	book := models.Book{
+		Name:             msg.Name,
+		Author:           msg.Author,
+		Price:            price,
+		Currency:         msg.Currency,
+		Genre:            int(msg.Genre),
+		Amount:           int(msg.Amount),
+		ReorderThreshold: int(msg.ReorderThreshold),
+	}
+	if err = binding.Validator.ValidateStruct(book); err != nil {
+		return book, status.Error(codes.InvalidArgument, "invalid input")
+	}
+	return book, nil
+}
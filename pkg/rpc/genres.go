@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/pkg/rpc/pb"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+)
+
+type GenreServer struct {
+	pb.UnimplementedGenreServiceServer
+	genres service.Genres
+}
+
+func (s *GenreServer) ListGenres(_ context.Context, _ *pb.ListGenresRequest) (*pb.ListGenresResponse, error) {
+	genres, err := s.genres.GetGenres()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListGenresResponse{Genres: make([]*pb.Genre, 0, len(genres))}
+	for _, genre := range genres {
+		resp.Genres = append(resp.Genres, &pb.Genre{Id: int64(genre.ID), Name: genre.Name})
+	}
+	return resp, nil
+}
+
+func (s *GenreServer) GetGenre(_ context.Context, req *pb.GetGenreRequest) (*pb.Genre, error) {
+	genre, err := s.genres.GetGenreByID(int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Genre{Id: int64(genre.ID), Name: genre.Name}, nil
+}
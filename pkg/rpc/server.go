@@ -0,0 +1,39 @@
+package rpc
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/pkg/rpc/pb"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	"github.com/jackc/pgconn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const uniqueViolation = "23505"
+
+// NewServer returns a gRPC server exposing the same services as the REST handlers.
+func NewServer(services *service.Service) *grpc.Server {
+	srv := grpc.NewServer()
+	pb.RegisterBookServiceServer(srv, &BookServer{books: services.BooksManager})
+	pb.RegisterGenreServiceServer(srv, &GenreServer{genres: services.Genres})
+	reflection.Register(srv)
+	return srv
+}
+
+// toStatus maps service errors to gRPC status codes.
+func toStatus(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, service.ErrUnknownCurrency):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		return status.Error(codes.AlreadyExists, pgErr.Message)
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,168 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/rpc/pb"
+	"github.com/TenderLimbo/rest-api/pkg/service"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+	"net"
+	"testing"
+)
+
+func dial(t *testing.T, services *service.Service) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := NewServer(services)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %s", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestGetBook(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockBooksManager, id int)
+	tests := []struct {
+		name         string
+		inputId      int64
+		mockBehavior mockBehavior
+		expectedBook *pb.Book
+		expectedCode codes.Code
+	}{
+		{
+			name:    "Ok",
+			inputId: 1,
+			mockBehavior: func(s *mock_service.MockBooksManager, id int) {
+				s.EXPECT().GetBookByID(id).Return(models.Book{ID: 1, Name: "hello", Price: 432, Currency: "USD", Genre: 2, Amount: 9}, nil)
+			},
+			expectedBook: &pb.Book{Id: 1, Name: "hello", Price: "4.32", Currency: "USD", Genre: 2, Amount: 9, Available: true},
+			expectedCode: codes.OK,
+		},
+		{
+			name:    "Not found",
+			inputId: 2,
+			mockBehavior: func(s *mock_service.MockBooksManager, id int) {
+				s.EXPECT().GetBookByID(id).Return(models.Book{}, gorm.ErrRecordNotFound)
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:    "Internal error",
+			inputId: 3,
+			mockBehavior: func(s *mock_service.MockBooksManager, id int) {
+				s.EXPECT().GetBookByID(id).Return(models.Book{}, errors.New("connection refused"))
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			test.mockBehavior(mockManager, int(test.inputId))
+			client := pb.NewBookServiceClient(dial(t, &service.Service{BooksManager: mockManager}))
+
+			book, err := client.GetBook(context.Background(), &pb.GetBookRequest{Id: test.inputId})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedBook != nil {
+				assert.Equal(t, test.expectedBook.String(), book.String())
+			}
+		})
+	}
+}
+
+func TestCreateBook(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockBooksManager, book models.Book)
+	tests := []struct {
+		name         string
+		inputBook    *pb.Book
+		mockBehavior mockBehavior
+		expectedId   int64
+		expectedCode codes.Code
+	}{
+		{
+			name:      "Ok",
+			inputBook: &pb.Book{Name: "hello", Price: "67.88", Genre: 1, Amount: 7},
+			mockBehavior: func(s *mock_service.MockBooksManager, book models.Book) {
+				s.EXPECT().CreateBook(book).Return(5, nil)
+			},
+			expectedId:   5,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Invalid genre",
+			inputBook:    &pb.Book{Name: "hello", Price: "67.88", Genre: 6, Amount: 7},
+			mockBehavior: func(s *mock_service.MockBooksManager, book models.Book) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Invalid price",
+			inputBook:    &pb.Book{Name: "hello", Price: "67.885", Genre: 1, Amount: 7},
+			mockBehavior: func(s *mock_service.MockBooksManager, book models.Book) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:      "Not unique name",
+			inputBook: &pb.Book{Name: "hello", Price: "67.88", Genre: 1, Amount: 7},
+			mockBehavior: func(s *mock_service.MockBooksManager, book models.Book) {
+				s.EXPECT().CreateBook(book).Return(0, &pgconn.PgError{Code: "23505", Message: "duplicate key"})
+			},
+			expectedCode: codes.AlreadyExists,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			price, _ := models.ParseMoney(test.inputBook.Price)
+			test.mockBehavior(mockManager, models.Book{
+				Name:   test.inputBook.Name,
+				Price:  price,
+				Genre:  int(test.inputBook.Genre),
+				Amount: int(test.inputBook.Amount),
+			})
+			client := pb.NewBookServiceClient(dial(t, &service.Service{BooksManager: mockManager}))
+
+			resp, err := client.CreateBook(context.Background(), &pb.CreateBookRequest{Book: test.inputBook})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			assert.Equal(t, test.expectedId, resp.GetId())
+		})
+	}
+}
+
+func TestListGenres(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockGenres := mock_service.NewMockGenres(c)
+	mockGenres.EXPECT().GetGenres().Return([]models.Genre{{ID: 1, Name: "adventure"}, {ID: 2, Name: "classics"}}, nil)
+	client := pb.NewGenreServiceClient(dial(t, &service.Service{Genres: mockGenres}))
+
+	resp, err := client.ListGenres(context.Background(), &pb.ListGenresRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Genres, 2) {
+		assert.Equal(t, "classics", resp.Genres[1].Name)
+	}
+}
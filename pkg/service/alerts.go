@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/mail"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"strings"
+)
+
+// LowStockNotifier tells staff that a book fell to its reorder threshold.
+type LowStockNotifier interface {
+	NotifyLowStock(book models.Book) error
+}
+
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyLowStock(book models.Book) error {
+	logging.Warnf("alerts: book %d %q is low on stock, %d left, reorder threshold %d",
+		book.ID, book.Name, book.Amount, book.ReorderThreshold)
+	return nil
+}
+
+type EmailNotifier struct {
+	sender mail.Sender
+	from   string
+	to     []string
+}
+
+func NewEmailNotifier(sender mail.Sender, from string, to []string) *EmailNotifier {
+	return &EmailNotifier{sender: sender, from: from, to: to}
+}
+
+func (n *EmailNotifier) NotifyLowStock(book models.Book) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Book %d \"%s\" is low on stock.\n\n", book.ID, book.Name)
+	fmt.Fprintf(&body, "Amount: %d\nReorder threshold: %d\n", book.Amount, book.ReorderThreshold)
+	return n.sender.Send(mail.Message{
+		From:    n.from,
+		To:      n.to,
+		Subject: fmt.Sprintf("Low stock: %s", book.Name),
+		Body:    body.String(),
+	})
+}
+
+// AlertsService passes low stock events to the notifiers in the background. Webhook
+// subscribers get the same events through the book.low_stock event type.
+type AlertsService struct {
+	notifiers []LowStockNotifier
+	events    chan models.Event
+	done      chan struct{}
+}
+
+func NewAlertsService(queueSize int, notifiers ...LowStockNotifier) *AlertsService {
+	return &AlertsService{notifiers: notifiers, events: make(chan models.Event, queueSize), done: make(chan struct{})}
+}
+
+// HandleEvent queues low stock events, they are dropped when the queue is full.
+func (s *AlertsService) HandleEvent(event models.Event) {
+	if event.Type != models.EventBookLowStock || event.Book == nil {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		logging.Warnf("alerts: queue is full, low stock alert of book %d dropped", event.BookID)
+	}
+}
+
+// Start sends the alerts until ctx is done.
+func (s *AlertsService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-s.events:
+				for _, notifier := range s.notifiers {
+					if err := notifier.NotifyLowStock(*event.Book); err != nil {
+						logging.Errorf("alerts: low stock alert of book %d: %s", event.BookID, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until the loop started by Start returns.
+func (s *AlertsService) Wait() {
+	<-s.done
+}
+
+type InventoryService struct {
+	repo repository.Inventory
+}
+
+func NewInventoryService(repo repository.Inventory) *InventoryService {
+	return &InventoryService{repo: repo}
+}
+
+func (s *InventoryService) GetLowStockBooks() ([]models.Book, error) {
+	return s.repo.GetLowStockBooks()
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/mail"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLowStockAlerts(t *testing.T) {
+	tests := []struct {
+		name           string
+		previousAmount int
+		book           models.Book
+		expectedAlerts int
+	}{
+		{
+			name:           "Crossed threshold",
+			previousAmount: 5,
+			book:           models.Book{Name: "hello", Price: 432, Genre: 2, Amount: 2, ReorderThreshold: 3},
+			expectedAlerts: 1,
+		},
+		{
+			name:           "Already below threshold",
+			previousAmount: 3,
+			book:           models.Book{Name: "hello", Price: 432, Genre: 2, Amount: 1, ReorderThreshold: 3},
+		},
+		{
+			name:           "Above threshold",
+			previousAmount: 8,
+			book:           models.Book{Name: "hello", Price: 432, Genre: 2, Amount: 4, ReorderThreshold: 3},
+		},
+		{
+			name:           "Out of stock",
+			previousAmount: 1,
+			book:           models.Book{Name: "hello", Price: 432, Genre: 2, Amount: 0},
+			expectedAlerts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			book := test.book
+			book.ID = 1
+			mockManager.EXPECT().UpdateBookByID(1, gomock.Any()).
+				Return(models.StockChange{PreviousAmount: test.previousAmount, Book: book}, nil)
+
+			sender := mail.NewFake()
+			alerts := NewAlertsService(10, NewEmailNotifier(sender, "shop@example.com", []string{"staff@example.com"}))
+			s := NewService(mockManager)
+			s.Subscribe(alerts)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			alerts.Start(ctx)
+			_, err := s.UpdateBookByID(1, test.book)
+			assert.NoError(t, err)
+			deadline := time.Now().Add(time.Second)
+			for len(sender.Messages()) < test.expectedAlerts && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			alerts.Wait()
+
+			messages := sender.Messages()
+			if assert.Len(t, messages, test.expectedAlerts) && test.expectedAlerts > 0 {
+				assert.Equal(t, []string{"staff@example.com"}, messages[0].To)
+				assert.Equal(t, "Low stock: hello", messages[0].Subject)
+			}
+		})
+	}
+}
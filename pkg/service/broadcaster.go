@@ -0,0 +1,96 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"sync"
+)
+
+// Broadcaster fans book events out to stream subscribers and keeps the latest
+// of them so that reconnecting clients can resume from the last event they saw.
+type Broadcaster struct {
+	mu          sync.Mutex
+	lastID      int64
+	buffer      []models.StreamEvent
+	bufferSize  int
+	queueSize   int
+	subscribers map[chan models.StreamEvent]struct{}
+	closed      bool
+}
+
+func NewBroadcaster(bufferSize, queueSize int) *Broadcaster {
+	return &Broadcaster{
+		bufferSize:  bufferSize,
+		queueSize:   queueSize,
+		subscribers: make(map[chan models.StreamEvent]struct{}),
+	}
+}
+
+// HandleEvent numbers the event and sends it to every subscriber. A subscriber that
+// does not keep up is disconnected, it resumes from the replay buffer on reconnect.
+func (b *Broadcaster) HandleEvent(event models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	streamEvent := models.StreamEvent{ID: b.lastID, Event: event}
+	if b.bufferSize > 0 {
+		if len(b.buffer) == b.bufferSize {
+			b.buffer = b.buffer[1:]
+		}
+		b.buffer = append(b.buffer, streamEvent)
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- streamEvent:
+		default:
+			logging.Warnf("stream: subscriber is too slow, disconnected at event %d", streamEvent.ID)
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the buffered events after lastEventID and a channel of the events
+// that follow them, the channel is closed when the subscriber is dropped. A zero
+// lastEventID starts from new events only. An ID the broadcaster has not issued yet
+// comes from before a restart, all buffered events are replayed then.
+func (b *Broadcaster) Subscribe(lastEventID int64) ([]models.StreamEvent, <-chan models.StreamEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var replay []models.StreamEvent
+	if lastEventID > b.lastID {
+		replay = append(replay, b.buffer...)
+	} else if lastEventID > 0 {
+		for _, event := range b.buffer {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	ch := make(chan models.StreamEvent, b.queueSize)
+	if b.closed {
+		close(ch)
+		return replay, ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+	return replay, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends all subscriptions, open streams would otherwise hold up a graceful shutdown.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
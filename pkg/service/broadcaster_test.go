@@ -0,0 +1,64 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func streamEventIDs(events []models.StreamEvent) []int64 {
+	var ids []int64
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestBroadcasterReplay(t *testing.T) {
+	tests := []struct {
+		name           string
+		lastEventID    int64
+		expectedReplay []int64
+	}{
+		{name: "New client", lastEventID: 0},
+		{name: "Resume", lastEventID: 3, expectedReplay: []int64{4, 5}},
+		{name: "Up to date", lastEventID: 5},
+		{name: "Evicted events", lastEventID: 1, expectedReplay: []int64{3, 4, 5}},
+		{name: "Before restart", lastEventID: 100, expectedReplay: []int64{3, 4, 5}},
+	}
+	b := NewBroadcaster(3, 1)
+	for i := 1; i <= 5; i++ {
+		b.HandleEvent(models.Event{Type: models.EventBookUpdated, BookID: i})
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replay, _, cancel := b.Subscribe(test.lastEventID)
+			defer cancel()
+			assert.Equal(t, test.expectedReplay, streamEventIDs(replay))
+		})
+	}
+}
+
+func TestBroadcasterSubscribers(t *testing.T) {
+	b := NewBroadcaster(10, 1)
+	_, fast, cancel := b.Subscribe(0)
+	_, slow, _ := b.Subscribe(0)
+
+	b.HandleEvent(models.Event{Type: models.EventBookCreated, BookID: 1})
+	assert.Equal(t, int64(1), (<-fast).ID)
+	b.HandleEvent(models.Event{Type: models.EventBookUpdated, BookID: 1})
+	assert.Equal(t, int64(2), (<-fast).ID)
+
+	assert.Equal(t, int64(1), (<-slow).ID)
+	_, ok := <-slow
+	assert.False(t, ok, "slow subscriber must be disconnected")
+
+	cancel()
+	_, ok = <-fast
+	assert.False(t, ok)
+
+	_, closed, _ := b.Subscribe(0)
+	b.Close()
+	_, ok = <-closed
+	assert.False(t, ok)
+}
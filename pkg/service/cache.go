@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/cache"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"golang.org/x/sync/singleflight"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const booksVersionKey = "books:version"
+
+var cacheStats = expvar.NewMap("books_cache")
+
+// CacheInvalidator is implemented by book managers that cache reads.
+type CacheInvalidator interface {
+	Invalidate(ids ...int)
+}
+
+// CachedBooksManager caches book reads of the wrapped BooksManager and invalidates them on writes.
+type CachedBooksManager struct {
+	next  BooksManager
+	cache cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+func NewCachedBooksManager(next BooksManager, cache cache.Cache, ttl time.Duration) *CachedBooksManager {
+	return &CachedBooksManager{next: next, cache: cache, ttl: ttl}
+}
+
+// cachedBook is a book as it is cached, with the fields the API leaves out of its JSON.
+type cachedBook struct {
+	models.Book
+	Cover string `json:"cover,omitempty"`
+}
+
+func newCachedBook(book models.Book) cachedBook {
+	return cachedBook{Book: book, Cover: book.Cover}
+}
+
+func (b cachedBook) book() models.Book {
+	b.Book.Cover = b.Cover
+	return b.Book
+}
+
+func (s *CachedBooksManager) GetBooks(filterCondition map[string][]string) ([]models.Book, error) {
+	key := "books:" + s.listVersion() + ":" + url.Values(filterCondition).Encode()
+	var cached []cachedBook
+	err := s.read(key, &cached, func() (interface{}, error) {
+		books, err := s.next.GetBooks(filterCondition)
+		if err != nil {
+			return nil, err
+		}
+		cached := make([]cachedBook, len(books))
+		for i, book := range books {
+			cached[i] = newCachedBook(book)
+		}
+		return cached, nil
+	})
+	if err != nil || cached == nil {
+		return nil, err
+	}
+	books := make([]models.Book, len(cached))
+	for i, book := range cached {
+		books[i] = book.book()
+	}
+	return books, nil
+}
+
+func (s *CachedBooksManager) GetBookByID(id int) (models.Book, error) {
+	var cached cachedBook
+	err := s.read(bookKey(id), &cached, func() (interface{}, error) {
+		book, err := s.next.GetBookByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return newCachedBook(book), nil
+	})
+	return cached.book(), err
+}
+
+func (s *CachedBooksManager) CreateBook(book models.Book) (int, error) {
+	id, err := s.next.CreateBook(book)
+	if err == nil {
+		s.invalidate()
+	}
+	return id, err
+}
+
+func (s *CachedBooksManager) DeleteBookByID(id int) error {
+	err := s.next.DeleteBookByID(id)
+	if err == nil {
+		s.invalidate(bookKey(id))
+	}
+	return err
+}
+
+func (s *CachedBooksManager) UpdateBookByID(id int, book models.Book) (models.StockChange, error) {
+	change, err := s.next.UpdateBookByID(id, book)
+	if err == nil {
+		s.invalidate(bookKey(id))
+	}
+	return change, err
+}
+
+// read decodes the cached value of key into dst. Concurrent misses of the same key share one load.
+func (s *CachedBooksManager) read(key string, dst interface{}, load func() (interface{}, error)) error {
+	ctx := context.Background()
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		logging.Errorf("cache: %s", err)
+	}
+	if ok && json.Unmarshal(data, dst) == nil {
+		cacheStats.Add("hits", 1)
+		return nil
+	}
+	cacheStats.Add("misses", 1)
+
+	loaded, err, _ := s.group.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.cache.Set(ctx, key, data, s.ttl); err != nil {
+			logging.Errorf("cache: %s", err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(loaded.([]byte), dst)
+}
+
+// Invalidate drops the cached reads of the books after changes made around the cache.
+func (s *CachedBooksManager) Invalidate(ids ...int) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, bookKey(id))
+	}
+	s.invalidate(keys...)
+}
+
+// invalidate drops the given keys and switches book lists to a new version.
+func (s *CachedBooksManager) invalidate(keys ...string) {
+	ctx := context.Background()
+	version := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := s.cache.Set(ctx, booksVersionKey, version, 0); err != nil {
+		logging.Errorf("cache: %s", err)
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		logging.Errorf("cache: %s", err)
+	}
+}
+
+func (s *CachedBooksManager) listVersion() string {
+	version, ok, err := s.cache.Get(context.Background(), booksVersionKey)
+	if err != nil || !ok {
+		return "0"
+	}
+	return string(version)
+}
+
+func bookKey(id int) string {
+	return "book:" + strconv.Itoa(id)
+}
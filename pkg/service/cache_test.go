@@ -0,0 +1,102 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/cache"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedBooksManager(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	book := models.Book{ID: 1, Name: "hello", Price: 432, Genre: 2, Amount: 9}
+	mockManager := mock_service.NewMockBooksManager(c)
+	s := NewCachedBooksManager(mockManager, cache.NewLRU(10), time.Minute)
+
+	mockManager.EXPECT().GetBookByID(1).Return(book, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		got, err := s.GetBookByID(1)
+		assert.NoError(t, err)
+		assert.Equal(t, book, got)
+	}
+
+	filter := map[string][]string{"genre": {"2"}}
+	mockManager.EXPECT().GetBooks(filter).Return([]models.Book{book}, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		got, err := s.GetBooks(filter)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Book{book}, got)
+	}
+
+	updated := book
+	updated.Amount = 3
+	mockManager.EXPECT().UpdateBookByID(1, updated).Return(models.StockChange{PreviousAmount: 9, Book: updated}, nil)
+	_, err := s.UpdateBookByID(1, updated)
+	assert.NoError(t, err)
+
+	mockManager.EXPECT().GetBookByID(1).Return(updated, nil).Times(1)
+	got, err := s.GetBookByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+
+	mockManager.EXPECT().GetBooks(filter).Return([]models.Book{updated}, nil).Times(1)
+	list, err := s.GetBooks(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Book{updated}, list)
+}
+
+func TestCachedBooksManagerKeepsCover(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	book := models.Book{ID: 1, Name: "hello", Price: 432, Genre: 2, Amount: 9, Cover: "0123456789abcdef.png"}
+	mockManager := mock_service.NewMockBooksManager(c)
+	s := NewCachedBooksManager(mockManager, cache.NewLRU(10), time.Minute)
+
+	mockManager.EXPECT().GetBookByID(1).Return(book, nil).Times(1)
+	mockManager.EXPECT().GetBooks(gomock.Any()).Return([]models.Book{book}, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		got, err := s.GetBookByID(1)
+		assert.NoError(t, err)
+		assert.Equal(t, "0123456789abcdef.png", got.Cover)
+
+		list, err := s.GetBooks(map[string][]string{})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Book{book}, list)
+	}
+}
+
+func TestCachedBooksManagerCollapsesMisses(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	book := models.Book{ID: 1, Name: "hello", Price: 432, Genre: 2, Amount: 9}
+	mockManager := mock_service.NewMockBooksManager(c)
+	s := NewCachedBooksManager(mockManager, cache.NewLRU(10), time.Minute)
+
+	release := make(chan struct{})
+	mockManager.EXPECT().GetBookByID(1).DoAndReturn(func(id int) (models.Book, error) {
+		<-release
+		return book, nil
+	}).Times(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.GetBookByID(1)
+			assert.NoError(t, err)
+			assert.Equal(t, book, got)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
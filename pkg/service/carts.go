@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"time"
+)
+
+var (
+	ErrInsufficientStock = repository.ErrInsufficientStock
+	ErrCartNotOpen       = repository.ErrCartNotOpen
+	ErrCartEmpty         = repository.ErrCartEmpty
+	ErrCartFull          = repository.ErrCartFull
+	ErrInvalidCartToken  = errors.New("invalid cart token")
+)
+
+type CartsConfig struct {
+	HoldTTL        time.Duration `mapstructure:"hold_ttl"`
+	ReaperInterval time.Duration `mapstructure:"reaper_interval"`
+	// MaxQuantity is the number of books a cart may hold, counting all its items.
+	MaxQuantity int `mapstructure:"max_quantity"`
+}
+
+// CartsService holds books for customers and releases the holds nobody checked out in time.
+// Customers use their carts with the token they get on creation.
+type CartsService struct {
+	repo  repository.Carts
+	stock StockObserver
+	clock clock.Clock
+	cfg   CartsConfig
+	// wake tells the reaper a hold was set, it may expire before the reaper meant to run.
+	wake chan struct{}
+	done chan struct{}
+}
+
+func NewCartsService(repo repository.Carts, stock StockObserver, clock clock.Clock, cfg CartsConfig) *CartsService {
+	return &CartsService{repo: repo, stock: stock, clock: clock, cfg: cfg, wake: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+func (s *CartsService) CreateCart() (models.Cart, error) {
+	cart := models.Cart{Status: models.CartOpen}
+	var err error
+	if cart.Token, cart.TokenHash, err = newToken(); err != nil {
+		return cart, err
+	}
+	cart.ID, err = s.repo.CreateCart(cart.TokenHash)
+	return cart, err
+}
+
+func (s *CartsService) GetCartByID(id int, token string) (models.Cart, error) {
+	return s.checkToken(id, token)
+}
+
+func (s *CartsService) DeleteCart(id int, token string) error {
+	if _, err := s.checkToken(id, token); err != nil {
+		return err
+	}
+	return s.repo.DeleteCart(id)
+}
+
+// SetCartItem holds the books for the hold TTL, setting an item again extends its hold.
+func (s *CartsService) SetCartItem(cartID int, token string, item models.CartItem) (models.CartItem, error) {
+	if _, err := s.checkToken(cartID, token); err != nil {
+		return item, err
+	}
+	now := s.clock.Now().UTC()
+	item.CartID = cartID
+	item.ExpiresAt = now.Add(s.cfg.HoldTTL)
+	if err := s.repo.SetItem(item, now, s.cfg.MaxQuantity); err != nil {
+		return item, err
+	}
+	s.holdsChanged(item.BookID)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return item, nil
+}
+
+func (s *CartsService) DeleteCartItem(cartID, bookID int, token string) error {
+	if _, err := s.checkToken(cartID, token); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteItem(cartID, bookID); err != nil {
+		return err
+	}
+	s.holdsChanged(bookID)
+	return nil
+}
+
+// holdsChanged drops cached reads of the books, they show whether they are available next to their holds.
+func (s *CartsService) holdsChanged(bookIDs ...int) {
+	if invalidator, ok := s.stock.(CacheInvalidator); ok && len(bookIDs) > 0 {
+		invalidator.Invalidate(bookIDs...)
+	}
+}
+
+func (s *CartsService) Checkout(id int, token string) (models.Cart, error) {
+	if _, err := s.checkToken(id, token); err != nil {
+		return models.Cart{}, err
+	}
+	changes, err := s.repo.Checkout(id, s.clock.Now().UTC())
+	if err != nil {
+		return models.Cart{}, err
+	}
+	s.stock.StockChanged(changes)
+	return s.repo.GetCartByID(id)
+}
+
+// checkToken returns the cart if it belongs to the holder of the token.
+func (s *CartsService) checkToken(id int, token string) (models.Cart, error) {
+	cart, err := s.repo.GetCartByID(id)
+	if err != nil {
+		return cart, err
+	}
+	if !checkTokenHash(cart.TokenHash, token) {
+		return models.Cart{}, ErrInvalidCartToken
+	}
+	return cart, nil
+}
+
+// Start releases expired holds until ctx is done. The reaper runs every reaper interval and when
+// a hold expires, so cached reads of the book stop showing the hold as soon as it is gone.
+func (s *CartsService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.clock.After(s.release()):
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// release releases the expired holds and returns how long to wait for the next run.
+func (s *CartsService) release() time.Duration {
+	now := s.clock.Now().UTC()
+	bookIDs, err := s.repo.ReleaseExpiredHolds(now)
+	if err != nil {
+		logging.Errorf("carts: %s", err)
+		return s.cfg.ReaperInterval
+	}
+	if len(bookIDs) > 0 {
+		logging.Infof("carts: released expired holds of %d books", len(bookIDs))
+		s.holdsChanged(bookIDs...)
+	}
+	next, err := s.repo.NextHoldExpiry()
+	if err != nil {
+		logging.Errorf("carts: %s", err)
+		return s.cfg.ReaperInterval
+	}
+	if !next.IsZero() && next.Sub(now) < s.cfg.ReaperInterval {
+		return next.Sub(now)
+	}
+	return s.cfg.ReaperInterval
+}
+
+// Wait blocks until the reaper started by Start returns.
+func (s *CartsService) Wait() {
+	<-s.done
+}
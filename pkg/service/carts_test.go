@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/cache"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cartsRepo keeps carts in memory, books holds the stock of each book.
+type cartsRepo struct {
+	mu    sync.Mutex
+	books map[int]models.Book
+	carts map[int]*models.Cart
+}
+
+func (r *cartsRepo) CreateCart(tokenHash string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := len(r.carts) + 1
+	r.carts[id] = &models.Cart{ID: id, Status: models.CartOpen, TokenHash: tokenHash}
+	return id, nil
+}
+
+func (r *cartsRepo) GetCartByID(id int) (models.Cart, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cart := *r.carts[id]
+	cart.Items = append([]models.CartItem{}, cart.Items...)
+	return cart, nil
+}
+
+func (r *cartsRepo) DeleteCart(id int) error {
+	return nil
+}
+
+func (r *cartsRepo) held(bookID, exceptCartID int, at time.Time) int {
+	var held int
+	for _, cart := range r.carts {
+		for _, item := range cart.Items {
+			if cart.ID != exceptCartID && cart.Status == models.CartOpen && item.BookID == bookID && item.ExpiresAt.After(at) {
+				held += item.Quantity
+			}
+		}
+	}
+	return held
+}
+
+func (r *cartsRepo) SetItem(item models.CartItem, at time.Time, maxQuantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cart := r.carts[item.CartID]
+	inCart := item.Quantity
+	for _, other := range cart.Items {
+		if other.BookID != item.BookID {
+			inCart += other.Quantity
+		}
+	}
+	if inCart > maxQuantity {
+		return ErrCartFull
+	}
+	if r.books[item.BookID].Amount-r.held(item.BookID, item.CartID, at) < item.Quantity {
+		return ErrInsufficientStock
+	}
+	cart.Items = append(cart.Items, item)
+	return nil
+}
+
+func (r *cartsRepo) DeleteItem(cartID, bookID int) error {
+	return nil
+}
+
+func (r *cartsRepo) ReleaseExpiredHolds(at time.Time) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var released []int
+	for _, cart := range r.carts {
+		items := cart.Items[:0]
+		for _, item := range cart.Items {
+			if cart.Status == models.CartOpen && !item.ExpiresAt.After(at) {
+				released = append(released, item.BookID)
+				continue
+			}
+			items = append(items, item)
+		}
+		cart.Items = items
+	}
+	return released, nil
+}
+
+func (r *cartsRepo) NextHoldExpiry() (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var next time.Time
+	for _, cart := range r.carts {
+		for _, item := range cart.Items {
+			if cart.Status == models.CartOpen && (next.IsZero() || item.ExpiresAt.Before(next)) {
+				next = item.ExpiresAt
+			}
+		}
+	}
+	return next, nil
+}
+
+func (r *cartsRepo) Checkout(id int, at time.Time) ([]models.StockChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []models.StockChange
+	cart := r.carts[id]
+	for _, item := range cart.Items {
+		book := r.books[item.BookID]
+		previous := book.Amount
+		book.Amount -= item.Quantity
+		r.books[book.ID] = book
+		changes = append(changes, models.StockChange{PreviousAmount: previous, Book: book})
+	}
+	cart.Status = models.CartCheckedOut
+	return changes, nil
+}
+
+func TestCartsHolds(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	repo := &cartsRepo{
+		books: map[int]models.Book{1: {ID: 1, Name: "hello", Amount: 3}},
+		carts: make(map[int]*models.Cart),
+	}
+	s := NewCartsService(repo, nil, fake, CartsConfig{HoldTTL: 15 * time.Minute, ReaperInterval: time.Minute, MaxQuantity: 2})
+
+	first, _ := s.CreateCart()
+	second, _ := s.CreateCart()
+	item, err := s.SetCartItem(first.ID, first.Token, models.CartItem{BookID: 1, Quantity: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), item.ExpiresAt)
+
+	// The held books are not available to other carts until the hold expires.
+	_, err = s.SetCartItem(second.ID, second.Token, models.CartItem{BookID: 1, Quantity: 2})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	// A cart cannot hold more than the maximum, nor be used with the token of another cart.
+	_, err = s.SetCartItem(second.ID, second.Token, models.CartItem{BookID: 2, Quantity: 3})
+	assert.ErrorIs(t, err, ErrCartFull)
+	_, err = s.SetCartItem(second.ID, first.Token, models.CartItem{BookID: 1, Quantity: 1})
+	assert.ErrorIs(t, err, ErrInvalidCartToken)
+	_, err = s.GetCartByID(first.ID, "")
+	assert.ErrorIs(t, err, ErrInvalidCartToken)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	waitIdle(t, fake)
+	for i := 0; i < 15; i++ {
+		fake.Advance(time.Minute)
+		waitIdle(t, fake)
+	}
+	cancel()
+	s.Wait()
+
+	cart, _ := s.GetCartByID(first.ID, first.Token)
+	assert.Empty(t, cart.Items)
+	_, err = s.SetCartItem(second.ID, second.Token, models.CartItem{BookID: 1, Quantity: 2})
+	assert.NoError(t, err)
+}
+
+func TestCartsReleaseInvalidatesCache(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	book := models.Book{ID: 1, Name: "hello", Amount: 2}
+	mockManager := mock_service.NewMockBooksManager(c)
+	books := NewService(NewCachedBooksManager(mockManager, cache.NewLRU(10), time.Hour))
+	repo := &cartsRepo{books: map[int]models.Book{1: book}, carts: make(map[int]*models.Cart)}
+	s := NewCartsService(repo, books, fake, CartsConfig{HoldTTL: 15 * time.Minute, ReaperInterval: time.Hour, MaxQuantity: 20})
+
+	cart, _ := s.CreateCart()
+	_, err := s.SetCartItem(cart.ID, cart.Token, models.CartItem{BookID: 1, Quantity: 2})
+	assert.NoError(t, err)
+	held := book
+	held.Held = 2
+	mockManager.EXPECT().GetBookByID(1).Return(held, nil).Times(1)
+	got, err := books.GetBookByID(1)
+	assert.NoError(t, err)
+	assert.False(t, got.InStock())
+
+	// The reaper runs when the hold expires, long before its interval, and drops the cached book.
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	waitIdle(t, fake)
+	fake.Advance(15 * time.Minute)
+	waitIdle(t, fake)
+	cancel()
+	s.Wait()
+
+	mockManager.EXPECT().GetBookByID(1).Return(book, nil).Times(1)
+	got, err = books.GetBookByID(1)
+	assert.NoError(t, err)
+	assert.True(t, got.InStock())
+}
+
+func TestCartsCheckout(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	book := models.Book{ID: 1, Name: "hello", Price: 432, Currency: "USD", Genre: 2, Amount: 2}
+	mockManager := mock_service.NewMockBooksManager(c)
+	books := NewService(NewCachedBooksManager(mockManager, cache.NewLRU(10), time.Minute))
+	recorder := &eventRecorder{}
+	books.Subscribe(recorder)
+
+	repo := &cartsRepo{books: map[int]models.Book{1: book}, carts: make(map[int]*models.Cart)}
+	s := NewCartsService(repo, books, clock.NewFake(time.Now()), CartsConfig{HoldTTL: time.Minute, MaxQuantity: 20})
+
+	mockManager.EXPECT().GetBookByID(1).Return(book, nil).Times(1)
+	_, err := books.GetBookByID(1)
+	assert.NoError(t, err)
+
+	created, _ := s.CreateCart()
+	_, err = s.SetCartItem(created.ID, created.Token, models.CartItem{BookID: 1, Quantity: 2})
+	assert.NoError(t, err)
+
+	// The cached book is dropped by the hold.
+	held := book
+	held.Held = 2
+	mockManager.EXPECT().GetBookByID(1).Return(held, nil).Times(1)
+	got, err := books.GetBookByID(1)
+	assert.NoError(t, err)
+	assert.False(t, got.InStock())
+
+	cart, err := s.Checkout(created.ID, created.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CartCheckedOut, cart.Status)
+	assert.Equal(t, []string{models.EventBookUpdated, models.EventBookOutOfStock, models.EventBookLowStock}, recorder.events)
+
+	// The cached book is dropped by the checkout.
+	sold := book
+	sold.Amount = 0
+	mockManager.EXPECT().GetBookByID(1).Return(sold, nil).Times(1)
+	got, err = books.GetBookByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, got.Amount)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// maxCoverPixels bounds the decoded size of covers, small files can decode to huge images.
+// A cover of 4000x4000 pixels takes 64 MB once converted for its thumbnails.
+const maxCoverPixels = 16_000_000
+
+var (
+	ErrCoverTooLarge    = errors.New("cover is too large")
+	ErrUnsupportedCover = errors.New("cover must be a JPEG or PNG image")
+)
+
+type CoversConfig struct {
+	Dir string `mapstructure:"dir"`
+	// MaxSize is the largest accepted upload in bytes.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxConcurrent is the number of uploads processed at once, others wait for their turn.
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+}
+
+// CoversService keeps the cover images of books with their thumbnails in a blob store. Covers
+// are named after their content, so a new cover gets new URLs and old ones can be cached forever.
+type CoversService struct {
+	repo    repository.Covers
+	store   storage.BlobStore
+	maxSize int64
+	cache   CacheInvalidator
+	// uploads has a slot for each upload that may be processed at once, decoded images take a lot of memory.
+	uploads chan struct{}
+}
+
+// NewCoversService takes the cache of book reads to drop books whose cover changes, it may be nil.
+func NewCoversService(repo repository.Covers, store storage.BlobStore, cfg CoversConfig, cache CacheInvalidator) *CoversService {
+	return &CoversService{repo: repo, store: store, maxSize: cfg.MaxSize, cache: cache, uploads: make(chan struct{}, cfg.MaxConcurrent)}
+}
+
+// SetCover validates the JPEG or PNG image, stores it with its thumbnails and makes it the cover of the book.
+// The files of the previous cover are deleted. It returns the file name of the cover, ErrBookNotFound is
+// returned for a missing book.
+func (s *CoversService) SetCover(bookID int, r io.Reader) (string, error) {
+	s.uploads <- struct{}{}
+	defer func() { <-s.uploads }()
+	if _, err := s.repo.GetCover(bookID); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > s.maxSize {
+		return "", ErrCoverTooLarge
+	}
+	var encode func(w io.Writer, img image.Image) error
+	var ext string
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg":
+		ext = ".jpg"
+		encode = func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+		}
+	case "image/png":
+		ext = ".png"
+		encode = png.Encode
+	default:
+		return "", ErrUnsupportedCover
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedCover
+	}
+	if config.Width*config.Height > maxCoverPixels {
+		return "", ErrCoverTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedCover
+	}
+
+	sum := sha256.Sum256(data)
+	cover := hex.EncodeToString(sum[:8]) + ext
+	ctx := context.Background()
+	if err = s.store.Put(ctx, coverKey(bookID, cover), bytes.NewReader(data), contentType); err != nil {
+		return "", err
+	}
+	src := toRGBA(img)
+	for size, width := range models.CoverThumbnails {
+		var thumb bytes.Buffer
+		if err = encode(&thumb, thumbnail(src, width)); err != nil {
+			return "", err
+		}
+		if err = s.store.Put(ctx, coverKey(bookID, models.CoverFile(cover, size)), &thumb, contentType); err != nil {
+			return "", err
+		}
+	}
+
+	previous, err := s.repo.SetCover(bookID, cover)
+	if err != nil {
+		s.deleteCover(bookID, cover)
+		return "", err
+	}
+	if previous != "" && previous != cover {
+		s.deleteCover(bookID, previous)
+	}
+	if s.cache != nil {
+		s.cache.Invalidate(bookID)
+	}
+	return cover, nil
+}
+
+// GetCover returns the cover file of the book, named as models.CoverFile names them.
+func (s *CoversService) GetCover(bookID int, file string) (storage.Blob, error) {
+	return s.store.Get(context.Background(), coverKey(bookID, file))
+}
+
+func (s *CoversService) deleteCover(bookID int, cover string) {
+	files := []string{cover}
+	for size := range models.CoverThumbnails {
+		files = append(files, models.CoverFile(cover, size))
+	}
+	for _, file := range files {
+		if err := s.store.Delete(context.Background(), coverKey(bookID, file)); err != nil {
+			logging.Errorf("covers: %s of book %d: %s", file, bookID, err)
+		}
+	}
+}
+
+func coverKey(bookID int, file string) string {
+	return fmt.Sprintf("covers/%d/%s", bookID, file)
+}
+
+// toRGBA returns the image as RGBA with its origin at 0,0, converting it only when it is not one already.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// thumbnail scales the image down to the width, averaging the pixels each thumbnail pixel covers.
+// Images narrower than the width are returned as they are.
+func thumbnail(src *image.RGBA, width int) image.Image {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= width {
+		return src
+	}
+	height := (h*width + w/2) / w
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*h/height, (y+1)*h/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*w/width, (x+1)*w/width
+			if x1 == x0 {
+				x1++
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// coversRepo keeps the covers of books in memory.
+type coversRepo map[int]string
+
+func (r coversRepo) GetCover(bookID int) (string, error) {
+	cover, ok := r[bookID]
+	if !ok {
+		return "", ErrBookNotFound
+	}
+	return cover, nil
+}
+
+func (r coversRepo) SetCover(bookID int, cover string) (string, error) {
+	previous, ok := r[bookID]
+	if !ok {
+		return "", ErrBookNotFound
+	}
+	r[bookID] = cover
+	return previous, nil
+}
+
+func pngCover(t *testing.T, width, height int, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestCoversService(t *testing.T) {
+	store := storage.NewLocalStore(t.TempDir())
+	repo := coversRepo{1: ""}
+	invalidated := &invalidations{}
+	s := NewCoversService(repo, store, CoversConfig{MaxSize: 1 << 20, MaxConcurrent: 1}, invalidated)
+
+	cover, err := s.SetCover(1, bytes.NewReader(pngCover(t, 800, 1200, color.RGBA{R: 200, A: 255})))
+	assert.NoError(t, err)
+	assert.Equal(t, cover, repo[1])
+	assert.True(t, strings.HasSuffix(cover, ".png"))
+	assert.Equal(t, &invalidations{1}, invalidated)
+
+	for size, width := range models.CoverThumbnails {
+		blob, err := s.GetCover(1, models.CoverFile(cover, size))
+		assert.NoError(t, err)
+		img, err := png.Decode(blob.Content)
+		assert.NoError(t, err)
+		blob.Content.Close()
+		assert.Equal(t, image.Pt(width, width*3/2), img.Bounds().Size(), size)
+		assert.Equal(t, color.RGBA{R: 200, A: 255}, color.RGBAModel.Convert(img.At(0, 0)))
+	}
+
+	next, err := s.SetCover(1, bytes.NewReader(pngCover(t, 100, 100, color.White)))
+	assert.NoError(t, err)
+	assert.NotEqual(t, cover, next)
+	_, err = s.GetCover(1, cover)
+	assert.ErrorIs(t, err, storage.ErrNotFound, "previous cover is deleted")
+	_, err = s.GetCover(1, models.CoverFile(next, "small"))
+	assert.NoError(t, err)
+
+	_, err = s.SetCover(1, strings.NewReader("GIF89a"))
+	assert.ErrorIs(t, err, ErrUnsupportedCover)
+	_, err = s.SetCover(1, bytes.NewReader(make([]byte, 1<<20+1)))
+	assert.ErrorIs(t, err, ErrCoverTooLarge)
+
+	orphan := pngCover(t, 10, 10, color.Black)
+	_, err = s.SetCover(2, bytes.NewReader(orphan))
+	assert.ErrorIs(t, err, ErrBookNotFound)
+	sum := sha256.Sum256(orphan)
+	_, err = s.GetCover(2, hex.EncodeToString(sum[:8])+".png")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "cover of a missing book is not kept")
+}
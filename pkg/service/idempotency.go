@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyInUse  = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused = errors.New("idempotency key is already used for another request")
+)
+
+type IdempotencyConfig struct {
+	// TTL is how long responses are replayed.
+	TTL time.Duration `mapstructure:"ttl"`
+	// CleanupInterval is how often expired keys are deleted.
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+}
+
+type IdempotencyService struct {
+	repo  repository.IdempotencyKeys
+	clock clock.Clock
+	cfg   IdempotencyConfig
+	done  chan struct{}
+}
+
+func NewIdempotencyService(repo repository.IdempotencyKeys, clock clock.Clock, cfg IdempotencyConfig) *IdempotencyService {
+	return &IdempotencyService{repo: repo, clock: clock, cfg: cfg, done: make(chan struct{})}
+}
+
+// Start deletes expired keys every cleanup interval until ctx is done.
+func (s *IdempotencyService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		for {
+			if err := s.repo.DeleteExpired(s.clock.Now()); err != nil {
+				logging.Errorf("idempotency: %s", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.clock.After(s.cfg.CleanupInterval):
+			}
+		}
+	}()
+}
+
+// Wait blocks until the cleanup started by Start returns.
+func (s *IdempotencyService) Wait() {
+	<-s.done
+}
+
+// Begin locks the key for a new request. If the key was already used for the same request
+// the stored response is returned and the second result is true.
+func (s *IdempotencyService) Begin(key, requestHash string) (models.IdempotencyRecord, bool, error) {
+	now := s.clock.Now()
+	record := models.IdempotencyRecord{Key: key, RequestHash: requestHash, ExpiresAt: now.Add(s.cfg.TTL)}
+	created, err := s.repo.CreateRecord(record)
+	if err != nil || created {
+		return models.IdempotencyRecord{}, false, err
+	}
+	stored, err := s.repo.GetRecord(key)
+	if err != nil {
+		return stored, false, err
+	}
+	// Expired keys are free again even before the cleanup deletes them.
+	if !stored.ExpiresAt.After(now) {
+		if err = s.repo.DeleteRecord(key); err != nil {
+			return models.IdempotencyRecord{}, false, err
+		}
+		if created, err = s.repo.CreateRecord(record); err != nil || created {
+			return models.IdempotencyRecord{}, false, err
+		}
+		return models.IdempotencyRecord{}, false, ErrIdempotencyKeyInUse
+	}
+	if stored.RequestHash != requestHash {
+		return stored, false, ErrIdempotencyKeyReused
+	}
+	if stored.StatusCode == 0 {
+		return stored, false, ErrIdempotencyKeyInUse
+	}
+	return stored, true, nil
+}
+
+func (s *IdempotencyService) Complete(key string, statusCode int, header http.Header, body []byte) error {
+	return s.repo.SaveResponse(key, statusCode, models.ResponseHeader(header), body)
+}
+
+func (s *IdempotencyService) Abort(key string) error {
+	return s.repo.DeleteRecord(key)
+}
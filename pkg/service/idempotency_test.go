@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// idempotencyRepo keeps records in memory and counts the cleanups.
+type idempotencyRepo struct {
+	mu       sync.Mutex
+	records  map[string]models.IdempotencyRecord
+	cleanups int
+}
+
+func (r *idempotencyRepo) GetRecord(key string) (models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.records[key], nil
+}
+
+func (r *idempotencyRepo) CreateRecord(record models.IdempotencyRecord) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[record.Key]; ok {
+		return false, nil
+	}
+	r.records[record.Key] = record
+	return true, nil
+}
+
+func (r *idempotencyRepo) SaveResponse(key string, statusCode int, header models.ResponseHeader, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.records[key]
+	record.StatusCode, record.Header, record.Body = statusCode, header, body
+	r.records[key] = record
+	return nil
+}
+
+func (r *idempotencyRepo) DeleteRecord(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, key)
+	return nil
+}
+
+func (r *idempotencyRepo) DeleteExpired(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleanups++
+	for key, record := range r.records {
+		if record.ExpiresAt.Before(now) {
+			delete(r.records, key)
+		}
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) cleanupCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cleanups
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	fake := clock.NewFake(time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC))
+	repo := &idempotencyRepo{records: map[string]models.IdempotencyRecord{}}
+	s := NewIdempotencyService(repo, fake, IdempotencyConfig{TTL: time.Hour, CleanupInterval: time.Hour})
+
+	_, replay, err := s.Begin("abc", "hash")
+	require.NoError(t, err)
+	assert.False(t, replay)
+	_, _, err = s.Begin("abc", "hash")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	header := http.Header{"Location": {"/books/1"}}
+	require.NoError(t, s.Complete("abc", http.StatusCreated, header, []byte(`{"id":1}`)))
+	record, replay, err := s.Begin("abc", "hash")
+	require.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, models.ResponseHeader(header), record.Header)
+	_, _, err = s.Begin("abc", "other")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// An expired key serves a new request although the cleanup has not run.
+	fake.Advance(time.Hour)
+	_, replay, err = s.Begin("abc", "other")
+	require.NoError(t, err)
+	assert.False(t, replay)
+}
+
+func TestIdempotencyCleanup(t *testing.T) {
+	fake := clock.NewFake(time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC))
+	repo := &idempotencyRepo{records: map[string]models.IdempotencyRecord{}}
+	s := NewIdempotencyService(repo, fake, IdempotencyConfig{TTL: time.Hour, CleanupInterval: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	assert.Eventually(t, func() bool { return repo.cleanupCount() == 1 }, time.Second, time.Millisecond)
+	_, _, err := s.Begin("abc", "hash")
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.cleanupCount())
+
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+	fake.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return repo.cleanupCount() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	s.Wait()
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+)
+
+// LocationsService manages the locations holding stock and moves stock between them.
+type LocationsService struct {
+	repo  repository.Locations
+	cache CacheInvalidator
+}
+
+// NewLocationsService takes the cache of book reads to drop book lists filtered by location after transfers,
+// it may be nil.
+func NewLocationsService(repo repository.Locations, cache CacheInvalidator) *LocationsService {
+	return &LocationsService{repo: repo, cache: cache}
+}
+
+func (s *LocationsService) GetLocations() ([]models.Location, error) {
+	return s.repo.GetLocations()
+}
+
+func (s *LocationsService) GetLocationByID(id int) (models.Location, error) {
+	return s.repo.GetLocationByID(id)
+}
+
+func (s *LocationsService) CreateLocation(location models.Location) (int, error) {
+	return s.repo.CreateLocation(location)
+}
+
+func (s *LocationsService) UpdateLocation(id int, location models.Location) error {
+	return s.repo.UpdateLocation(id, location)
+}
+
+func (s *LocationsService) GetBookStock(bookID int) ([]models.StockLevel, error) {
+	return s.repo.GetBookStock(bookID)
+}
+
+func (s *LocationsService) GetLocationStock(locationID int) ([]models.StockLevel, error) {
+	return s.repo.GetLocationStock(locationID)
+}
+
+func (s *LocationsService) Transfer(transfer models.StockTransfer) (models.StockTransfer, error) {
+	transfer, err := s.repo.Transfer(transfer)
+	if err != nil {
+		return transfer, err
+	}
+	if s.cache != nil {
+		s.cache.Invalidate(transfer.BookID)
+	}
+	return transfer, nil
+}
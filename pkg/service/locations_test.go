@@ -0,0 +1,41 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// locationsRepo keeps the stock of one book by location in memory, other methods are not used.
+type locationsRepo struct {
+	repository.Locations
+	stock map[int]int
+}
+
+func (r *locationsRepo) Transfer(transfer models.StockTransfer) (models.StockTransfer, error) {
+	if r.stock[transfer.FromLocationID] < transfer.Quantity {
+		return models.StockTransfer{}, repository.ErrInsufficientStock
+	}
+	r.stock[transfer.FromLocationID] -= transfer.Quantity
+	r.stock[transfer.ToLocationID] += transfer.Quantity
+	transfer.ID = 1
+	return transfer, nil
+}
+
+func TestLocationsService(t *testing.T) {
+	repo := &locationsRepo{stock: map[int]int{1: 3}}
+	invalidated := &invalidations{}
+	s := NewLocationsService(repo, invalidated)
+
+	_, err := s.Transfer(models.StockTransfer{BookID: 2, FromLocationID: 1, ToLocationID: 2, Quantity: 4})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Empty(t, *invalidated)
+
+	transfer, err := s.Transfer(models.StockTransfer{BookID: 2, FromLocationID: 1, ToLocationID: 2, Quantity: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transfer.ID)
+	assert.Equal(t, map[int]int{1: 1, 2: 2}, repo.stock)
+	// Lists of books filtered by location are cached.
+	assert.Equal(t, &invalidations{2}, invalidated)
+}
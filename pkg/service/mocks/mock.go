@@ -5,9 +5,12 @@
 package mock_service
 
 import (
-	models "github.com/TenderLimbo/rest-api/models"
+	io "io"
+	http "net/http"
 	reflect "reflect"
 
+	models "github.com/TenderLimbo/rest-api/models"
+	storage "github.com/TenderLimbo/rest-api/pkg/storage"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -94,11 +97,12 @@ func (mr *MockBooksManagerMockRecorder) GetBooks(filterCondition interface{}) *g
 }
 
 // UpdateBookByID mocks base method.
-func (m *MockBooksManager) UpdateBookByID(id int, book models.Book) error {
+func (m *MockBooksManager) UpdateBookByID(id int, book models.Book) (models.StockChange, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateBookByID", id, book)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(models.StockChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // UpdateBookByID indicates an expected call of UpdateBookByID.
@@ -106,3 +110,1484 @@ func (mr *MockBooksManagerMockRecorder) UpdateBookByID(id, book interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBookByID", reflect.TypeOf((*MockBooksManager)(nil).UpdateBookByID), id, book)
 }
+
+// MockEventListener is a mock of EventListener interface.
+type MockEventListener struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventListenerMockRecorder
+}
+
+// MockEventListenerMockRecorder is the mock recorder for MockEventListener.
+type MockEventListenerMockRecorder struct {
+	mock *MockEventListener
+}
+
+// NewMockEventListener creates a new mock instance.
+func NewMockEventListener(ctrl *gomock.Controller) *MockEventListener {
+	mock := &MockEventListener{ctrl: ctrl}
+	mock.recorder = &MockEventListenerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventListener) EXPECT() *MockEventListenerMockRecorder {
+	return m.recorder
+}
+
+// HandleEvent mocks base method.
+func (m *MockEventListener) HandleEvent(event models.Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "HandleEvent", event)
+}
+
+// HandleEvent indicates an expected call of HandleEvent.
+func (mr *MockEventListenerMockRecorder) HandleEvent(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleEvent", reflect.TypeOf((*MockEventListener)(nil).HandleEvent), event)
+}
+
+// MockGenres is a mock of Genres interface.
+type MockGenres struct {
+	ctrl     *gomock.Controller
+	recorder *MockGenresMockRecorder
+}
+
+// MockGenresMockRecorder is the mock recorder for MockGenres.
+type MockGenresMockRecorder struct {
+	mock *MockGenres
+}
+
+// NewMockGenres creates a new mock instance.
+func NewMockGenres(ctrl *gomock.Controller) *MockGenres {
+	mock := &MockGenres{ctrl: ctrl}
+	mock.recorder = &MockGenresMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGenres) EXPECT() *MockGenresMockRecorder {
+	return m.recorder
+}
+
+// CreateGenre mocks base method.
+func (m *MockGenres) CreateGenre(genre models.Genre) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGenre", genre)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGenre indicates an expected call of CreateGenre.
+func (mr *MockGenresMockRecorder) CreateGenre(genre interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGenre", reflect.TypeOf((*MockGenres)(nil).CreateGenre), genre)
+}
+
+// GetGenreByID mocks base method.
+func (m *MockGenres) GetGenreByID(id int) (models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGenreByID", id)
+	ret0, _ := ret[0].(models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGenreByID indicates an expected call of GetGenreByID.
+func (mr *MockGenresMockRecorder) GetGenreByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGenreByID", reflect.TypeOf((*MockGenres)(nil).GetGenreByID), id)
+}
+
+// GetGenres mocks base method.
+func (m *MockGenres) GetGenres() ([]models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGenres")
+	ret0, _ := ret[0].([]models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGenres indicates an expected call of GetGenres.
+func (mr *MockGenresMockRecorder) GetGenres() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGenres", reflect.TypeOf((*MockGenres)(nil).GetGenres))
+}
+
+// GetGenresByIDs mocks base method.
+func (m *MockGenres) GetGenresByIDs(ids []int) ([]models.Genre, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGenresByIDs", ids)
+	ret0, _ := ret[0].([]models.Genre)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGenresByIDs indicates an expected call of GetGenresByIDs.
+func (mr *MockGenresMockRecorder) GetGenresByIDs(ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGenresByIDs", reflect.TypeOf((*MockGenres)(nil).GetGenresByIDs), ids)
+}
+
+// MockIdempotency is a mock of Idempotency interface.
+type MockIdempotency struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyMockRecorder
+}
+
+// MockIdempotencyMockRecorder is the mock recorder for MockIdempotency.
+type MockIdempotencyMockRecorder struct {
+	mock *MockIdempotency
+}
+
+// NewMockIdempotency creates a new mock instance.
+func NewMockIdempotency(ctrl *gomock.Controller) *MockIdempotency {
+	mock := &MockIdempotency{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotency) EXPECT() *MockIdempotencyMockRecorder {
+	return m.recorder
+}
+
+// Abort mocks base method.
+func (m *MockIdempotency) Abort(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort.
+func (mr *MockIdempotencyMockRecorder) Abort(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockIdempotency)(nil).Abort), key)
+}
+
+// Begin mocks base method.
+func (m *MockIdempotency) Begin(key, requestHash string) (models.IdempotencyRecord, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", key, requestHash)
+	ret0, _ := ret[0].(models.IdempotencyRecord)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockIdempotencyMockRecorder) Begin(key, requestHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockIdempotency)(nil).Begin), key, requestHash)
+}
+
+// Complete mocks base method.
+func (m *MockIdempotency) Complete(key string, statusCode int, header http.Header, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", key, statusCode, header, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyMockRecorder) Complete(key, statusCode, header, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotency)(nil).Complete), key, statusCode, header, body)
+}
+
+// MockWebhooks is a mock of Webhooks interface.
+type MockWebhooks struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhooksMockRecorder
+}
+
+// MockWebhooksMockRecorder is the mock recorder for MockWebhooks.
+type MockWebhooksMockRecorder struct {
+	mock *MockWebhooks
+}
+
+// NewMockWebhooks creates a new mock instance.
+func NewMockWebhooks(ctrl *gomock.Controller) *MockWebhooks {
+	mock := &MockWebhooks{ctrl: ctrl}
+	mock.recorder = &MockWebhooksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhooks) EXPECT() *MockWebhooksMockRecorder {
+	return m.recorder
+}
+
+// CreateSubscription mocks base method.
+func (m *MockWebhooks) CreateSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubscription", subscription)
+	ret0, _ := ret[0].(models.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSubscription indicates an expected call of CreateSubscription.
+func (mr *MockWebhooksMockRecorder) CreateSubscription(subscription interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubscription", reflect.TypeOf((*MockWebhooks)(nil).CreateSubscription), subscription)
+}
+
+// DeleteSubscription mocks base method.
+func (m *MockWebhooks) DeleteSubscription(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscription", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscription indicates an expected call of DeleteSubscription.
+func (mr *MockWebhooksMockRecorder) DeleteSubscription(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscription", reflect.TypeOf((*MockWebhooks)(nil).DeleteSubscription), id)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockWebhooks) GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveries", subscriptionID)
+	ret0, _ := ret[0].([]models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockWebhooksMockRecorder) GetDeliveries(subscriptionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockWebhooks)(nil).GetDeliveries), subscriptionID)
+}
+
+// GetSubscriptionByID mocks base method.
+func (m *MockWebhooks) GetSubscriptionByID(id int) (models.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionByID", id)
+	ret0, _ := ret[0].(models.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionByID indicates an expected call of GetSubscriptionByID.
+func (mr *MockWebhooksMockRecorder) GetSubscriptionByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionByID", reflect.TypeOf((*MockWebhooks)(nil).GetSubscriptionByID), id)
+}
+
+// GetSubscriptions mocks base method.
+func (m *MockWebhooks) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptions")
+	ret0, _ := ret[0].([]models.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptions indicates an expected call of GetSubscriptions.
+func (mr *MockWebhooksMockRecorder) GetSubscriptions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptions", reflect.TypeOf((*MockWebhooks)(nil).GetSubscriptions))
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockWebhooks) UpdateSubscription(id int, subscription models.WebhookSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", id, subscription)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockWebhooksMockRecorder) UpdateSubscription(id, subscription interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockWebhooks)(nil).UpdateSubscription), id, subscription)
+}
+
+// MockPricing is a mock of Pricing interface.
+type MockPricing struct {
+	ctrl     *gomock.Controller
+	recorder *MockPricingMockRecorder
+}
+
+// MockPricingMockRecorder is the mock recorder for MockPricing.
+type MockPricingMockRecorder struct {
+	mock *MockPricing
+}
+
+// NewMockPricing creates a new mock instance.
+func NewMockPricing(ctrl *gomock.Controller) *MockPricing {
+	mock := &MockPricing{ctrl: ctrl}
+	mock.recorder = &MockPricingMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPricing) EXPECT() *MockPricingMockRecorder {
+	return m.recorder
+}
+
+// Convert mocks base method.
+func (m *MockPricing) Convert(books []models.Book, currency string) ([]models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Convert", books, currency)
+	ret0, _ := ret[0].([]models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Convert indicates an expected call of Convert.
+func (mr *MockPricingMockRecorder) Convert(books, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Convert", reflect.TypeOf((*MockPricing)(nil).Convert), books, currency)
+}
+
+// DeletePrice mocks base method.
+func (m *MockPricing) DeletePrice(bookID int, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePrice", bookID, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePrice indicates an expected call of DeletePrice.
+func (mr *MockPricingMockRecorder) DeletePrice(bookID, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePrice", reflect.TypeOf((*MockPricing)(nil).DeletePrice), bookID, currency)
+}
+
+// GetPrices mocks base method.
+func (m *MockPricing) GetPrices(bookID int) ([]models.BookPrice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrices", bookID)
+	ret0, _ := ret[0].([]models.BookPrice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrices indicates an expected call of GetPrices.
+func (mr *MockPricingMockRecorder) GetPrices(bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrices", reflect.TypeOf((*MockPricing)(nil).GetPrices), bookID)
+}
+
+// SetPrice mocks base method.
+func (m *MockPricing) SetPrice(price models.BookPrice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPrice", price)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPrice indicates an expected call of SetPrice.
+func (mr *MockPricingMockRecorder) SetPrice(price interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPrice", reflect.TypeOf((*MockPricing)(nil).SetPrice), price)
+}
+
+// MockPromotions is a mock of Promotions interface.
+type MockPromotions struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromotionsMockRecorder
+}
+
+// MockPromotionsMockRecorder is the mock recorder for MockPromotions.
+type MockPromotionsMockRecorder struct {
+	mock *MockPromotions
+}
+
+// NewMockPromotions creates a new mock instance.
+func NewMockPromotions(ctrl *gomock.Controller) *MockPromotions {
+	mock := &MockPromotions{ctrl: ctrl}
+	mock.recorder = &MockPromotionsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromotions) EXPECT() *MockPromotionsMockRecorder {
+	return m.recorder
+}
+
+// CreatePromotion mocks base method.
+func (m *MockPromotions) CreatePromotion(promotion models.Promotion) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromotion", promotion)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePromotion indicates an expected call of CreatePromotion.
+func (mr *MockPromotionsMockRecorder) CreatePromotion(promotion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromotion", reflect.TypeOf((*MockPromotions)(nil).CreatePromotion), promotion)
+}
+
+// DeletePromotion mocks base method.
+func (m *MockPromotions) DeletePromotion(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePromotion", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePromotion indicates an expected call of DeletePromotion.
+func (mr *MockPromotionsMockRecorder) DeletePromotion(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePromotion", reflect.TypeOf((*MockPromotions)(nil).DeletePromotion), id)
+}
+
+// GetPromotionByID mocks base method.
+func (m *MockPromotions) GetPromotionByID(id int) (models.Promotion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromotionByID", id)
+	ret0, _ := ret[0].(models.Promotion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPromotionByID indicates an expected call of GetPromotionByID.
+func (mr *MockPromotionsMockRecorder) GetPromotionByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotionByID", reflect.TypeOf((*MockPromotions)(nil).GetPromotionByID), id)
+}
+
+// GetPromotions mocks base method.
+func (m *MockPromotions) GetPromotions() ([]models.Promotion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromotions")
+	ret0, _ := ret[0].([]models.Promotion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPromotions indicates an expected call of GetPromotions.
+func (mr *MockPromotionsMockRecorder) GetPromotions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotions", reflect.TypeOf((*MockPromotions)(nil).GetPromotions))
+}
+
+// UpdatePromotion mocks base method.
+func (m *MockPromotions) UpdatePromotion(id int, promotion models.Promotion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePromotion", id, promotion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePromotion indicates an expected call of UpdatePromotion.
+func (mr *MockPromotionsMockRecorder) UpdatePromotion(id, promotion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePromotion", reflect.TypeOf((*MockPromotions)(nil).UpdatePromotion), id, promotion)
+}
+
+// MockPriceHistory is a mock of PriceHistory interface.
+type MockPriceHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockPriceHistoryMockRecorder
+}
+
+// MockPriceHistoryMockRecorder is the mock recorder for MockPriceHistory.
+type MockPriceHistoryMockRecorder struct {
+	mock *MockPriceHistory
+}
+
+// NewMockPriceHistory creates a new mock instance.
+func NewMockPriceHistory(ctrl *gomock.Controller) *MockPriceHistory {
+	mock := &MockPriceHistory{ctrl: ctrl}
+	mock.recorder = &MockPriceHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPriceHistory) EXPECT() *MockPriceHistoryMockRecorder {
+	return m.recorder
+}
+
+// CancelScheduledPrice mocks base method.
+func (m *MockPriceHistory) CancelScheduledPrice(bookID, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelScheduledPrice", bookID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelScheduledPrice indicates an expected call of CancelScheduledPrice.
+func (mr *MockPriceHistoryMockRecorder) CancelScheduledPrice(bookID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelScheduledPrice", reflect.TypeOf((*MockPriceHistory)(nil).CancelScheduledPrice), bookID, id)
+}
+
+// GetPriceHistory mocks base method.
+func (m *MockPriceHistory) GetPriceHistory(bookID int) ([]models.PriceChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPriceHistory", bookID)
+	ret0, _ := ret[0].([]models.PriceChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPriceHistory indicates an expected call of GetPriceHistory.
+func (mr *MockPriceHistoryMockRecorder) GetPriceHistory(bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPriceHistory", reflect.TypeOf((*MockPriceHistory)(nil).GetPriceHistory), bookID)
+}
+
+// GetScheduledPrices mocks base method.
+func (m *MockPriceHistory) GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledPrices", bookID)
+	ret0, _ := ret[0].([]models.ScheduledPrice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPrices indicates an expected call of GetScheduledPrices.
+func (mr *MockPriceHistoryMockRecorder) GetScheduledPrices(bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPrices", reflect.TypeOf((*MockPriceHistory)(nil).GetScheduledPrices), bookID)
+}
+
+// SchedulePrice mocks base method.
+func (m *MockPriceHistory) SchedulePrice(price models.ScheduledPrice) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SchedulePrice", price)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SchedulePrice indicates an expected call of SchedulePrice.
+func (mr *MockPriceHistoryMockRecorder) SchedulePrice(price interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SchedulePrice", reflect.TypeOf((*MockPriceHistory)(nil).SchedulePrice), price)
+}
+
+// MockStockObserver is a mock of StockObserver interface.
+type MockStockObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockStockObserverMockRecorder
+}
+
+// MockStockObserverMockRecorder is the mock recorder for MockStockObserver.
+type MockStockObserverMockRecorder struct {
+	mock *MockStockObserver
+}
+
+// NewMockStockObserver creates a new mock instance.
+func NewMockStockObserver(ctrl *gomock.Controller) *MockStockObserver {
+	mock := &MockStockObserver{ctrl: ctrl}
+	mock.recorder = &MockStockObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStockObserver) EXPECT() *MockStockObserverMockRecorder {
+	return m.recorder
+}
+
+// StockChanged mocks base method.
+func (m *MockStockObserver) StockChanged(changes []models.StockChange) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StockChanged", changes)
+}
+
+// StockChanged indicates an expected call of StockChanged.
+func (mr *MockStockObserverMockRecorder) StockChanged(changes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StockChanged", reflect.TypeOf((*MockStockObserver)(nil).StockChanged), changes)
+}
+
+// MockCarts is a mock of Carts interface.
+type MockCarts struct {
+	ctrl     *gomock.Controller
+	recorder *MockCartsMockRecorder
+}
+
+// MockCartsMockRecorder is the mock recorder for MockCarts.
+type MockCartsMockRecorder struct {
+	mock *MockCarts
+}
+
+// NewMockCarts creates a new mock instance.
+func NewMockCarts(ctrl *gomock.Controller) *MockCarts {
+	mock := &MockCarts{ctrl: ctrl}
+	mock.recorder = &MockCartsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCarts) EXPECT() *MockCartsMockRecorder {
+	return m.recorder
+}
+
+// Checkout mocks base method.
+func (m *MockCarts) Checkout(id int, token string) (models.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkout", id, token)
+	ret0, _ := ret[0].(models.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkout indicates an expected call of Checkout.
+func (mr *MockCartsMockRecorder) Checkout(id, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkout", reflect.TypeOf((*MockCarts)(nil).Checkout), id, token)
+}
+
+// CreateCart mocks base method.
+func (m *MockCarts) CreateCart() (models.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCart")
+	ret0, _ := ret[0].(models.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCart indicates an expected call of CreateCart.
+func (mr *MockCartsMockRecorder) CreateCart() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCart", reflect.TypeOf((*MockCarts)(nil).CreateCart))
+}
+
+// DeleteCart mocks base method.
+func (m *MockCarts) DeleteCart(id int, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCart", id, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCart indicates an expected call of DeleteCart.
+func (mr *MockCartsMockRecorder) DeleteCart(id, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCart", reflect.TypeOf((*MockCarts)(nil).DeleteCart), id, token)
+}
+
+// DeleteCartItem mocks base method.
+func (m *MockCarts) DeleteCartItem(cartID, bookID int, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCartItem", cartID, bookID, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCartItem indicates an expected call of DeleteCartItem.
+func (mr *MockCartsMockRecorder) DeleteCartItem(cartID, bookID, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCartItem", reflect.TypeOf((*MockCarts)(nil).DeleteCartItem), cartID, bookID, token)
+}
+
+// GetCartByID mocks base method.
+func (m *MockCarts) GetCartByID(id int, token string) (models.Cart, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCartByID", id, token)
+	ret0, _ := ret[0].(models.Cart)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCartByID indicates an expected call of GetCartByID.
+func (mr *MockCartsMockRecorder) GetCartByID(id, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCartByID", reflect.TypeOf((*MockCarts)(nil).GetCartByID), id, token)
+}
+
+// SetCartItem mocks base method.
+func (m *MockCarts) SetCartItem(cartID int, token string, item models.CartItem) (models.CartItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCartItem", cartID, token, item)
+	ret0, _ := ret[0].(models.CartItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCartItem indicates an expected call of SetCartItem.
+func (mr *MockCartsMockRecorder) SetCartItem(cartID, token, item interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCartItem", reflect.TypeOf((*MockCarts)(nil).SetCartItem), cartID, token, item)
+}
+
+// MockInventory is a mock of Inventory interface.
+type MockInventory struct {
+	ctrl     *gomock.Controller
+	recorder *MockInventoryMockRecorder
+}
+
+// MockInventoryMockRecorder is the mock recorder for MockInventory.
+type MockInventoryMockRecorder struct {
+	mock *MockInventory
+}
+
+// NewMockInventory creates a new mock instance.
+func NewMockInventory(ctrl *gomock.Controller) *MockInventory {
+	mock := &MockInventory{ctrl: ctrl}
+	mock.recorder = &MockInventoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInventory) EXPECT() *MockInventoryMockRecorder {
+	return m.recorder
+}
+
+// GetLowStockBooks mocks base method.
+func (m *MockInventory) GetLowStockBooks() ([]models.Book, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLowStockBooks")
+	ret0, _ := ret[0].([]models.Book)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLowStockBooks indicates an expected call of GetLowStockBooks.
+func (mr *MockInventoryMockRecorder) GetLowStockBooks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLowStockBooks", reflect.TypeOf((*MockInventory)(nil).GetLowStockBooks))
+}
+
+// MockReviews is a mock of Reviews interface.
+type MockReviews struct {
+	ctrl     *gomock.Controller
+	recorder *MockReviewsMockRecorder
+}
+
+// MockReviewsMockRecorder is the mock recorder for MockReviews.
+type MockReviewsMockRecorder struct {
+	mock *MockReviews
+}
+
+// NewMockReviews creates a new mock instance.
+func NewMockReviews(ctrl *gomock.Controller) *MockReviews {
+	mock := &MockReviews{ctrl: ctrl}
+	mock.recorder = &MockReviewsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReviews) EXPECT() *MockReviewsMockRecorder {
+	return m.recorder
+}
+
+// CreateReview mocks base method.
+func (m *MockReviews) CreateReview(review models.Review) (models.Review, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReview", review)
+	ret0, _ := ret[0].(models.Review)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReview indicates an expected call of CreateReview.
+func (mr *MockReviewsMockRecorder) CreateReview(review interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReview", reflect.TypeOf((*MockReviews)(nil).CreateReview), review)
+}
+
+// DeleteReview mocks base method.
+func (m *MockReviews) DeleteReview(bookID, id int, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReview", bookID, id, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReview indicates an expected call of DeleteReview.
+func (mr *MockReviewsMockRecorder) DeleteReview(bookID, id, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReview", reflect.TypeOf((*MockReviews)(nil).DeleteReview), bookID, id, token)
+}
+
+// GetPendingReviews mocks base method.
+func (m *MockReviews) GetPendingReviews(page models.Page) ([]models.Review, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPendingReviews", page)
+	ret0, _ := ret[0].([]models.Review)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPendingReviews indicates an expected call of GetPendingReviews.
+func (mr *MockReviewsMockRecorder) GetPendingReviews(page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPendingReviews", reflect.TypeOf((*MockReviews)(nil).GetPendingReviews), page)
+}
+
+// GetReviews mocks base method.
+func (m *MockReviews) GetReviews(bookID int, page models.Page) ([]models.Review, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReviews", bookID, page)
+	ret0, _ := ret[0].([]models.Review)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetReviews indicates an expected call of GetReviews.
+func (mr *MockReviewsMockRecorder) GetReviews(bookID, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviews", reflect.TypeOf((*MockReviews)(nil).GetReviews), bookID, page)
+}
+
+// ModerateReview mocks base method.
+func (m *MockReviews) ModerateReview(id int, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModerateReview", id, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ModerateReview indicates an expected call of ModerateReview.
+func (mr *MockReviewsMockRecorder) ModerateReview(id, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModerateReview", reflect.TypeOf((*MockReviews)(nil).ModerateReview), id, status)
+}
+
+// UpdateReview mocks base method.
+func (m *MockReviews) UpdateReview(bookID, id int, token string, review models.Review) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateReview", bookID, id, token, review)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateReview indicates an expected call of UpdateReview.
+func (mr *MockReviewsMockRecorder) UpdateReview(bookID, id, token, review interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReview", reflect.TypeOf((*MockReviews)(nil).UpdateReview), bookID, id, token, review)
+}
+
+// MockWaitlist is a mock of Waitlist interface.
+type MockWaitlist struct {
+	ctrl     *gomock.Controller
+	recorder *MockWaitlistMockRecorder
+}
+
+// MockWaitlistMockRecorder is the mock recorder for MockWaitlist.
+type MockWaitlistMockRecorder struct {
+	mock *MockWaitlist
+}
+
+// NewMockWaitlist creates a new mock instance.
+func NewMockWaitlist(ctrl *gomock.Controller) *MockWaitlist {
+	mock := &MockWaitlist{ctrl: ctrl}
+	mock.recorder = &MockWaitlistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWaitlist) EXPECT() *MockWaitlistMockRecorder {
+	return m.recorder
+}
+
+// AddToWaitlist mocks base method.
+func (m *MockWaitlist) AddToWaitlist(entry models.WaitlistEntry) (models.WaitlistEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToWaitlist", entry)
+	ret0, _ := ret[0].(models.WaitlistEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddToWaitlist indicates an expected call of AddToWaitlist.
+func (mr *MockWaitlistMockRecorder) AddToWaitlist(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToWaitlist", reflect.TypeOf((*MockWaitlist)(nil).AddToWaitlist), entry)
+}
+
+// MockCovers is a mock of Covers interface.
+type MockCovers struct {
+	ctrl     *gomock.Controller
+	recorder *MockCoversMockRecorder
+}
+
+// MockCoversMockRecorder is the mock recorder for MockCovers.
+type MockCoversMockRecorder struct {
+	mock *MockCovers
+}
+
+// NewMockCovers creates a new mock instance.
+func NewMockCovers(ctrl *gomock.Controller) *MockCovers {
+	mock := &MockCovers{ctrl: ctrl}
+	mock.recorder = &MockCoversMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCovers) EXPECT() *MockCoversMockRecorder {
+	return m.recorder
+}
+
+// GetCover mocks base method.
+func (m *MockCovers) GetCover(bookID int, file string) (storage.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCover", bookID, file)
+	ret0, _ := ret[0].(storage.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCover indicates an expected call of GetCover.
+func (mr *MockCoversMockRecorder) GetCover(bookID, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCover", reflect.TypeOf((*MockCovers)(nil).GetCover), bookID, file)
+}
+
+// SetCover mocks base method.
+func (m *MockCovers) SetCover(bookID int, image io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCover", bookID, image)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCover indicates an expected call of SetCover.
+func (mr *MockCoversMockRecorder) SetCover(bookID, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCover", reflect.TypeOf((*MockCovers)(nil).SetCover), bookID, image)
+}
+
+// MockLocations is a mock of Locations interface.
+type MockLocations struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocationsMockRecorder
+}
+
+// MockLocationsMockRecorder is the mock recorder for MockLocations.
+type MockLocationsMockRecorder struct {
+	mock *MockLocations
+}
+
+// NewMockLocations creates a new mock instance.
+func NewMockLocations(ctrl *gomock.Controller) *MockLocations {
+	mock := &MockLocations{ctrl: ctrl}
+	mock.recorder = &MockLocationsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocations) EXPECT() *MockLocationsMockRecorder {
+	return m.recorder
+}
+
+// CreateLocation mocks base method.
+func (m *MockLocations) CreateLocation(location models.Location) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocation", location)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocation indicates an expected call of CreateLocation.
+func (mr *MockLocationsMockRecorder) CreateLocation(location interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*MockLocations)(nil).CreateLocation), location)
+}
+
+// GetBookStock mocks base method.
+func (m *MockLocations) GetBookStock(bookID int) ([]models.StockLevel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBookStock", bookID)
+	ret0, _ := ret[0].([]models.StockLevel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBookStock indicates an expected call of GetBookStock.
+func (mr *MockLocationsMockRecorder) GetBookStock(bookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBookStock", reflect.TypeOf((*MockLocations)(nil).GetBookStock), bookID)
+}
+
+// GetLocationByID mocks base method.
+func (m *MockLocations) GetLocationByID(id int) (models.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationByID", id)
+	ret0, _ := ret[0].(models.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationByID indicates an expected call of GetLocationByID.
+func (mr *MockLocationsMockRecorder) GetLocationByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationByID", reflect.TypeOf((*MockLocations)(nil).GetLocationByID), id)
+}
+
+// GetLocationStock mocks base method.
+func (m *MockLocations) GetLocationStock(locationID int) ([]models.StockLevel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationStock", locationID)
+	ret0, _ := ret[0].([]models.StockLevel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationStock indicates an expected call of GetLocationStock.
+func (mr *MockLocationsMockRecorder) GetLocationStock(locationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationStock", reflect.TypeOf((*MockLocations)(nil).GetLocationStock), locationID)
+}
+
+// GetLocations mocks base method.
+func (m *MockLocations) GetLocations() ([]models.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocations")
+	ret0, _ := ret[0].([]models.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocations indicates an expected call of GetLocations.
+func (mr *MockLocationsMockRecorder) GetLocations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocations", reflect.TypeOf((*MockLocations)(nil).GetLocations))
+}
+
+// Transfer mocks base method.
+func (m *MockLocations) Transfer(transfer models.StockTransfer) (models.StockTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", transfer)
+	ret0, _ := ret[0].(models.StockTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockLocationsMockRecorder) Transfer(transfer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockLocations)(nil).Transfer), transfer)
+}
+
+// UpdateLocation mocks base method.
+func (m *MockLocations) UpdateLocation(id int, location models.Location) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLocation", id, location)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLocation indicates an expected call of UpdateLocation.
+func (mr *MockLocationsMockRecorder) UpdateLocation(id, location interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLocation", reflect.TypeOf((*MockLocations)(nil).UpdateLocation), id, location)
+}
+
+// MockSuppliers is a mock of Suppliers interface.
+type MockSuppliers struct {
+	ctrl     *gomock.Controller
+	recorder *MockSuppliersMockRecorder
+}
+
+// MockSuppliersMockRecorder is the mock recorder for MockSuppliers.
+type MockSuppliersMockRecorder struct {
+	mock *MockSuppliers
+}
+
+// NewMockSuppliers creates a new mock instance.
+func NewMockSuppliers(ctrl *gomock.Controller) *MockSuppliers {
+	mock := &MockSuppliers{ctrl: ctrl}
+	mock.recorder = &MockSuppliersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSuppliers) EXPECT() *MockSuppliersMockRecorder {
+	return m.recorder
+}
+
+// CreateSupplier mocks base method.
+func (m *MockSuppliers) CreateSupplier(supplier models.Supplier) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSupplier", supplier)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSupplier indicates an expected call of CreateSupplier.
+func (mr *MockSuppliersMockRecorder) CreateSupplier(supplier interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSupplier", reflect.TypeOf((*MockSuppliers)(nil).CreateSupplier), supplier)
+}
+
+// GetSupplierByID mocks base method.
+func (m *MockSuppliers) GetSupplierByID(id int) (models.Supplier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSupplierByID", id)
+	ret0, _ := ret[0].(models.Supplier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSupplierByID indicates an expected call of GetSupplierByID.
+func (mr *MockSuppliersMockRecorder) GetSupplierByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSupplierByID", reflect.TypeOf((*MockSuppliers)(nil).GetSupplierByID), id)
+}
+
+// GetSuppliers mocks base method.
+func (m *MockSuppliers) GetSuppliers() ([]models.Supplier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuppliers")
+	ret0, _ := ret[0].([]models.Supplier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuppliers indicates an expected call of GetSuppliers.
+func (mr *MockSuppliersMockRecorder) GetSuppliers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuppliers", reflect.TypeOf((*MockSuppliers)(nil).GetSuppliers))
+}
+
+// UpdateSupplier mocks base method.
+func (m *MockSuppliers) UpdateSupplier(id int, supplier models.Supplier) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSupplier", id, supplier)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSupplier indicates an expected call of UpdateSupplier.
+func (mr *MockSuppliersMockRecorder) UpdateSupplier(id, supplier interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSupplier", reflect.TypeOf((*MockSuppliers)(nil).UpdateSupplier), id, supplier)
+}
+
+// MockPurchaseOrders is a mock of PurchaseOrders interface.
+type MockPurchaseOrders struct {
+	ctrl     *gomock.Controller
+	recorder *MockPurchaseOrdersMockRecorder
+}
+
+// MockPurchaseOrdersMockRecorder is the mock recorder for MockPurchaseOrders.
+type MockPurchaseOrdersMockRecorder struct {
+	mock *MockPurchaseOrders
+}
+
+// NewMockPurchaseOrders creates a new mock instance.
+func NewMockPurchaseOrders(ctrl *gomock.Controller) *MockPurchaseOrders {
+	mock := &MockPurchaseOrders{ctrl: ctrl}
+	mock.recorder = &MockPurchaseOrdersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPurchaseOrders) EXPECT() *MockPurchaseOrdersMockRecorder {
+	return m.recorder
+}
+
+// CancelPurchaseOrder mocks base method.
+func (m *MockPurchaseOrders) CancelPurchaseOrder(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPurchaseOrder", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelPurchaseOrder indicates an expected call of CancelPurchaseOrder.
+func (mr *MockPurchaseOrdersMockRecorder) CancelPurchaseOrder(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPurchaseOrder", reflect.TypeOf((*MockPurchaseOrders)(nil).CancelPurchaseOrder), id)
+}
+
+// CreatePurchaseOrder mocks base method.
+func (m *MockPurchaseOrders) CreatePurchaseOrder(order models.PurchaseOrder) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePurchaseOrder", order)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePurchaseOrder indicates an expected call of CreatePurchaseOrder.
+func (mr *MockPurchaseOrdersMockRecorder) CreatePurchaseOrder(order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePurchaseOrder", reflect.TypeOf((*MockPurchaseOrders)(nil).CreatePurchaseOrder), order)
+}
+
+// GetOutstandingOrders mocks base method.
+func (m *MockPurchaseOrders) GetOutstandingOrders() ([]models.OutstandingOrders, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutstandingOrders")
+	ret0, _ := ret[0].([]models.OutstandingOrders)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutstandingOrders indicates an expected call of GetOutstandingOrders.
+func (mr *MockPurchaseOrdersMockRecorder) GetOutstandingOrders() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutstandingOrders", reflect.TypeOf((*MockPurchaseOrders)(nil).GetOutstandingOrders))
+}
+
+// GetPurchaseOrderByID mocks base method.
+func (m *MockPurchaseOrders) GetPurchaseOrderByID(id int) (models.PurchaseOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPurchaseOrderByID", id)
+	ret0, _ := ret[0].(models.PurchaseOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPurchaseOrderByID indicates an expected call of GetPurchaseOrderByID.
+func (mr *MockPurchaseOrdersMockRecorder) GetPurchaseOrderByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurchaseOrderByID", reflect.TypeOf((*MockPurchaseOrders)(nil).GetPurchaseOrderByID), id)
+}
+
+// GetPurchaseOrders mocks base method.
+func (m *MockPurchaseOrders) GetPurchaseOrders(supplierID int, status string) ([]models.PurchaseOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPurchaseOrders", supplierID, status)
+	ret0, _ := ret[0].([]models.PurchaseOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPurchaseOrders indicates an expected call of GetPurchaseOrders.
+func (mr *MockPurchaseOrdersMockRecorder) GetPurchaseOrders(supplierID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurchaseOrders", reflect.TypeOf((*MockPurchaseOrders)(nil).GetPurchaseOrders), supplierID, status)
+}
+
+// Receive mocks base method.
+func (m *MockPurchaseOrders) Receive(id int, delivery models.Delivery) (models.PurchaseOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Receive", id, delivery)
+	ret0, _ := ret[0].(models.PurchaseOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Receive indicates an expected call of Receive.
+func (mr *MockPurchaseOrdersMockRecorder) Receive(id, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockPurchaseOrders)(nil).Receive), id, delivery)
+}
+
+// SendPurchaseOrder mocks base method.
+func (m *MockPurchaseOrders) SendPurchaseOrder(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPurchaseOrder", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPurchaseOrder indicates an expected call of SendPurchaseOrder.
+func (mr *MockPurchaseOrdersMockRecorder) SendPurchaseOrder(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPurchaseOrder", reflect.TypeOf((*MockPurchaseOrders)(nil).SendPurchaseOrder), id)
+}
+
+// MockStocktakes is a mock of Stocktakes interface.
+type MockStocktakes struct {
+	ctrl     *gomock.Controller
+	recorder *MockStocktakesMockRecorder
+}
+
+// MockStocktakesMockRecorder is the mock recorder for MockStocktakes.
+type MockStocktakesMockRecorder struct {
+	mock *MockStocktakes
+}
+
+// NewMockStocktakes creates a new mock instance.
+func NewMockStocktakes(ctrl *gomock.Controller) *MockStocktakes {
+	mock := &MockStocktakes{ctrl: ctrl}
+	mock.recorder = &MockStocktakesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStocktakes) EXPECT() *MockStocktakesMockRecorder {
+	return m.recorder
+}
+
+// AddCount mocks base method.
+func (m *MockStocktakes) AddCount(id int, count models.StocktakeCount) (models.StocktakeItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCount", id, count)
+	ret0, _ := ret[0].(models.StocktakeItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddCount indicates an expected call of AddCount.
+func (mr *MockStocktakesMockRecorder) AddCount(id, count interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCount", reflect.TypeOf((*MockStocktakes)(nil).AddCount), id, count)
+}
+
+// CreateStocktake mocks base method.
+func (m *MockStocktakes) CreateStocktake(stocktake models.Stocktake) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStocktake", stocktake)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStocktake indicates an expected call of CreateStocktake.
+func (mr *MockStocktakesMockRecorder) CreateStocktake(stocktake interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStocktake", reflect.TypeOf((*MockStocktakes)(nil).CreateStocktake), stocktake)
+}
+
+// FinalizeStocktake mocks base method.
+func (m *MockStocktakes) FinalizeStocktake(id int) (models.Stocktake, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinalizeStocktake", id)
+	ret0, _ := ret[0].(models.Stocktake)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinalizeStocktake indicates an expected call of FinalizeStocktake.
+func (mr *MockStocktakesMockRecorder) FinalizeStocktake(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalizeStocktake", reflect.TypeOf((*MockStocktakes)(nil).FinalizeStocktake), id)
+}
+
+// GetStocktakeByID mocks base method.
+func (m *MockStocktakes) GetStocktakeByID(id int) (models.Stocktake, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStocktakeByID", id)
+	ret0, _ := ret[0].(models.Stocktake)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStocktakeByID indicates an expected call of GetStocktakeByID.
+func (mr *MockStocktakesMockRecorder) GetStocktakeByID(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStocktakeByID", reflect.TypeOf((*MockStocktakes)(nil).GetStocktakeByID), id)
+}
+
+// GetStocktakes mocks base method.
+func (m *MockStocktakes) GetStocktakes() ([]models.Stocktake, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStocktakes")
+	ret0, _ := ret[0].([]models.Stocktake)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStocktakes indicates an expected call of GetStocktakes.
+func (mr *MockStocktakesMockRecorder) GetStocktakes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStocktakes", reflect.TypeOf((*MockStocktakes)(nil).GetStocktakes))
+}
+
+// GetVariances mocks base method.
+func (m *MockStocktakes) GetVariances(id int) ([]models.StocktakeVariance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVariances", id)
+	ret0, _ := ret[0].([]models.StocktakeVariance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVariances indicates an expected call of GetVariances.
+func (mr *MockStocktakesMockRecorder) GetVariances(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVariances", reflect.TypeOf((*MockStocktakes)(nil).GetVariances), id)
+}
+
+// SetCounts mocks base method.
+func (m *MockStocktakes) SetCounts(id int, counts models.StocktakeCounts) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCounts", id, counts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCounts indicates an expected call of SetCounts.
+func (mr *MockStocktakesMockRecorder) SetCounts(id, counts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCounts", reflect.TypeOf((*MockStocktakes)(nil).SetCounts), id, counts)
+}
+
+// MockUsers is a mock of Users interface.
+type MockUsers struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersMockRecorder
+}
+
+// MockUsersMockRecorder is the mock recorder for MockUsers.
+type MockUsersMockRecorder struct {
+	mock *MockUsers
+}
+
+// NewMockUsers creates a new mock instance.
+func NewMockUsers(ctrl *gomock.Controller) *MockUsers {
+	mock := &MockUsers{ctrl: ctrl}
+	mock.recorder = &MockUsersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockUsers) CreateUser(user models.User) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", user)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUsersMockRecorder) CreateUser(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsers)(nil).CreateUser), user)
+}
+
+// MockStream is a mock of Stream interface.
+type MockStream struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamMockRecorder
+}
+
+// MockStreamMockRecorder is the mock recorder for MockStream.
+type MockStreamMockRecorder struct {
+	mock *MockStream
+}
+
+// NewMockStream creates a new mock instance.
+func NewMockStream(ctrl *gomock.Controller) *MockStream {
+	mock := &MockStream{ctrl: ctrl}
+	mock.recorder = &MockStreamMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStream) EXPECT() *MockStreamMockRecorder {
+	return m.recorder
+}
+
+// Subscribe mocks base method.
+func (m *MockStream) Subscribe(lastEventID int64) ([]models.StreamEvent, <-chan models.StreamEvent, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", lastEventID)
+	ret0, _ := ret[0].([]models.StreamEvent)
+	ret1, _ := ret[1].(<-chan models.StreamEvent)
+	ret2, _ := ret[2].(func())
+	return ret0, ret1, ret2
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockStreamMockRecorder) Subscribe(lastEventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockStream)(nil).Subscribe), lastEventID)
+}
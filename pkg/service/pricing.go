@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"math/big"
+	"strings"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// PricingService converts book prices to other currencies. A price from the price list of
+// a book wins over the conversion with exchange rates.
+type PricingService struct {
+	repo  repository.Prices
+	rates map[string]*big.Rat
+}
+
+// NewPricingService takes exchange rates as decimal strings, in units of the currency per
+// unit of a common base currency, which has the rate 1.
+func NewPricingService(repo repository.Prices, rates map[string]string) (*PricingService, error) {
+	s := &PricingService{repo: repo, rates: make(map[string]*big.Rat, len(rates))}
+	for currency, rate := range rates {
+		r, ok := new(big.Rat).SetString(rate)
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q of %s", rate, currency)
+		}
+		// Configuration keys are case-insensitive and come lowercased.
+		s.rates[strings.ToUpper(currency)] = r
+	}
+	return s, nil
+}
+
+func (s *PricingService) Convert(books []models.Book, currency string) ([]models.Book, error) {
+	if _, ok := s.rates[currency]; !ok {
+		return nil, ErrUnknownCurrency
+	}
+	ids := make([]int, 0, len(books))
+	for _, book := range books {
+		if book.Currency != currency {
+			ids = append(ids, book.ID)
+		}
+	}
+	listed := make(map[int]models.Money)
+	if len(ids) > 0 {
+		prices, err := s.repo.GetPricesByBooks(ids, currency)
+		if err != nil {
+			return nil, err
+		}
+		for _, price := range prices {
+			listed[price.BookID] = price.Price
+		}
+	}
+
+	converted := make([]models.Book, len(books))
+	for i, book := range books {
+		converted[i] = book
+		if book.Currency == currency {
+			continue
+		}
+		// Amounts are scaled by the exchange rates, or by the ratio of the listed price to
+		// the own price of the book, so discounts keep their share of the price.
+		from, to := s.rates[book.Currency], s.rates[currency]
+		listedPrice, ok := listed[book.ID]
+		switch {
+		case ok && book.Price > 0:
+			from, to = big.NewRat(int64(book.Price), 1), big.NewRat(int64(listedPrice), 1)
+		case ok:
+			from, to = big.NewRat(1, 1), big.NewRat(1, 1)
+		case from == nil:
+			return nil, fmt.Errorf("no exchange rate for %s", book.Currency)
+		}
+		converted[i].Currency = currency
+		converted[i].Price = convertMoney(book.Price, from, to)
+		if ok {
+			converted[i].Price = listedPrice
+		}
+		if book.EffectivePrice != nil {
+			effectivePrice := convertMoney(*book.EffectivePrice, from, to)
+			converted[i].EffectivePrice = &effectivePrice
+		}
+		if len(book.Promotions) > 0 {
+			converted[i].Promotions = make([]models.AppliedPromotion, len(book.Promotions))
+			for j, promotion := range book.Promotions {
+				if promotion.Type == models.PromotionFixed {
+					promotion.Value = convertMoney(promotion.Value, from, to)
+				}
+				converted[i].Promotions[j] = promotion
+			}
+		}
+	}
+	return converted, nil
+}
+
+func (s *PricingService) GetPrices(bookID int) ([]models.BookPrice, error) {
+	return s.repo.GetPrices(bookID)
+}
+
+func (s *PricingService) SetPrice(price models.BookPrice) error {
+	if _, ok := s.rates[price.Currency]; !ok {
+		return ErrUnknownCurrency
+	}
+	return s.repo.SetPrice(price)
+}
+
+func (s *PricingService) DeletePrice(bookID int, currency string) error {
+	return s.repo.DeletePrice(bookID, currency)
+}
+
+// convertMoney returns amount*to/from rounded half away from zero to whole minor units.
+func convertMoney(amount models.Money, from, to *big.Rat) models.Money {
+	value := new(big.Rat).SetInt64(int64(amount))
+	value.Mul(value, to).Quo(value, from)
+	num, denom := value.Num(), value.Denom()
+	quo, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if rem.Mul(rem.Abs(rem), big.NewInt(2)).Cmp(denom) >= 0 {
+		quo.Add(quo, big.NewInt(int64(num.Sign())))
+	}
+	return models.Money(quo.Int64())
+}
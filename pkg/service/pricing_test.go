@@ -0,0 +1,114 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// pricesRepo keeps price lists in memory.
+type pricesRepo struct {
+	prices []models.BookPrice
+}
+
+func (r *pricesRepo) GetPrices(bookID int) ([]models.BookPrice, error) {
+	return nil, nil
+}
+
+func (r *pricesRepo) GetPricesByBooks(bookIDs []int, currency string) ([]models.BookPrice, error) {
+	var prices []models.BookPrice
+	for _, price := range r.prices {
+		for _, id := range bookIDs {
+			if price.BookID == id && price.Currency == currency {
+				prices = append(prices, price)
+			}
+		}
+	}
+	return prices, nil
+}
+
+func (r *pricesRepo) SetPrice(price models.BookPrice) error {
+	r.prices = append(r.prices, price)
+	return nil
+}
+
+func (r *pricesRepo) DeletePrice(bookID int, currency string) error {
+	return nil
+}
+
+func TestPricingConvert(t *testing.T) {
+	repo := &pricesRepo{prices: []models.BookPrice{{BookID: 2, Currency: "EUR", Price: 1000}}}
+	s, err := NewPricingService(repo, map[string]string{"usd": "1", "eur": "0.88", "jpy": "113.5"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		currency      string
+		book          models.Book
+		expectedPrice models.Money
+		expectedError error
+	}{
+		{name: "Same currency", currency: "USD", book: models.Book{ID: 1, Price: 6788, Currency: "USD"}, expectedPrice: 6788},
+		{name: "Converted", currency: "EUR", book: models.Book{ID: 1, Price: 6788, Currency: "USD"}, expectedPrice: 5973},
+		{name: "Rounded", currency: "EUR", book: models.Book{ID: 1, Price: 1, Currency: "USD"}, expectedPrice: 1},
+		{name: "Between rates", currency: "USD", book: models.Book{ID: 1, Price: 11350, Currency: "JPY"}, expectedPrice: 100},
+		{name: "Price list", currency: "EUR", book: models.Book{ID: 2, Price: 6788, Currency: "USD"}, expectedPrice: 1000},
+		{name: "Unknown currency", currency: "CHF", book: models.Book{ID: 1, Price: 6788, Currency: "USD"}, expectedError: ErrUnknownCurrency},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			books, err := s.Convert([]models.Book{test.book}, test.currency)
+			assert.Equal(t, test.expectedError, err)
+			if err == nil {
+				assert.Equal(t, test.expectedPrice, books[0].Price)
+				assert.Equal(t, test.currency, books[0].Currency)
+			}
+		})
+	}
+}
+
+func TestNewPricingServiceInvalidRate(t *testing.T) {
+	_, err := NewPricingService(&pricesRepo{}, map[string]string{"EUR": "0"})
+	assert.Error(t, err)
+}
+
+func TestPricingConvertEffectivePrice(t *testing.T) {
+	repo := &pricesRepo{prices: []models.BookPrice{{BookID: 2, Currency: "EUR", Price: 1000}}}
+	s, err := NewPricingService(repo, map[string]string{"USD": "1", "EUR": "0.5"})
+	assert.NoError(t, err)
+
+	effectivePrice := models.Money(1500)
+	books, err := s.Convert([]models.Book{
+		{ID: 1, Price: 2000, Currency: "USD", EffectivePrice: &effectivePrice,
+			Promotions: []models.AppliedPromotion{{ID: 1, Type: models.PromotionFixed, Value: 500}}},
+		{ID: 2, Price: 2000, Currency: "USD", EffectivePrice: &effectivePrice},
+	}, "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(750), *books[0].EffectivePrice)
+	assert.Equal(t, models.Money(250), books[0].Promotions[0].Value)
+	assert.Equal(t, models.Money(1000), books[1].Price)
+	assert.Equal(t, models.Money(750), *books[1].EffectivePrice)
+}
+
+func TestUseCurrencies(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+	repo := mock_service.NewMockBooksManager(c)
+	s := NewService(repo)
+	s.UseCurrencies(map[string]string{"usd": "1", "eur": "0.88"})
+
+	repo.EXPECT().CreateBook(models.Book{Name: "hello", Currency: "EUR"}).Return(1, nil)
+	_, err := s.CreateBook(models.Book{Name: "hello", Currency: "EUR"})
+	assert.NoError(t, err)
+
+	repo.EXPECT().CreateBook(models.Book{Name: "hello", Currency: "USD"}).Return(2, nil)
+	_, err = s.CreateBook(models.Book{Name: "hello"})
+	assert.NoError(t, err)
+
+	_, err = s.CreateBook(models.Book{Name: "hello", Currency: "CHF"})
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+	_, err = s.UpdateBookByID(1, models.Book{Name: "hello", Currency: "CHF"})
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"math/big"
+	"strconv"
+)
+
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+type PromotionsService struct {
+	repo repository.Promotions
+}
+
+func NewPromotionsService(repo repository.Promotions) *PromotionsService {
+	return &PromotionsService{repo: repo}
+}
+
+func (s *PromotionsService) GetPromotions() ([]models.Promotion, error) {
+	return s.repo.GetPromotions()
+}
+
+func (s *PromotionsService) GetPromotionByID(id int) (models.Promotion, error) {
+	return s.repo.GetPromotionByID(id)
+}
+
+func (s *PromotionsService) CreatePromotion(promotion models.Promotion) (int, error) {
+	if err := validatePromotion(promotion); err != nil {
+		return 0, err
+	}
+	return s.repo.CreatePromotion(inUTC(promotion))
+}
+
+func (s *PromotionsService) UpdatePromotion(id int, promotion models.Promotion) error {
+	if err := validatePromotion(promotion); err != nil {
+		return err
+	}
+	return s.repo.UpdatePromotion(id, inUTC(promotion))
+}
+
+func (s *PromotionsService) DeletePromotion(id int) error {
+	return s.repo.DeletePromotion(id)
+}
+
+// validatePromotion checks the rules the binding tags cannot express.
+func validatePromotion(promotion models.Promotion) error {
+	if promotion.Type == models.PromotionPercentage && promotion.Value > 100*100 {
+		return ErrInvalidPromotion
+	}
+	if promotion.Scope == models.PromotionScopeGenre || promotion.Scope == models.PromotionScopeBook {
+		if _, err := strconv.Atoi(promotion.Target); err != nil {
+			return ErrInvalidPromotion
+		}
+	}
+	return nil
+}
+
+// inUTC returns the promotion with its times in UTC, they are stored without their zone.
+func inUTC(promotion models.Promotion) models.Promotion {
+	promotion.StartsAt = promotion.StartsAt.UTC()
+	promotion.EndsAt = promotion.EndsAt.UTC()
+	return promotion
+}
+
+// promotionApplies reports whether the book is in the scope of the promotion.
+func promotionApplies(promotion models.Promotion, book models.Book) bool {
+	switch promotion.Scope {
+	case models.PromotionScopeGenre:
+		return promotion.Target == strconv.Itoa(book.Genre)
+	case models.PromotionScopeBook:
+		return promotion.Target == strconv.Itoa(book.ID)
+	case models.PromotionScopeAuthor:
+		return book.Author != "" && promotion.Target == book.Author
+	}
+	return false
+}
+
+// applyPromotions sets the effective price of the book. Promotions must be ordered by
+// priority, the first matching one applies and further ones are stacked on top of it
+// as long as both the applied and the next matching promotion are stackable.
+func applyPromotions(book *models.Book, promotions []models.Promotion) {
+	price := book.Price
+	book.Promotions = nil
+	for _, promotion := range promotions {
+		if !promotionApplies(promotion, *book) {
+			continue
+		}
+		if len(book.Promotions) > 0 && !promotion.Stackable {
+			continue
+		}
+		switch promotion.Type {
+		case models.PromotionPercentage:
+			price = convertMoney(price, big.NewRat(100*100, 1), big.NewRat(int64(100*100-promotion.Value), 1))
+		case models.PromotionFixed:
+			price -= promotion.Value
+		}
+		if price < 0 {
+			price = 0
+		}
+		book.Promotions = append(book.Promotions, models.AppliedPromotion{
+			ID:    promotion.ID,
+			Name:  promotion.Name,
+			Type:  promotion.Type,
+			Value: promotion.Value,
+		})
+		if !promotion.Stackable {
+			break
+		}
+	}
+	book.EffectivePrice = &price
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// promotionsRepo returns the promotions running at the time, highest priority first.
+type promotionsRepo struct {
+	promotions []models.Promotion
+	active     []time.Time
+}
+
+func (r *promotionsRepo) GetPromotions() ([]models.Promotion, error) {
+	return r.promotions, nil
+}
+
+func (r *promotionsRepo) GetPromotionByID(id int) (models.Promotion, error) {
+	return models.Promotion{}, nil
+}
+
+func (r *promotionsRepo) GetActivePromotions(at time.Time) ([]models.Promotion, error) {
+	r.active = append(r.active, at)
+	var promotions []models.Promotion
+	for _, promotion := range r.promotions {
+		if !promotion.StartsAt.After(at) && promotion.EndsAt.After(at) {
+			promotions = append(promotions, promotion)
+		}
+	}
+	return promotions, nil
+}
+
+func (r *promotionsRepo) CreatePromotion(promotion models.Promotion) (int, error) {
+	r.promotions = append(r.promotions, promotion)
+	return len(r.promotions), nil
+}
+
+func (r *promotionsRepo) UpdatePromotion(id int, promotion models.Promotion) error {
+	r.promotions[id-1] = promotion
+	return nil
+}
+
+func (r *promotionsRepo) DeletePromotion(id int) error {
+	return nil
+}
+
+func TestBooksManagerServicePromotions(t *testing.T) {
+	now := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	weekend := func(p models.Promotion) models.Promotion {
+		p.StartsAt, p.EndsAt = now.Add(-time.Hour), now.Add(24*time.Hour)
+		return p
+	}
+	tests := []struct {
+		name                   string
+		promotions             []models.Promotion
+		expectedEffectivePrice models.Money
+		expectedPromotions     []int
+	}{
+		{
+			name:                   "No promotions",
+			expectedEffectivePrice: 6788,
+		},
+		{
+			name: "Percentage of genre",
+			promotions: []models.Promotion{
+				weekend(models.Promotion{ID: 1, Type: models.PromotionPercentage, Value: 2000, Scope: models.PromotionScopeGenre, Target: "3"}),
+			},
+			expectedEffectivePrice: 5430,
+			expectedPromotions:     []int{1},
+		},
+		{
+			name: "Other scopes",
+			promotions: []models.Promotion{
+				weekend(models.Promotion{ID: 1, Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeGenre, Target: "1"}),
+				weekend(models.Promotion{ID: 2, Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeBook, Target: "8"}),
+				weekend(models.Promotion{ID: 3, Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeAuthor, Target: "Someone"}),
+			},
+			expectedEffectivePrice: 6788,
+		},
+		{
+			name: "Not started",
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeBook, Target: "7",
+					StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+			},
+			expectedEffectivePrice: 6788,
+		},
+		{
+			name: "Highest priority wins",
+			promotions: []models.Promotion{
+				weekend(models.Promotion{ID: 2, Type: models.PromotionFixed, Value: 1000, Scope: models.PromotionScopeAuthor, Target: "Tolkien", Priority: 10}),
+				weekend(models.Promotion{ID: 1, Type: models.PromotionPercentage, Value: 5000, Scope: models.PromotionScopeGenre, Target: "3"}),
+			},
+			expectedEffectivePrice: 5788,
+			expectedPromotions:     []int{2},
+		},
+		{
+			name: "Stacked",
+			promotions: []models.Promotion{
+				weekend(models.Promotion{ID: 2, Type: models.PromotionFixed, Value: 788, Scope: models.PromotionScopeBook, Target: "7", Priority: 10, Stackable: true}),
+				weekend(models.Promotion{ID: 3, Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeBook, Target: "7", Priority: 5}),
+				weekend(models.Promotion{ID: 1, Type: models.PromotionPercentage, Value: 1000, Scope: models.PromotionScopeGenre, Target: "3", Stackable: true}),
+			},
+			expectedEffectivePrice: 5400,
+			expectedPromotions:     []int{2, 1},
+		},
+		{
+			name: "Not below zero",
+			promotions: []models.Promotion{
+				weekend(models.Promotion{ID: 1, Type: models.PromotionFixed, Value: 10000, Scope: models.PromotionScopeBook, Target: "7"}),
+			},
+			expectedEffectivePrice: 0,
+			expectedPromotions:     []int{1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mockManager := mock_service.NewMockBooksManager(c)
+			mockManager.EXPECT().GetBookByID(7).
+				Return(models.Book{ID: 7, Author: "Tolkien", Price: 6788, Currency: "USD", Genre: 3}, nil)
+			s := NewService(mockManager)
+			s.UsePromotions(&promotionsRepo{promotions: test.promotions})
+			s.now = func() time.Time { return now }
+
+			book, err := s.GetBookByID(7)
+			assert.NoError(t, err)
+			assert.Equal(t, models.Money(6788), book.Price)
+			if assert.NotNil(t, book.EffectivePrice) {
+				assert.Equal(t, test.expectedEffectivePrice, *book.EffectivePrice)
+			}
+			var applied []int
+			for _, promotion := range book.Promotions {
+				applied = append(applied, promotion.ID)
+			}
+			assert.Equal(t, test.expectedPromotions, applied)
+		})
+	}
+}
+
+func TestPromotionsServiceValidation(t *testing.T) {
+	s := NewPromotionsService(&promotionsRepo{})
+	_, err := s.CreatePromotion(models.Promotion{Type: models.PromotionPercentage, Value: 10001, Scope: models.PromotionScopeAuthor})
+	assert.Equal(t, ErrInvalidPromotion, err)
+	_, err = s.CreatePromotion(models.Promotion{Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeGenre, Target: "fantasy"})
+	assert.Equal(t, ErrInvalidPromotion, err)
+	_, err = s.CreatePromotion(models.Promotion{Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeGenre, Target: "3"})
+	assert.NoError(t, err)
+}
+
+func TestPromotionsInUTC(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	startsAt := time.Date(2021, 11, 20, 0, 0, 0, 0, zone)
+	repo := &promotionsRepo{}
+	s := NewPromotionsService(repo)
+
+	promotion := models.Promotion{Type: models.PromotionFixed, Value: 100, Scope: models.PromotionScopeAuthor, Target: "Tolkien",
+		StartsAt: startsAt, EndsAt: startsAt.Add(48 * time.Hour)}
+	id, err := s.CreatePromotion(promotion)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 11, 19, 22, 0, 0, 0, time.UTC), repo.promotions[0].StartsAt)
+	assert.Equal(t, time.UTC, repo.promotions[0].EndsAt.Location())
+
+	promotion.EndsAt = startsAt.Add(24 * time.Hour)
+	assert.NoError(t, s.UpdatePromotion(id, promotion))
+	assert.Equal(t, time.Date(2021, 11, 20, 22, 0, 0, 0, time.UTC), repo.promotions[0].EndsAt)
+
+	// Running promotions are looked up at the time in UTC too.
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockManager := mock_service.NewMockBooksManager(c)
+	mockManager.EXPECT().GetBookByID(7).Return(models.Book{ID: 7, Author: "Tolkien", Price: 6788, Currency: "USD"}, nil)
+	books := NewService(mockManager)
+	books.UsePromotions(repo)
+	books.now = func() time.Time { return startsAt.Add(time.Hour) }
+	book, err := books.GetBookByID(7)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money(6688), *book.EffectivePrice)
+	assert.Equal(t, []time.Time{time.Date(2021, 11, 19, 23, 0, 0, 0, time.UTC)}, repo.active)
+}
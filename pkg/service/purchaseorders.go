@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+)
+
+var (
+	ErrInvalidPurchaseOrder = errors.New("book is ordered twice")
+	ErrInvalidStatusChange  = repository.ErrInvalidStatusChange
+	ErrNotOrdered           = repository.ErrNotOrdered
+	ErrOverReceived         = repository.ErrOverReceived
+)
+
+// PurchaseOrdersService restocks books from suppliers. Received books are announced like any stock change.
+type PurchaseOrdersService struct {
+	repo  repository.PurchaseOrders
+	stock StockObserver
+	clock clock.Clock
+}
+
+func NewPurchaseOrdersService(repo repository.PurchaseOrders, stock StockObserver, clock clock.Clock) *PurchaseOrdersService {
+	return &PurchaseOrdersService{repo: repo, stock: stock, clock: clock}
+}
+
+func (s *PurchaseOrdersService) GetPurchaseOrders(supplierID int, status string) ([]models.PurchaseOrder, error) {
+	return s.repo.GetPurchaseOrders(supplierID, status)
+}
+
+func (s *PurchaseOrdersService) GetPurchaseOrderByID(id int) (models.PurchaseOrder, error) {
+	return s.repo.GetPurchaseOrderByID(id)
+}
+
+func (s *PurchaseOrdersService) CreatePurchaseOrder(order models.PurchaseOrder) (int, error) {
+	ordered := make(map[int]bool, len(order.Items))
+	for _, item := range order.Items {
+		if ordered[item.BookID] {
+			return 0, ErrInvalidPurchaseOrder
+		}
+		ordered[item.BookID] = true
+	}
+	order.CreatedAt = s.clock.Now().UTC()
+	order.UpdatedAt = order.CreatedAt
+	return s.repo.CreatePurchaseOrder(order)
+}
+
+// SendPurchaseOrder marks a draft purchase order as sent to the supplier, its books can be received from then on.
+func (s *PurchaseOrdersService) SendPurchaseOrder(id int) error {
+	return s.repo.SetPurchaseOrderStatus(id, models.PurchaseOrderSent, s.clock.Now().UTC())
+}
+
+// CancelPurchaseOrder cancels the books of the purchase order that are not received yet.
+func (s *PurchaseOrdersService) CancelPurchaseOrder(id int) error {
+	return s.repo.SetPurchaseOrderStatus(id, models.PurchaseOrderCancelled, s.clock.Now().UTC())
+}
+
+func (s *PurchaseOrdersService) Receive(id int, delivery models.Delivery) (models.PurchaseOrder, error) {
+	changes, err := s.repo.Receive(id, delivery.Items, s.clock.Now().UTC())
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+	s.stock.StockChanged(changes)
+	return s.repo.GetPurchaseOrderByID(id)
+}
+
+func (s *PurchaseOrdersService) GetOutstandingOrders() ([]models.OutstandingOrders, error) {
+	return s.repo.GetOutstandingOrders()
+}
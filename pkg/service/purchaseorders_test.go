@@ -0,0 +1,61 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// purchaseOrdersRepo receives the books of its only purchase order, other methods are not used.
+type purchaseOrdersRepo struct {
+	repository.PurchaseOrders
+	created []models.PurchaseOrder
+	book    models.Book
+}
+
+func (r *purchaseOrdersRepo) CreatePurchaseOrder(order models.PurchaseOrder) (int, error) {
+	r.created = append(r.created, order)
+	return len(r.created), nil
+}
+
+func (r *purchaseOrdersRepo) Receive(id int, receipts []models.PurchaseOrderReceipt, at time.Time) ([]models.StockChange, error) {
+	previous := r.book.Amount
+	r.book.Amount += receipts[0].Quantity
+	return []models.StockChange{{PreviousAmount: previous, Book: r.book}}, nil
+}
+
+func (r *purchaseOrdersRepo) GetPurchaseOrderByID(id int) (models.PurchaseOrder, error) {
+	return models.PurchaseOrder{ID: id, Status: models.PurchaseOrderReceived}, nil
+}
+
+func TestPurchaseOrdersService(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	books := NewService(mock_service.NewMockBooksManager(c))
+	recorder := &eventRecorder{}
+	books.Subscribe(recorder)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &purchaseOrdersRepo{book: models.Book{ID: 1, Amount: 0, ReorderThreshold: 2}}
+	s := NewPurchaseOrdersService(repo, books, clock.NewFake(now))
+
+	_, err := s.CreatePurchaseOrder(models.PurchaseOrder{SupplierID: 1, Items: []models.PurchaseOrderItem{
+		{BookID: 1, Quantity: 2}, {BookID: 1, Quantity: 3},
+	}})
+	assert.ErrorIs(t, err, ErrInvalidPurchaseOrder)
+	id, err := s.CreatePurchaseOrder(models.PurchaseOrder{SupplierID: 1, Items: []models.PurchaseOrderItem{{BookID: 1, Quantity: 5}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, now, repo.created[0].CreatedAt)
+
+	order, err := s.Receive(id, models.Delivery{Items: []models.PurchaseOrderReceipt{{BookID: 1, Quantity: 5}}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PurchaseOrderReceived, order.Status)
+	// Received books are announced like sold ones, so waitlists learn the book is back in stock.
+	assert.Equal(t, []string{models.EventBookUpdated, models.EventBookBackInStock}, recorder.events)
+}
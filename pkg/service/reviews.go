@@ -0,0 +1,113 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+)
+
+var ErrInvalidReviewToken = errors.New("invalid review token")
+
+// ReviewsService keeps reviews in moderation until staff approve them. Authors manage their
+// reviews with the token they get on creation.
+type ReviewsService struct {
+	repo  repository.Reviews
+	cache CacheInvalidator
+}
+
+// NewReviewsService takes the cache of book reads to drop books whose rating changes, it may be nil.
+func NewReviewsService(repo repository.Reviews, cache CacheInvalidator) *ReviewsService {
+	return &ReviewsService{repo: repo, cache: cache}
+}
+
+func (s *ReviewsService) CreateReview(review models.Review) (models.Review, error) {
+	var err error
+	if review.Token, review.TokenHash, err = newToken(); err != nil {
+		return review, err
+	}
+	review.Status = models.ReviewPending
+	id, err := s.repo.CreateReview(review)
+	review.ID = id
+	return review, err
+}
+
+func (s *ReviewsService) GetReviews(bookID int, page models.Page) ([]models.Review, int64, error) {
+	return s.repo.GetReviews(bookID, models.ReviewApproved, page)
+}
+
+func (s *ReviewsService) UpdateReview(bookID, id int, token string, review models.Review) error {
+	if err := s.checkToken(bookID, id, token); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateReview(id, review); err != nil {
+		return err
+	}
+	s.invalidate(bookID)
+	return nil
+}
+
+func (s *ReviewsService) DeleteReview(bookID, id int, token string) error {
+	if err := s.checkToken(bookID, id, token); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteReview(id); err != nil {
+		return err
+	}
+	s.invalidate(bookID)
+	return nil
+}
+
+// GetPendingReviews returns the moderation queue.
+func (s *ReviewsService) GetPendingReviews(page models.Page) ([]models.Review, int64, error) {
+	return s.repo.GetReviews(0, models.ReviewPending, page)
+}
+
+func (s *ReviewsService) ModerateReview(id int, status string) error {
+	review, err := s.repo.SetReviewStatus(id, status)
+	if err != nil {
+		return err
+	}
+	s.invalidate(review.BookID)
+	return nil
+}
+
+// checkToken makes sure the review of the book was written by the holder of the token.
+func (s *ReviewsService) checkToken(bookID, id int, token string) error {
+	review, err := s.repo.GetReviewByID(id)
+	if err != nil {
+		return err
+	}
+	if review.BookID != bookID || !checkTokenHash(review.TokenHash, token) {
+		return ErrInvalidReviewToken
+	}
+	return nil
+}
+
+func (s *ReviewsService) invalidate(bookID int) {
+	if s.cache != nil {
+		s.cache.Invalidate(bookID)
+	}
+}
+
+// newToken returns a random token and its hash, the hash is stored and checked with checkTokenHash.
+func newToken() (string, string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(token), hashToken(hex.EncodeToString(token)), nil
+}
+
+// checkTokenHash reports whether token is the one stored as hash.
+func checkTokenHash(hash, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(token))) == 1
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// reviewsRepo keeps reviews in memory.
+type reviewsRepo struct {
+	reviews map[int]models.Review
+}
+
+func (r *reviewsRepo) CreateReview(review models.Review) (int, error) {
+	review.ID = len(r.reviews) + 1
+	r.reviews[review.ID] = review
+	return review.ID, nil
+}
+
+func (r *reviewsRepo) GetReviews(bookID int, status string, page models.Page) ([]models.Review, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *reviewsRepo) GetReviewByID(id int) (models.Review, error) {
+	return r.reviews[id], nil
+}
+
+func (r *reviewsRepo) UpdateReview(id int, review models.Review) error {
+	previous := r.reviews[id]
+	previous.Rating = review.Rating
+	previous.Status = models.ReviewPending
+	r.reviews[id] = previous
+	return nil
+}
+
+func (r *reviewsRepo) DeleteReview(id int) error {
+	delete(r.reviews, id)
+	return nil
+}
+
+func (r *reviewsRepo) SetReviewStatus(id int, status string) (models.Review, error) {
+	review := r.reviews[id]
+	review.Status = status
+	r.reviews[id] = review
+	return review, nil
+}
+
+type invalidations []int
+
+func (i *invalidations) Invalidate(ids ...int) {
+	*i = append(*i, ids...)
+}
+
+func TestReviewsService(t *testing.T) {
+	repo := &reviewsRepo{reviews: map[int]models.Review{}}
+	invalidated := &invalidations{}
+	s := NewReviewsService(repo, invalidated)
+
+	review, err := s.CreateReview(models.Review{BookID: 2, Author: "Ann", Rating: 5, Status: models.ReviewApproved})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewPending, review.Status)
+	assert.Len(t, review.Token, 64)
+	assert.NotEqual(t, review.Token, repo.reviews[review.ID].TokenHash)
+
+	assert.ErrorIs(t, s.UpdateReview(2, review.ID, "wrong", models.Review{Rating: 1}), ErrInvalidReviewToken)
+	assert.ErrorIs(t, s.DeleteReview(3, review.ID, review.Token), ErrInvalidReviewToken)
+	assert.Equal(t, 5, repo.reviews[review.ID].Rating)
+
+	assert.NoError(t, s.ModerateReview(review.ID, models.ReviewApproved))
+	assert.Equal(t, models.ReviewApproved, repo.reviews[review.ID].Status)
+
+	assert.NoError(t, s.UpdateReview(2, review.ID, review.Token, models.Review{Rating: 1}))
+	assert.Equal(t, 1, repo.reviews[review.ID].Rating)
+	assert.Equal(t, models.ReviewPending, repo.reviews[review.ID].Status)
+
+	assert.NoError(t, s.DeleteReview(2, review.ID, review.Token))
+	assert.Empty(t, repo.reviews)
+	assert.Equal(t, &invalidations{2, 2, 2}, invalidated)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"time"
+)
+
+var ErrPriceNotInFuture = errors.New("effective time must be in the future")
+
+// PriceSchedulerService keeps the price history of books and applies scheduled prices
+// once their effective time has passed.
+type PriceSchedulerService struct {
+	repo     repository.PriceHistory
+	books    StockObserver
+	clock    clock.Clock
+	interval time.Duration
+	done     chan struct{}
+}
+
+func NewPriceSchedulerService(repo repository.PriceHistory, books StockObserver, clock clock.Clock,
+	interval time.Duration) *PriceSchedulerService {
+	return &PriceSchedulerService{repo: repo, books: books, clock: clock, interval: interval, done: make(chan struct{})}
+}
+
+func (s *PriceSchedulerService) GetPriceHistory(bookID int) ([]models.PriceChange, error) {
+	return s.repo.GetPriceHistory(bookID)
+}
+
+func (s *PriceSchedulerService) GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error) {
+	return s.repo.GetScheduledPrices(bookID)
+}
+
+func (s *PriceSchedulerService) SchedulePrice(price models.ScheduledPrice) (int, error) {
+	if !price.EffectiveAt.After(s.clock.Now()) {
+		return 0, ErrPriceNotInFuture
+	}
+	price.EffectiveAt = price.EffectiveAt.UTC()
+	return s.repo.CreateScheduledPrice(price)
+}
+
+func (s *PriceSchedulerService) CancelScheduledPrice(bookID, id int) error {
+	return s.repo.DeleteScheduledPrice(bookID, id)
+}
+
+// Start applies the due prices every interval until ctx is done.
+func (s *PriceSchedulerService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		for {
+			if err := s.ApplyDue(); err != nil {
+				logging.Errorf("scheduler: %s", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.clock.After(s.interval):
+			}
+		}
+	}()
+}
+
+// Wait blocks until the scheduler started by Start returns.
+func (s *PriceSchedulerService) Wait() {
+	<-s.done
+}
+
+// ApplyDue sets the prices whose effective time has passed, in the order they take effect.
+// Prices applied meanwhile by other replicas are skipped, a price failing to apply is retried
+// in the next round without holding back the others.
+func (s *PriceSchedulerService) ApplyDue() error {
+	now := s.clock.Now().UTC()
+	due, err := s.repo.GetDuePrices(now)
+	if err != nil {
+		return err
+	}
+	for _, price := range due {
+		change, err := s.repo.ApplyPrice(price, now)
+		if errors.Is(err, repository.ErrPriceApplied) {
+			continue
+		}
+		if err != nil {
+			logging.Errorf("scheduler: price %d of book %d: %s", price.ID, price.BookID, err)
+			continue
+		}
+		s.books.StockChanged([]models.StockChange{change})
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// priceHistoryRepo keeps scheduled prices in memory. Prices of books in failing are not applied.
+type priceHistoryRepo struct {
+	mu      sync.Mutex
+	prices  []models.ScheduledPrice
+	failing map[int]bool
+}
+
+func (r *priceHistoryRepo) GetPriceHistory(bookID int) ([]models.PriceChange, error) {
+	return nil, nil
+}
+
+func (r *priceHistoryRepo) GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.ScheduledPrice(nil), r.prices...), nil
+}
+
+func (r *priceHistoryRepo) CreateScheduledPrice(price models.ScheduledPrice) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	price.ID = len(r.prices) + 1
+	r.prices = append(r.prices, price)
+	return price.ID, nil
+}
+
+func (r *priceHistoryRepo) DeleteScheduledPrice(bookID, id int) error {
+	return nil
+}
+
+func (r *priceHistoryRepo) GetDuePrices(at time.Time) ([]models.ScheduledPrice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []models.ScheduledPrice
+	for _, price := range r.prices {
+		if price.AppliedAt == nil && !price.EffectiveAt.After(at) {
+			due = append(due, price)
+		}
+	}
+	return due, nil
+}
+
+func (r *priceHistoryRepo) ApplyPrice(price models.ScheduledPrice, at time.Time) (models.StockChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.prices[price.ID-1].AppliedAt != nil {
+		return models.StockChange{}, repository.ErrPriceApplied
+	}
+	if r.failing[price.BookID] {
+		return models.StockChange{}, errors.New("connection reset")
+	}
+	r.prices[price.ID-1].AppliedAt = &at
+	return models.StockChange{PreviousAmount: 3, Book: models.Book{ID: price.BookID, Price: price.Price, Amount: 3}}, nil
+}
+
+// waitIdle waits until the scheduler waits for its next round.
+func waitIdle(t *testing.T, fake *clock.Fake) {
+	deadline := time.Now().Add(time.Second)
+	for fake.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler did not wait for the next round")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriceScheduler(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	repo := &priceHistoryRepo{failing: map[int]bool{1: true}}
+	books := mock_service.NewMockStockObserver(c)
+	s := NewPriceSchedulerService(repo, books, fake, time.Minute)
+
+	_, err := s.SchedulePrice(models.ScheduledPrice{BookID: 1, Price: 3999, EffectiveAt: now})
+	assert.ErrorIs(t, err, ErrPriceNotInFuture)
+	_, err = s.SchedulePrice(models.ScheduledPrice{BookID: 1, Price: 3999, EffectiveAt: now.Add(90 * time.Second)})
+	assert.NoError(t, err)
+	_, err = s.SchedulePrice(models.ScheduledPrice{BookID: 2, Price: 1999, EffectiveAt: now.Add(90 * time.Second)})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	waitIdle(t, fake)
+
+	// The prices are not due after the first interval.
+	fake.Advance(time.Minute)
+	waitIdle(t, fake)
+
+	// A price failing to apply does not hold back the others.
+	books.EXPECT().StockChanged([]models.StockChange{
+		{PreviousAmount: 3, Book: models.Book{ID: 2, Price: 1999, Amount: 3}},
+	})
+	fake.Advance(time.Minute)
+	waitIdle(t, fake)
+
+	// It is applied in a later round, applied prices are not applied again.
+	repo.mu.Lock()
+	repo.failing = nil
+	repo.mu.Unlock()
+	books.EXPECT().StockChanged([]models.StockChange{
+		{PreviousAmount: 3, Book: models.Book{ID: 1, Price: 3999, Amount: 3}},
+	})
+	fake.Advance(time.Minute)
+	waitIdle(t, fake)
+	fake.Advance(time.Minute)
+	waitIdle(t, fake)
+	cancel()
+	s.Wait()
+
+	prices, _ := repo.GetScheduledPrices(1)
+	if assert.Len(t, prices, 2) && assert.NotNil(t, prices[0].AppliedAt) && assert.NotNil(t, prices[1].AppliedAt) {
+		assert.Equal(t, now.Add(3*time.Minute), *prices[0].AppliedAt)
+		assert.Equal(t, now.Add(2*time.Minute), *prices[1].AppliedAt)
+	}
+}
@@ -3,6 +3,11 @@ package service
 import (
 	"github.com/TenderLimbo/rest-api/models"
 	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"github.com/TenderLimbo/rest-api/pkg/storage"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
@@ -12,33 +17,346 @@ type BooksManager interface {
 	GetBookByID(id int) (models.Book, error)
 	CreateBook(book models.Book) (int, error)
 	DeleteBookByID(id int) error
-	UpdateBookByID(id int, book models.Book) error
+	// UpdateBookByID stores the book and returns the change of its amount made by the update.
+	UpdateBookByID(id int, book models.Book) (models.StockChange, error)
+}
+
+// EventListener is notified after a change of a book is stored. It must not block.
+type EventListener interface {
+	HandleEvent(event models.Event)
+}
+
+type Genres interface {
+	GetGenres() ([]models.Genre, error)
+	GetGenreByID(id int) (models.Genre, error)
+	GetGenresByIDs(ids []int) ([]models.Genre, error)
+	CreateGenre(genre models.Genre) (int, error)
+}
+
+type Idempotency interface {
+	Begin(key, requestHash string) (models.IdempotencyRecord, bool, error)
+	Complete(key string, statusCode int, header http.Header, body []byte) error
+	Abort(key string) error
+}
+
+type Webhooks interface {
+	CreateSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetSubscriptions() ([]models.WebhookSubscription, error)
+	GetSubscriptionByID(id int) (models.WebhookSubscription, error)
+	UpdateSubscription(id int, subscription models.WebhookSubscription) error
+	DeleteSubscription(id int) error
+	GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error)
+}
+
+// Pricing converts book prices and manages the price lists of books in other currencies.
+type Pricing interface {
+	Convert(books []models.Book, currency string) ([]models.Book, error)
+	GetPrices(bookID int) ([]models.BookPrice, error)
+	SetPrice(price models.BookPrice) error
+	DeletePrice(bookID int, currency string) error
+}
+
+type Promotions interface {
+	GetPromotions() ([]models.Promotion, error)
+	GetPromotionByID(id int) (models.Promotion, error)
+	CreatePromotion(promotion models.Promotion) (int, error)
+	UpdatePromotion(id int, promotion models.Promotion) error
+	DeletePromotion(id int) error
+}
+
+// PriceHistory lists the past prices of books and schedules their future ones.
+type PriceHistory interface {
+	GetPriceHistory(bookID int) ([]models.PriceChange, error)
+	GetScheduledPrices(bookID int) ([]models.ScheduledPrice, error)
+	SchedulePrice(price models.ScheduledPrice) (int, error)
+	CancelScheduledPrice(bookID, id int) error
+}
+
+// StockObserver is told about changes of books committed outside of UpdateBookByID, like stock
+// movements and scheduled prices.
+type StockObserver interface {
+	StockChanged(changes []models.StockChange)
+}
+
+type Carts interface {
+	CreateCart() (models.Cart, error)
+	GetCartByID(id int, token string) (models.Cart, error)
+	DeleteCart(id int, token string) error
+	SetCartItem(cartID int, token string, item models.CartItem) (models.CartItem, error)
+	DeleteCartItem(cartID, bookID int, token string) error
+	Checkout(id int, token string) (models.Cart, error)
+}
+
+// Inventory reports on the stock of books.
+type Inventory interface {
+	GetLowStockBooks() ([]models.Book, error)
+}
+
+type Reviews interface {
+	CreateReview(review models.Review) (models.Review, error)
+	GetReviews(bookID int, page models.Page) ([]models.Review, int64, error)
+	UpdateReview(bookID, id int, token string, review models.Review) error
+	DeleteReview(bookID, id int, token string) error
+	GetPendingReviews(page models.Page) ([]models.Review, int64, error)
+	ModerateReview(id int, status string) error
+}
+
+// Waitlist subscribes customers to books coming back in stock.
+type Waitlist interface {
+	AddToWaitlist(entry models.WaitlistEntry) (models.WaitlistEntry, error)
+}
+
+// Covers stores the cover images of books.
+type Covers interface {
+	SetCover(bookID int, image io.Reader) (string, error)
+	GetCover(bookID int, file string) (storage.Blob, error)
+}
+
+// Locations manages the shops and warehouses holding stock. The amount of a book is the total of its stock
+// at all locations.
+type Locations interface {
+	GetLocations() ([]models.Location, error)
+	GetLocationByID(id int) (models.Location, error)
+	CreateLocation(location models.Location) (int, error)
+	UpdateLocation(id int, location models.Location) error
+	GetBookStock(bookID int) ([]models.StockLevel, error)
+	GetLocationStock(locationID int) ([]models.StockLevel, error)
+	Transfer(transfer models.StockTransfer) (models.StockTransfer, error)
+}
+
+type Suppliers interface {
+	GetSuppliers() ([]models.Supplier, error)
+	GetSupplierByID(id int) (models.Supplier, error)
+	CreateSupplier(supplier models.Supplier) (int, error)
+	UpdateSupplier(id int, supplier models.Supplier) error
+}
+
+// PurchaseOrders restocks books from suppliers. Receiving books of a purchase order adds them to the stock.
+type PurchaseOrders interface {
+	GetPurchaseOrders(supplierID int, status string) ([]models.PurchaseOrder, error)
+	GetPurchaseOrderByID(id int) (models.PurchaseOrder, error)
+	CreatePurchaseOrder(order models.PurchaseOrder) (int, error)
+	SendPurchaseOrder(id int) error
+	CancelPurchaseOrder(id int) error
+	Receive(id int, delivery models.Delivery) (models.PurchaseOrder, error)
+	GetOutstandingOrders() ([]models.OutstandingOrders, error)
+}
+
+// Stocktakes counts the books in stock. Finalizing a stocktake applies the variances of the counts to the stock.
+type Stocktakes interface {
+	GetStocktakes() ([]models.Stocktake, error)
+	GetStocktakeByID(id int) (models.Stocktake, error)
+	CreateStocktake(stocktake models.Stocktake) (int, error)
+	SetCounts(id int, counts models.StocktakeCounts) error
+	AddCount(id int, count models.StocktakeCount) (models.StocktakeItem, error)
+	GetVariances(id int) ([]models.StocktakeVariance, error)
+	FinalizeStocktake(id int) (models.Stocktake, error)
+}
+
+// Users manages the accounts of the people running the shop.
+type Users interface {
+	CreateUser(user models.User) (models.User, error)
+}
+
+// Stream lets clients follow book events as they happen.
+type Stream interface {
+	Subscribe(lastEventID int64) ([]models.StreamEvent, <-chan models.StreamEvent, func())
+}
+
+type Service struct {
+	BooksManager
+	Genres
+	Idempotency
+	Webhooks
+	Stream
+	Pricing
+	Promotions
+	PriceHistory
+	Inventory
+	Carts
+	Reviews
+	Waitlist
+	Covers
+	Locations
+	Suppliers
+	PurchaseOrders
+	Stocktakes
 }
 
 type BooksManagerService struct {
-	repo repository.BooksManager
+	repo       repository.BooksManager
+	promotions repository.Promotions
+	currencies map[string]bool
+	listeners  []EventListener
+	now        func() time.Time
 }
 
 func NewService(repo repository.BooksManager) *BooksManagerService {
-	return &BooksManagerService{repo: repo}
+	return &BooksManagerService{repo: repo, now: time.Now}
+}
+
+// UsePromotions makes book reads apply the running promotions. It is not safe to call once requests are served.
+func (s *BooksManagerService) UsePromotions(promotions repository.Promotions) {
+	s.promotions = promotions
+}
+
+// UseCurrencies limits the currencies of created and updated books to those with exchange rates.
+// It is not safe to call once requests are served.
+func (s *BooksManagerService) UseCurrencies(rates map[string]string) {
+	s.currencies = make(map[string]bool, len(rates))
+	for currency := range rates {
+		// Configuration keys are case-insensitive and come lowercased.
+		s.currencies[strings.ToUpper(currency)] = true
+	}
+}
+
+// checkCurrency returns ErrUnknownCurrency for a book in a currency without an exchange rate.
+func (s *BooksManagerService) checkCurrency(book models.Book) error {
+	if s.currencies != nil && !s.currencies[book.Currency] {
+		return ErrUnknownCurrency
+	}
+	return nil
+}
+
+// Subscribe registers a listener for book events. It is not safe to call once requests are served.
+func (s *BooksManagerService) Subscribe(listener EventListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *BooksManagerService) publish(eventType string, id int, book *models.Book) {
+	event := models.Event{Type: eventType, BookID: id, Book: book, OccurredAt: time.Now().UTC()}
+	for _, listener := range s.listeners {
+		listener.HandleEvent(event)
+	}
 }
 
 func (s *BooksManagerService) CreateBook(book models.Book) (int, error) {
-	return s.repo.CreateBook(book)
+	book = book.WithDefaults()
+	if err := s.checkCurrency(book); err != nil {
+		return 0, err
+	}
+	id, err := s.repo.CreateBook(book)
+	if err != nil {
+		return id, err
+	}
+	book.ID = id
+	s.publish(models.EventBookCreated, id, &book)
+	return id, nil
 }
 
 func (s *BooksManagerService) GetBookByID(id int) (models.Book, error) {
-	return s.repo.GetBookByID(id)
+	book, err := s.repo.GetBookByID(id)
+	if err != nil {
+		return book, err
+	}
+	books := []models.Book{book}
+	if err = s.applyPromotions(books); err != nil {
+		return book, err
+	}
+	return books[0], nil
 }
 
 func (s *BooksManagerService) GetBooks(filterCondition map[string][]string) ([]models.Book, error) {
-	return s.repo.GetBooks(filterCondition)
+	books, err := s.repo.GetBooks(filterCondition)
+	if err != nil {
+		return books, err
+	}
+	return books, s.applyPromotions(books)
+}
+
+// applyPromotions sets the effective prices of the books, which equal the prices without promotions.
+func (s *BooksManagerService) applyPromotions(books []models.Book) error {
+	var promotions []models.Promotion
+	if s.promotions != nil {
+		var err error
+		if promotions, err = s.promotions.GetActivePromotions(s.now().UTC()); err != nil {
+			return err
+		}
+	}
+	for i := range books {
+		applyPromotions(&books[i], promotions)
+	}
+	return nil
 }
 
 func (s *BooksManagerService) DeleteBookByID(id int) error {
-	return s.repo.DeleteBookByID(id)
+	if err := s.repo.DeleteBookByID(id); err != nil {
+		return err
+	}
+	s.publish(models.EventBookDeleted, id, nil)
+	return nil
+}
+
+// UpdateBookByID stores the book. Stock transitions are published from the amount the repository
+// replaced under lock, so concurrent stock changes are never missed or announced twice.
+func (s *BooksManagerService) UpdateBookByID(id int, book models.Book) (models.StockChange, error) {
+	book = book.WithDefaults()
+	if err := s.checkCurrency(book); err != nil {
+		return models.StockChange{}, err
+	}
+	change, err := s.repo.UpdateBookByID(id, book)
+	if err != nil {
+		return change, err
+	}
+	s.publish(models.EventBookUpdated, id, &change.Book)
+	s.publishStockEvents(change.PreviousAmount, &change.Book)
+	return change, nil
+}
+
+// StockChanged drops cached reads of the changed books and notifies the listeners of the changes.
+func (s *BooksManagerService) StockChanged(changes []models.StockChange) {
+	ids := make([]int, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.Book.ID)
+	}
+	s.Invalidate(ids...)
+	for _, change := range changes {
+		book := change.Book
+		s.publish(models.EventBookUpdated, book.ID, &book)
+		s.publishStockEvents(change.PreviousAmount, &book)
+	}
+}
+
+// Invalidate drops cached reads of the books, whose held amounts changed for example.
+func (s *BooksManagerService) Invalidate(ids ...int) {
+	if invalidator, ok := s.repo.(CacheInvalidator); ok {
+		invalidator.Invalidate(ids...)
+	}
+}
+
+// publishStockEvents publishes the stock transitions of the book from previousAmount.
+func (s *BooksManagerService) publishStockEvents(previousAmount int, book *models.Book) {
+	if previousAmount > 0 && book.Amount == 0 {
+		s.publish(models.EventBookOutOfStock, book.ID, book)
+	}
+	if previousAmount == 0 && book.Amount > 0 {
+		s.publish(models.EventBookBackInStock, book.ID, book)
+	}
+	if book.CrossedReorderThreshold(previousAmount) {
+		s.publish(models.EventBookLowStock, book.ID, book)
+	}
+}
+
+type GenresService struct {
+	repo repository.Genres
+}
+
+func NewGenresService(repo repository.Genres) *GenresService {
+	return &GenresService{repo: repo}
+}
+
+func (s *GenresService) GetGenres() ([]models.Genre, error) {
+	return s.repo.GetGenres()
+}
+
+func (s *GenresService) GetGenreByID(id int) (models.Genre, error) {
+	return s.repo.GetGenreByID(id)
+}
+
+func (s *GenresService) GetGenresByIDs(ids []int) ([]models.Genre, error) {
+	return s.repo.GetGenresByIDs(ids)
 }
 
-func (s *BooksManagerService) UpdateBookByID(id int, book models.Book) error {
-	return s.repo.UpdateBookByID(id, book)
+func (s *GenresService) CreateGenre(genre models.Genre) (int, error) {
+	return s.repo.CreateGenre(genre)
 }
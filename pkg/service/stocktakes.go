@@ -0,0 +1,58 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+)
+
+var (
+	ErrStocktakeFinalized = repository.ErrStocktakeFinalized
+	ErrNotInStocktake     = repository.ErrNotInStocktake
+)
+
+// StocktakesService counts the books in stock and corrects the stock with the counts.
+// Corrections are announced like any stock change.
+type StocktakesService struct {
+	repo  repository.Stocktakes
+	stock StockObserver
+	clock clock.Clock
+}
+
+func NewStocktakesService(repo repository.Stocktakes, stock StockObserver, clock clock.Clock) *StocktakesService {
+	return &StocktakesService{repo: repo, stock: stock, clock: clock}
+}
+
+func (s *StocktakesService) GetStocktakes() ([]models.Stocktake, error) {
+	return s.repo.GetStocktakes()
+}
+
+func (s *StocktakesService) GetStocktakeByID(id int) (models.Stocktake, error) {
+	return s.repo.GetStocktakeByID(id)
+}
+
+func (s *StocktakesService) CreateStocktake(stocktake models.Stocktake) (int, error) {
+	stocktake.CreatedAt = s.clock.Now().UTC()
+	return s.repo.CreateStocktake(stocktake)
+}
+
+func (s *StocktakesService) SetCounts(id int, counts models.StocktakeCounts) error {
+	return s.repo.SetCounts(id, counts.Counts)
+}
+
+func (s *StocktakesService) AddCount(id int, count models.StocktakeCount) (models.StocktakeItem, error) {
+	return s.repo.AddCount(id, count)
+}
+
+func (s *StocktakesService) GetVariances(id int) ([]models.StocktakeVariance, error) {
+	return s.repo.GetVariances(id)
+}
+
+func (s *StocktakesService) FinalizeStocktake(id int) (models.Stocktake, error) {
+	changes, err := s.repo.Finalize(id, s.clock.Now().UTC())
+	if err != nil {
+		return models.Stocktake{}, err
+	}
+	s.stock.StockChanged(changes)
+	return s.repo.GetStocktakeByID(id)
+}
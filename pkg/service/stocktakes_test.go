@@ -0,0 +1,55 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// stocktakesRepo finds no copy of its only book when finalizing, other methods are not used.
+type stocktakesRepo struct {
+	repository.Stocktakes
+	book      models.Book
+	finalized *time.Time
+}
+
+func (r *stocktakesRepo) Finalize(id int, at time.Time) ([]models.StockChange, error) {
+	if r.finalized != nil {
+		return nil, repository.ErrStocktakeFinalized
+	}
+	r.finalized = &at
+	previous := r.book.Amount
+	r.book.Amount = 0
+	return []models.StockChange{{PreviousAmount: previous, Book: r.book}}, nil
+}
+
+func (r *stocktakesRepo) GetStocktakeByID(id int) (models.Stocktake, error) {
+	return models.Stocktake{ID: id, Status: models.StocktakeFinalized, FinalizedAt: r.finalized}, nil
+}
+
+func TestStocktakesService(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	books := NewService(mock_service.NewMockBooksManager(c))
+	recorder := &eventRecorder{}
+	books.Subscribe(recorder)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stocktakesRepo{book: models.Book{ID: 1, Amount: 3, ReorderThreshold: 1}}
+	s := NewStocktakesService(repo, books, clock.NewFake(now))
+
+	stocktake, err := s.FinalizeStocktake(1)
+	assert.NoError(t, err)
+	assert.Equal(t, &now, stocktake.FinalizedAt)
+	// Corrections are announced like sales, so a book found missing goes out of stock for everyone.
+	assert.Equal(t, []string{models.EventBookUpdated, models.EventBookOutOfStock, models.EventBookLowStock}, recorder.events)
+
+	_, err = s.FinalizeStocktake(1)
+	assert.ErrorIs(t, err, ErrStocktakeFinalized)
+	assert.Len(t, recorder.events, 3)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+)
+
+type SuppliersService struct {
+	repo repository.Suppliers
+}
+
+func NewSuppliersService(repo repository.Suppliers) *SuppliersService {
+	return &SuppliersService{repo: repo}
+}
+
+func (s *SuppliersService) GetSuppliers() ([]models.Supplier, error) {
+	return s.repo.GetSuppliers()
+}
+
+func (s *SuppliersService) GetSupplierByID(id int) (models.Supplier, error) {
+	return s.repo.GetSupplierByID(id)
+}
+
+func (s *SuppliersService) CreateSupplier(supplier models.Supplier) (int, error) {
+	return s.repo.CreateSupplier(supplier)
+}
+
+func (s *SuppliersService) UpdateSupplier(id int, supplier models.Supplier) error {
+	return s.repo.UpdateSupplier(id, supplier)
+}
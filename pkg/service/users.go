@@ -0,0 +1,23 @@
+package service
+
+import (
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"strings"
+)
+
+var ErrUserExists = repository.ErrUserExists
+
+type UsersService struct {
+	repo repository.Users
+}
+
+func NewUsersService(repo repository.Users) *UsersService {
+	return &UsersService{repo: repo}
+}
+
+// CreateUser creates the user, emails are stored in lower case so each address has one user.
+func (s *UsersService) CreateUser(user models.User) (models.User, error) {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	return s.repo.CreateUser(user)
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/mail"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var ErrBookNotFound = repository.ErrBookNotFound
+
+// Notifier tells a waitlist subscriber that the book is back in stock.
+type Notifier interface {
+	Notify(entry models.WaitlistEntry, book models.Book) error
+}
+
+// WaitlistNotifier emails subscribers with an address and posts a signed book.back_in_stock event
+// to subscribers with a URL. URLs are only posted to over HTTPS at public addresses, without redirects.
+type WaitlistNotifier struct {
+	sender mail.Sender
+	from   string
+	client *http.Client
+}
+
+func NewWaitlistNotifier(sender mail.Sender, from string, timeout time.Duration) *WaitlistNotifier {
+	return &WaitlistNotifier{sender: sender, from: from, client: newPublicClient(timeout)}
+}
+
+func (n *WaitlistNotifier) Notify(entry models.WaitlistEntry, book models.Book) error {
+	if entry.URL != "" {
+		return n.post(entry, book)
+	}
+	return n.sender.Send(mail.Message{
+		From:    n.from,
+		To:      []string{entry.Email},
+		Subject: fmt.Sprintf("Back in stock: %s", book.Name),
+		Body:    fmt.Sprintf("Book %d \"%s\" you are waiting for is back in stock.\n", book.ID, book.Name),
+	})
+}
+
+func (n *WaitlistNotifier) post(entry models.WaitlistEntry, book models.Book) error {
+	if err := checkHTTPS(entry.URL); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(models.Event{
+		Type:       models.EventBookBackInStock,
+		BookID:     book.ID,
+		Book:       &book,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, entry.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, models.EventBookBackInStock)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+Sign(entry.Secret, timestamp, payload))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type WaitlistConfig struct {
+	QueueSize int `mapstructure:"queue_size"`
+	// Interval is how often books in stock are checked for subscribers who were not notified,
+	// e.g. because the queue was full or a notification failed.
+	Interval time.Duration `mapstructure:"interval"`
+	From     string        `mapstructure:"from"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// MaxAttempts is the number of failed notifications after which an entry is given up.
+	MaxAttempts int `mapstructure:"max_attempts"`
+}
+
+// WaitlistService notifies the subscribers of books coming back in stock in the background,
+// in the order they joined the waitlist. Entries are marked once their notification is sent.
+type WaitlistService struct {
+	repo        repository.Waitlist
+	notifier    Notifier
+	clock       clock.Clock
+	interval    time.Duration
+	maxAttempts int
+	books       chan models.Book
+	done        chan struct{}
+}
+
+func NewWaitlistService(repo repository.Waitlist, notifier Notifier, clock clock.Clock, cfg WaitlistConfig) *WaitlistService {
+	return &WaitlistService{
+		repo:        repo,
+		notifier:    notifier,
+		clock:       clock,
+		interval:    cfg.Interval,
+		maxAttempts: cfg.MaxAttempts,
+		books:       make(chan models.Book, cfg.QueueSize),
+		done:        make(chan struct{}),
+	}
+}
+
+// AddToWaitlist stores the entry, an entry with a URL gets the secret its events are signed with.
+func (s *WaitlistService) AddToWaitlist(entry models.WaitlistEntry) (models.WaitlistEntry, error) {
+	entry.Secret = ""
+	if entry.URL != "" {
+		secret, err := newSecret()
+		if err != nil {
+			return entry, err
+		}
+		entry.Secret = secret
+	}
+	id, err := s.repo.AddToWaitlist(entry)
+	entry.ID = id
+	return entry, err
+}
+
+// HandleEvent queues books coming back in stock. When the queue is full the book is left
+// to the next check of the waitlist.
+func (s *WaitlistService) HandleEvent(event models.Event) {
+	if event.Type != models.EventBookBackInStock || event.Book == nil {
+		return
+	}
+	select {
+	case s.books <- *event.Book:
+	default:
+		logging.Warnf("waitlist: queue is full, book %d is left to the next check", event.BookID)
+	}
+}
+
+// Start notifies the subscribers of queued books and checks the whole waitlist on start and every interval
+// until ctx is done.
+func (s *WaitlistService) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		s.NotifyPending()
+		check := s.clock.After(s.interval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case book := <-s.books:
+				s.notify(book)
+			case <-check:
+				s.NotifyPending()
+				check = s.clock.After(s.interval)
+			}
+		}
+	}()
+}
+
+// Wait blocks until the loop started by Start returns.
+func (s *WaitlistService) Wait() {
+	<-s.done
+}
+
+// NotifyPending notifies the subscribers of all books in stock who were not notified yet.
+func (s *WaitlistService) NotifyPending() {
+	books, err := s.repo.GetBooksInStockWithPendingEntries(s.maxAttempts)
+	if err != nil {
+		logging.Errorf("waitlist: %s", err)
+		return
+	}
+	for _, book := range books {
+		s.notify(book)
+	}
+}
+
+// notify sends the notifications of the book one by one, a failed one is retried by the next checks
+// until it failed max attempts times.
+func (s *WaitlistService) notify(book models.Book) {
+	entries, err := s.repo.GetPendingEntries(book.ID, s.maxAttempts)
+	if err != nil {
+		logging.Errorf("waitlist: %s", err)
+		return
+	}
+	for _, entry := range entries {
+		if err = s.notifier.Notify(entry, book); err != nil {
+			logging.Errorf("waitlist: entry %d of book %d: %s", entry.ID, book.ID, err)
+			if entry.Attempts+1 >= s.maxAttempts {
+				logging.Warnf("waitlist: entry %d of book %d is given up after %d attempts", entry.ID, book.ID, s.maxAttempts)
+			}
+			if err = s.repo.RecordFailure(entry.ID); err != nil {
+				logging.Errorf("waitlist: %s", err)
+			}
+			continue
+		}
+		if err = s.repo.MarkNotified(entry.ID, s.clock.Now()); err != nil {
+			logging.Errorf("waitlist: %s", err)
+		}
+	}
+}
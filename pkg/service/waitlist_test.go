@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/clock"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitlistRepo keeps entries in memory, books holds the stock of each book.
+type waitlistRepo struct {
+	mu      sync.Mutex
+	books   map[int]models.Book
+	entries []models.WaitlistEntry
+}
+
+func (r *waitlistRepo) AddToWaitlist(entry models.WaitlistEntry) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.ID = len(r.entries) + 1
+	r.entries = append(r.entries, entry)
+	return entry.ID, nil
+}
+
+func (r *waitlistRepo) GetPendingEntries(bookID, maxAttempts int) ([]models.WaitlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []models.WaitlistEntry
+	for _, entry := range r.entries {
+		if entry.BookID == bookID && entry.NotifiedAt == nil && entry.Attempts < maxAttempts {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *waitlistRepo) GetBooksInStockWithPendingEntries(maxAttempts int) ([]models.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var books []models.Book
+	for _, book := range r.books {
+		for _, entry := range r.entries {
+			if entry.BookID == book.ID && entry.NotifiedAt == nil && entry.Attempts < maxAttempts && book.Amount > 0 {
+				books = append(books, book)
+				break
+			}
+		}
+	}
+	return books, nil
+}
+
+func (r *waitlistRepo) MarkNotified(id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[id-1].NotifiedAt = &at
+	return nil
+}
+
+func (r *waitlistRepo) RecordFailure(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[id-1].Attempts++
+	return nil
+}
+
+func (r *waitlistRepo) pending() int {
+	entries, _ := r.GetPendingEntries(1, 100)
+	return len(entries)
+}
+
+func (r *waitlistRepo) attempts(id int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries[id-1].Attempts
+}
+
+// notification is a message given to fakeNotifier.
+type notification struct {
+	Entry models.WaitlistEntry
+	Book  models.Book
+}
+
+// fakeNotifier keeps the notifications it is given instead of sending them.
+type fakeNotifier struct {
+	mu            sync.Mutex
+	notifications []notification
+}
+
+func (f *fakeNotifier) Notify(entry models.WaitlistEntry, book models.Book) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifications = append(f.notifications, notification{Entry: entry, Book: book})
+	return nil
+}
+
+// sent returns the notifications given so far.
+func (f *fakeNotifier) sent() []notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]notification(nil), f.notifications...)
+}
+
+// failingNotifier fails the notifications of the entry until fail is cleared.
+type failingNotifier struct {
+	*fakeNotifier
+	mu   sync.Mutex
+	fail int
+}
+
+func (n *failingNotifier) Notify(entry models.WaitlistEntry, book models.Book) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if entry.ID == n.fail {
+		return errors.New("mailbox unavailable")
+	}
+	return n.fakeNotifier.Notify(entry, book)
+}
+
+func (n *failingNotifier) recover() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fail = 0
+}
+
+func TestBackInStockEvents(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockManager := mock_service.NewMockBooksManager(c)
+	recorder := &eventRecorder{}
+	s := NewService(mockManager)
+	s.Subscribe(recorder)
+
+	book := models.Book{Name: "hello", Price: 432, Currency: "USD", Genre: 2, Amount: 2}
+	updated := book
+	updated.ID = 1
+	mockManager.EXPECT().UpdateBookByID(1, book).Return(models.StockChange{PreviousAmount: 0, Book: updated}, nil)
+
+	_, err := s.UpdateBookByID(1, book)
+	assert.NoError(t, err)
+	s.StockChanged([]models.StockChange{{PreviousAmount: 0, Book: models.Book{ID: 2, Amount: 1}}})
+
+	assert.Equal(t, []string{
+		models.EventBookUpdated,
+		models.EventBookBackInStock,
+		models.EventBookUpdated,
+		models.EventBookBackInStock,
+	}, recorder.events)
+}
+
+func TestWaitlistService(t *testing.T) {
+	fake := clock.NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := &waitlistRepo{books: map[int]models.Book{1: {ID: 1, Name: "hello"}}}
+	notifier := &failingNotifier{fakeNotifier: &fakeNotifier{}, fail: 2}
+	s := NewWaitlistService(repo, notifier, fake, WaitlistConfig{QueueSize: 1, Interval: time.Minute, MaxAttempts: 3})
+
+	for _, email := range []string{"a@books.local", "b@books.local", "c@books.local"} {
+		_, err := s.AddToWaitlist(models.WaitlistEntry{BookID: 1, Email: email})
+		assert.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	waitIdle(t, fake)
+	assert.Empty(t, notifier.sent(), "out of stock books are not notified")
+
+	repo.mu.Lock()
+	repo.books[1] = models.Book{ID: 1, Name: "hello", Amount: 3}
+	repo.mu.Unlock()
+	s.HandleEvent(models.Event{Type: models.EventBookBackInStock, BookID: 1, Book: &models.Book{ID: 1, Name: "hello", Amount: 3}})
+	assert.Eventually(t, func() bool { return repo.pending() == 1 }, time.Second, time.Millisecond)
+
+	notifier.recover()
+	fake.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return repo.pending() == 0 }, time.Second, time.Millisecond)
+
+	var emails []string
+	for _, n := range notifier.sent() {
+		emails = append(emails, n.Entry.Email)
+	}
+	assert.Equal(t, []string{"a@books.local", "c@books.local", "b@books.local"}, emails)
+
+	cancel()
+	s.Wait()
+}
+
+func TestWaitlistGivesUp(t *testing.T) {
+	fake := clock.NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := &waitlistRepo{books: map[int]models.Book{1: {ID: 1, Name: "hello", Amount: 1}}}
+	notifier := &failingNotifier{fakeNotifier: &fakeNotifier{}, fail: 1}
+	s := NewWaitlistService(repo, notifier, fake, WaitlistConfig{QueueSize: 1, Interval: time.Minute, MaxAttempts: 2})
+	_, err := s.AddToWaitlist(models.WaitlistEntry{BookID: 1, Email: "a@books.local"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		s.NotifyPending()
+	}
+	assert.Equal(t, 2, repo.attempts(1))
+	entries, err := repo.GetPendingEntries(1, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "given up entries are not pending")
+}
+
+func TestWaitlistNotifier(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	entry := models.WaitlistEntry{ID: 1, BookID: 1, URL: srv.URL, Secret: "secret"}
+	book := models.Book{ID: 1, Name: "hello", Amount: 1}
+	n := &WaitlistNotifier{client: srv.Client()}
+	assert.NoError(t, n.Notify(entry, book))
+	timestamp := got.Header.Get(webhookTimestampHeader)
+	assert.NotEmpty(t, timestamp)
+	assert.Equal(t, "sha256="+Sign("secret", timestamp, body), got.Header.Get(webhookSignatureHeader))
+	assert.Equal(t, models.EventBookBackInStock, got.Header.Get(webhookEventHeader))
+
+	entry.URL = "http://books.local/hook"
+	assert.Error(t, n.Notify(entry, book), "plain http is refused")
+
+	entry.URL = srv.URL
+	err := NewWaitlistNotifier(nil, "", time.Second).Notify(entry, book)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TenderLimbo/rest-api/models"
+	"github.com/TenderLimbo/rest-api/pkg/logging"
+	"github.com/TenderLimbo/rest-api/pkg/repository"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for URLs of the internal network, which are never posted to.
+var ErrPrivateAddress = errors.New("address is not public")
+
+const (
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+type WebhooksConfig struct {
+	Workers     int           `mapstructure:"workers"`
+	QueueSize   int           `mapstructure:"queue_size"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+	MaxFailures int           `mapstructure:"max_failures"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+type webhookJob struct {
+	subscription models.WebhookSubscription
+	eventType    string
+	payload      []byte
+}
+
+// WebhooksService manages subscriptions and delivers book events to them in the background.
+type WebhooksService struct {
+	repo   repository.Webhooks
+	cfg    WebhooksConfig
+	client *http.Client
+	events chan models.Event
+	jobs   chan webhookJob
+	wg     sync.WaitGroup
+}
+
+func NewWebhooksService(repo repository.Webhooks, cfg WebhooksConfig) *WebhooksService {
+	return &WebhooksService{
+		repo:   repo,
+		cfg:    cfg,
+		client: newPublicClient(cfg.Timeout),
+		events: make(chan models.Event, cfg.QueueSize),
+		jobs:   make(chan webhookJob, cfg.QueueSize),
+	}
+}
+
+func (s *WebhooksService) CreateSubscription(subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return subscription, err
+	}
+	subscription.Secret = secret
+	active := true
+	subscription.Active = &active
+	id, err := s.repo.CreateSubscription(subscription)
+	subscription.ID = id
+	return subscription, err
+}
+
+func (s *WebhooksService) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.repo.GetSubscriptions()
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, err
+}
+
+func (s *WebhooksService) GetSubscriptionByID(id int) (models.WebhookSubscription, error) {
+	subscription, err := s.repo.GetSubscriptionByID(id)
+	subscription.Secret = ""
+	return subscription, err
+}
+
+func (s *WebhooksService) UpdateSubscription(id int, subscription models.WebhookSubscription) error {
+	return s.repo.UpdateSubscription(id, subscription)
+}
+
+func (s *WebhooksService) DeleteSubscription(id int) error {
+	return s.repo.DeleteSubscription(id)
+}
+
+func (s *WebhooksService) GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error) {
+	return s.repo.GetDeliveries(subscriptionID)
+}
+
+// HandleEvent queues the event for delivery, events are dropped when the queue is full.
+func (s *WebhooksService) HandleEvent(event models.Event) {
+	select {
+	case s.events <- event:
+	default:
+		logging.Warnf("webhooks: queue is full, %s event of book %d dropped", event.Type, event.BookID)
+	}
+}
+
+// Start runs the delivery workers until ctx is done.
+func (s *WebhooksService) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.fanOut(ctx)
+	}()
+	for i := 0; i < s.cfg.Workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.jobs:
+					s.deliver(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until the workers started by Start return.
+func (s *WebhooksService) Wait() {
+	s.wg.Wait()
+}
+
+func (s *WebhooksService) fanOut(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				logging.Errorf("webhooks: %s", err)
+				continue
+			}
+			subscriptions, err := s.repo.GetActiveSubscriptions(event.Type)
+			if err != nil {
+				logging.Errorf("webhooks: %s", err)
+				continue
+			}
+			for _, subscription := range subscriptions {
+				select {
+				case <-ctx.Done():
+					return
+				case s.jobs <- webhookJob{subscription: subscription, eventType: event.Type, payload: payload}:
+				}
+			}
+		}
+	}
+}
+
+// deliver posts the payload, retrying with exponential backoff. Every attempt is logged.
+func (s *WebhooksService) deliver(ctx context.Context, job webhookJob) {
+	backoff := s.cfg.Backoff
+	for attempt := 1; attempt <= s.cfg.MaxAttempts; attempt++ {
+		statusCode, err := s.send(ctx, job)
+		delivery := models.WebhookDelivery{
+			SubscriptionID: job.subscription.ID,
+			EventType:      job.eventType,
+			Payload:        string(job.payload),
+			Attempt:        attempt,
+			StatusCode:     statusCode,
+			Success:        err == nil,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if logErr := s.repo.CreateDelivery(delivery); logErr != nil {
+			logging.Errorf("webhooks: %s", logErr)
+		}
+		if err == nil {
+			if job.subscription.FailureCount > 0 {
+				if err = s.repo.ResetFailures(job.subscription.ID); err != nil {
+					logging.Errorf("webhooks: %s", err)
+				}
+			}
+			return
+		}
+		if attempt == s.cfg.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err := s.repo.RecordFailure(job.subscription.ID, s.cfg.MaxFailures); err != nil {
+		logging.Errorf("webhooks: %s", err)
+	}
+}
+
+func (s *WebhooksService) send(ctx context.Context, job webhookJob) (int, error) {
+	if err := checkHTTPS(job.subscription.URL); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.subscription.URL, bytes.NewReader(job.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, job.eventType)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+Sign(job.subscription.Secret, timestamp, job.payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// newPublicClient returns the client events are posted to subscribers with. It only connects to public
+// addresses and does not follow redirects, so subscribers cannot make the server call its internal network.
+func newPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicAddressesOnly}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddressesOnly refuses connections to loopback, private, link-local and other non-public addresses.
+// It checks the address connected to, so host names resolving to the internal network are refused too.
+func publicAddressesOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+func checkHTTPS(url string) error {
+	if !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("url %q is not https", url)
+	}
+	return nil
+}
+
+// newSecret returns a random signing secret.
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.payload", receivers recompute it with their secret.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/TenderLimbo/rest-api/models"
+	mock_service "github.com/TenderLimbo/rest-api/pkg/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhooksRepo keeps subscriptions and deliveries in memory.
+type webhooksRepo struct {
+	mu            sync.Mutex
+	subscriptions map[int]models.WebhookSubscription
+	deliveries    []models.WebhookDelivery
+}
+
+func (r *webhooksRepo) CreateSubscription(subscription models.WebhookSubscription) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription.ID = len(r.subscriptions) + 1
+	r.subscriptions[subscription.ID] = subscription
+	return subscription.ID, nil
+}
+
+func (r *webhooksRepo) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (r *webhooksRepo) GetSubscriptionByID(id int) (models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.subscriptions[id], nil
+}
+
+func (r *webhooksRepo) GetActiveSubscriptions(eventType string) ([]models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if *subscription.Active && subscription.Events.Has(eventType) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *webhooksRepo) UpdateSubscription(id int, subscription models.WebhookSubscription) error {
+	return nil
+}
+
+func (r *webhooksRepo) DeleteSubscription(id int) error {
+	return nil
+}
+
+func (r *webhooksRepo) RecordFailure(id int, maxFailures int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription := r.subscriptions[id]
+	subscription.FailureCount++
+	active := *subscription.Active && subscription.FailureCount < maxFailures
+	subscription.Active = &active
+	r.subscriptions[id] = subscription
+	return nil
+}
+
+func (r *webhooksRepo) ResetFailures(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription := r.subscriptions[id]
+	subscription.FailureCount = 0
+	r.subscriptions[id] = subscription
+	return nil
+}
+
+func (r *webhooksRepo) CreateDelivery(delivery models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+func (r *webhooksRepo) GetDeliveries(subscriptionID int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookDelivery(nil), r.deliveries...), nil
+}
+
+func TestWebhooksDelivery(t *testing.T) {
+	tests := []struct {
+		name                 string
+		failures             int32
+		events               models.EventList
+		expectedAttempts     int
+		expectedSuccess      bool
+		expectedActive       bool
+		expectedFailureCount int
+	}{
+		{
+			name:             "Delivered",
+			events:           models.EventList{models.EventBookCreated},
+			expectedAttempts: 1,
+			expectedSuccess:  true,
+			expectedActive:   true,
+		},
+		{
+			name:             "Delivered after retries",
+			failures:         2,
+			events:           models.EventList{models.EventBookCreated},
+			expectedAttempts: 3,
+			expectedSuccess:  true,
+			expectedActive:   true,
+		},
+		{
+			name:                 "Disabled after failures",
+			failures:             100,
+			events:               models.EventList{models.EventBookCreated},
+			expectedAttempts:     3,
+			expectedActive:       false,
+			expectedFailureCount: 1,
+		},
+		{
+			name:           "Not subscribed",
+			events:         models.EventList{models.EventBookDeleted},
+			expectedActive: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &webhooksRepo{subscriptions: make(map[int]models.WebhookSubscription)}
+			s := NewWebhooksService(repo, WebhooksConfig{
+				Workers:     1,
+				QueueSize:   10,
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				MaxFailures: 1,
+				Timeout:     time.Second,
+			})
+
+			var calls int32
+			var secret string
+			done := make(chan struct{}, 10)
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer func() { done <- struct{}{} }()
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, models.EventBookCreated, r.Header.Get("X-Webhook-Event"))
+				assert.Equal(t, "sha256="+Sign(secret, r.Header.Get("X-Webhook-Timestamp"), body),
+					r.Header.Get("X-Webhook-Signature"))
+				var event models.Event
+				assert.NoError(t, json.Unmarshal(body, &event))
+				assert.Equal(t, 7, event.BookID)
+				if atomic.AddInt32(&calls, 1) <= test.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer receiver.Close()
+			// The receiver is on loopback, which the client of the service refuses.
+			s.client = receiver.Client()
+
+			subscription, err := s.CreateSubscription(models.WebhookSubscription{URL: receiver.URL, Events: test.events})
+			assert.NoError(t, err)
+			secret = subscription.Secret
+
+			ctx, cancel := context.WithCancel(context.Background())
+			s.Start(ctx)
+			s.HandleEvent(models.Event{Type: models.EventBookCreated, BookID: 7, Book: &models.Book{ID: 7}})
+			for i := 0; i < test.expectedAttempts; i++ {
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					t.Fatal("webhook was not delivered")
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+			s.Wait()
+
+			deliveries, _ := repo.GetDeliveries(subscription.ID)
+			assert.Len(t, deliveries, test.expectedAttempts)
+			if len(deliveries) > 0 {
+				assert.Equal(t, test.expectedSuccess, deliveries[len(deliveries)-1].Success)
+			}
+			stored, _ := repo.GetSubscriptionByID(subscription.ID)
+			assert.Equal(t, test.expectedActive, *stored.Active)
+			assert.Equal(t, test.expectedFailureCount, stored.FailureCount)
+		})
+	}
+}
+
+func TestPublicAddressesOnly(t *testing.T) {
+	tests := []struct {
+		address       string
+		expectedError error
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "127.0.0.1:8090", expectedError: ErrPrivateAddress},
+		{address: "[::1]:443", expectedError: ErrPrivateAddress},
+		{address: "10.0.0.1:443", expectedError: ErrPrivateAddress},
+		{address: "172.16.0.1:443", expectedError: ErrPrivateAddress},
+		{address: "192.168.1.1:443", expectedError: ErrPrivateAddress},
+		{address: "[fd00::1]:443", expectedError: ErrPrivateAddress},
+		{address: "169.254.169.254:80", expectedError: ErrPrivateAddress},
+		{address: "[fe80::1]:443", expectedError: ErrPrivateAddress},
+		{address: "0.0.0.0:443", expectedError: ErrPrivateAddress},
+	}
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			err := publicAddressesOnly("tcp", test.address, nil)
+			if test.expectedError != nil {
+				assert.ErrorIs(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebhooksRefuseInternalURLs(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+	s := NewWebhooksService(&webhooksRepo{}, WebhooksConfig{Timeout: time.Second})
+	job := webhookJob{subscription: models.WebhookSubscription{URL: receiver.URL}, eventType: models.EventBookCreated}
+
+	_, err := s.send(context.Background(), job)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+	job.subscription.URL = "http://books.local/hook"
+	_, err = s.send(context.Background(), job)
+	assert.EqualError(t, err, `url "http://books.local/hook" is not https`)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+type eventRecorder struct {
+	events []string
+}
+
+func (r *eventRecorder) HandleEvent(event models.Event) {
+	r.events = append(r.events, event.Type)
+}
+
+func TestBooksManagerServiceEvents(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	mockManager := mock_service.NewMockBooksManager(c)
+	recorder := &eventRecorder{}
+	s := NewService(mockManager)
+	s.Subscribe(recorder)
+
+	book := models.Book{Name: "hello", Price: 432, Currency: "USD", Genre: 2, Amount: 0}
+	mockManager.EXPECT().CreateBook(book).Return(1, nil)
+	updated := book
+	updated.ID = 1
+	mockManager.EXPECT().UpdateBookByID(1, book).Return(models.StockChange{PreviousAmount: 3, Book: updated}, nil)
+	mockManager.EXPECT().DeleteBookByID(1).Return(nil)
+
+	_, err := s.CreateBook(book)
+	assert.NoError(t, err)
+	_, err = s.UpdateBookByID(1, book)
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteBookByID(1))
+
+	assert.Equal(t, []string{
+		models.EventBookCreated,
+		models.EventBookUpdated,
+		models.EventBookOutOfStock,
+		models.EventBookLowStock,
+		models.EventBookDeleted,
+	}, recorder.events)
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStore(t.TempDir())
+
+	assert.NoError(t, s.Put(ctx, "covers/1/cover.png", strings.NewReader("image"), "image/png"))
+	blob, err := s.Get(ctx, "covers/1/cover.png")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(blob.Content)
+	assert.NoError(t, err)
+	assert.NoError(t, blob.Content.Close())
+	assert.Equal(t, "image", string(data))
+	assert.Equal(t, "image/png", blob.ContentType)
+	assert.Equal(t, int64(5), blob.Size)
+
+	assert.NoError(t, s.Delete(ctx, "covers/1/cover.png"))
+	assert.NoError(t, s.Delete(ctx, "covers/1/cover.png"))
+	_, err = s.Get(ctx, "covers/1/cover.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "covers/../../secret", "covers//1"} {
+		_, err = s.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}